@@ -3,6 +3,7 @@ package app
 import (
 	"waynezhang/buku/internal/infra/config"
 	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/repo/authors"
 	"waynezhang/buku/internal/route"
 
 	"github.com/gofiber/fiber/v2"
@@ -24,6 +25,10 @@ func New() *App {
 	if err != nil {
 		log.Fatal("Failed to load database (%s).", err.Error())
 	}
+	// Books saved before authors were kept are credited from their author
+	if err := authors.LinkMissing(db); err != nil {
+		log.Fatal("Failed to migrate authors (%s).", err.Error())
+	}
 	app.db = db
 	if app.config.Debug {
 		app.db = db.Debug()
@@ -0,0 +1,196 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/authors"
+
+	"gorm.io/gorm"
+)
+
+// SCHEMA_VERSION is bumped whenever the document layout changes. Documents
+// with a newer version than this are rejected by Read, so that an older buku
+// doesn't restore them while dropping what it doesn't know. Older documents
+// are restored, leaving out what they miss. The versions added:
+//
+//  1. books and shares
+//  2. import presets
+//  3. highlights
+//  4. KOReader documents
+//  5. authors and credits
+const SCHEMA_VERSION = 5
+
+// Document is a full-fidelity snapshot of the library. Every model is
+// exported with all of its fields, including IDs and timestamps, so that
+// Restore reproduces the database exactly.
+type Document struct {
+	SchemaVersion int            `json:"schema_version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	Books         []models.Book  `json:"books"`
+	Shares        []models.Share `json:"shares"`
+	// Missing in documents exported before presets existed, which is fine
+	// since restoring those simply leaves no presets.
+	ImportPresets []models.ImportPreset     `json:"import_presets"`
+	Highlights    []models.Highlight        `json:"highlights"`
+	Documents     []models.DocumentProgress `json:"documents"`
+	// Missing in documents exported before authors were kept. Restoring
+	// those credits the authors from the author of each book instead.
+	Authors []models.Author     `json:"authors"`
+	Credits []models.BookAuthor `json:"credits"`
+}
+
+type Summary struct {
+	Books         int `json:"books"`
+	Shares        int `json:"shares"`
+	ImportPresets int `json:"import_presets"`
+	Highlights    int `json:"highlights"`
+	Documents     int `json:"documents"`
+	// Authors and Credits include the ones credited from the author of the
+	// books, for backups without them.
+	Authors int64 `json:"authors"`
+	Credits int64 `json:"credits"`
+}
+
+func Export(db *gorm.DB) (*Document, error) {
+	doc := Document{
+		SchemaVersion: SCHEMA_VERSION,
+		ExportedAt:    time.Now().UTC(),
+		Books:         []models.Book{},
+		Shares:        []models.Share{},
+		ImportPresets: []models.ImportPreset{},
+		Highlights:    []models.Highlight{},
+		Documents:     []models.DocumentProgress{},
+		Authors:       []models.Author{},
+		Credits:       []models.BookAuthor{},
+	}
+
+	if err := db.Order("id").Find(&doc.Books).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id").Find(&doc.Shares).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id").Find(&doc.ImportPresets).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id").Find(&doc.Highlights).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id").Find(&doc.Documents).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id").Find(&doc.Authors).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("book_id, position").Find(&doc.Credits).Error; err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Write encodes the document indented, one field per line, so that
+// successive backups diff nicely.
+func Write(w io.Writer, doc *Document) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func Read(r io.Reader) (*Document, error) {
+	doc := Document{}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.SchemaVersion < 1 {
+		return nil, errors.New("Schema version is missing")
+	}
+	if doc.SchemaVersion > SCHEMA_VERSION {
+		return nil, errors.New("Unsupported schema version")
+	}
+	return &doc, nil
+}
+
+// Restore replaces the whole library with the document contents in a single
+// transaction. Nothing is changed if any record fails to insert.
+func Restore(db *gorm.DB, doc *Document) (*Summary, error) {
+	summary := Summary{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("true").Delete(&models.DocumentProgress{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("true").Delete(&models.Highlight{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("true").Delete(&models.BookAuthor{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("true").Delete(&models.Author{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("true").Delete(&models.ImportPreset{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("true").Delete(&models.Share{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("true").Delete(&models.Book{}).Error; err != nil {
+			return err
+		}
+
+		for i := range doc.Books {
+			if err := tx.Create(&doc.Books[i]).Error; err != nil {
+				return err
+			}
+		}
+		for i := range doc.Shares {
+			if err := tx.Create(&doc.Shares[i]).Error; err != nil {
+				return err
+			}
+		}
+		for i := range doc.ImportPresets {
+			if err := tx.Create(&doc.ImportPresets[i]).Error; err != nil {
+				return err
+			}
+		}
+		for i := range doc.Highlights {
+			if err := tx.Create(&doc.Highlights[i]).Error; err != nil {
+				return err
+			}
+		}
+		for i := range doc.Documents {
+			if err := tx.Create(&doc.Documents[i]).Error; err != nil {
+				return err
+			}
+		}
+		for i := range doc.Authors {
+			if err := tx.Create(&doc.Authors[i]).Error; err != nil {
+				return err
+			}
+		}
+		for i := range doc.Credits {
+			if err := tx.Create(&doc.Credits[i]).Error; err != nil {
+				return err
+			}
+		}
+		if err := authors.LinkMissing(tx); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Author{}).Count(&summary.Authors).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.BookAuthor{}).Count(&summary.Credits).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Books = len(doc.Books)
+	summary.Shares = len(doc.Shares)
+	summary.ImportPresets = len(doc.ImportPresets)
+	summary.Highlights = len(doc.Highlights)
+	summary.Documents = len(doc.Documents)
+	return &summary, nil
+}
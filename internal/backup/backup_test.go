@@ -0,0 +1,122 @@
+package backup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/authors"
+	"waynezhang/buku/internal/repo/books"
+	"waynezhang/buku/internal/repo/highlights"
+	"waynezhang/buku/internal/repo/presets"
+	"waynezhang/buku/internal/repo/progress"
+	"waynezhang/buku/internal/repo/shares"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func testDB() *gorm.DB {
+	db, _ := database.Load(":memory:")
+	return db
+}
+
+func exportString(t *testing.T, db *gorm.DB) string {
+	doc, err := Export(db)
+	assert.Nil(t, err)
+	doc.ExportedAt = time.Time{}
+
+	b := new(bytes.Buffer)
+	assert.Nil(t, Write(b, doc))
+	return b.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	db := testDB()
+
+	started := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	finished := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+	_, _ = books.Create(db, &models.Book{Title: "Test 1", Author: "Author 1", ISBN: "isbn", Comments: "c"})
+	_, _ = books.Create(db, &models.Book{Title: "Test 2", Author: "Author 2 & Author 3 (translator)", Series: "Series 1", StartedAt: &started})
+	_, _ = books.Create(db, &models.Book{Title: "Test 3", StartedAt: &started, FinishedAt: &finished})
+	_ = books.Delete(db, 1)
+	_, _ = shares.Create(db, &models.Share{Kind: models.SHARE_KIND_YEAR, Value: "2024", ShowComments: true})
+	_, _ = presets.Create(db, &models.ImportPreset{Name: "Sheet", Delimiter: ";", Columns: map[string]string{"Title": "Titel"}})
+	_, _ = highlights.Create(db, &models.Highlight{BookID: 2, Kind: models.HIGHLIGHT_KIND_HIGHLIGHT, Text: "Quote", Location: "10-12", AddedAt: &started})
+	bookID := uint(2)
+	_, _ = progress.Link(db, "abc", &bookID)
+
+	first := exportString(t, db)
+	assert.Contains(t, first, `"schema_version": 5`)
+
+	doc, err := Read(strings.NewReader(first))
+	assert.Nil(t, err)
+
+	other := testDB()
+	_, _ = books.Create(other, &models.Book{Title: "Overwritten"})
+	summary, err := Restore(other, doc)
+	assert.Nil(t, err)
+	assert.Equal(t, summary.Books, 2)
+	assert.Equal(t, summary.Shares, 1)
+	assert.Equal(t, summary.ImportPresets, 1)
+	assert.Equal(t, summary.Highlights, 1)
+	assert.Equal(t, summary.Documents, 1)
+	assert.Equal(t, summary.Authors, int64(2))
+	assert.Equal(t, summary.Credits, int64(2))
+
+	assert.Equal(t, first, exportString(t, other))
+	assert.Nil(t, books.GetByID(other, 1))
+	assert.Equal(t, books.GetByID(other, 3).Status, models.STATUS_READ)
+	assert.Equal(t, authors.GetByBook(other, 2), []models.Credit{{Name: "Author 2", Role: models.AUTHOR_ROLE_AUTHOR}, {Name: "Author 3", Role: models.AUTHOR_ROLE_TRANSLATOR}})
+}
+
+func TestRestoreWithoutAuthors(t *testing.T) {
+	db := testDB()
+
+	doc := &Document{
+		SchemaVersion: SCHEMA_VERSION,
+		Books:         []models.Book{{ID: 1, Title: "A", Author: "Author 1; Author 2"}},
+	}
+	summary, err := Restore(db, doc)
+	assert.Nil(t, err)
+	assert.Equal(t, summary.Authors, int64(2))
+
+	assert.Len(t, books.GetByAuthor(db, "Author 2"), 1)
+	assert.Len(t, authors.GetByBook(db, 1), 2)
+}
+
+func TestRestoreIsAtomic(t *testing.T) {
+	db := testDB()
+	_, _ = books.Create(db, &models.Book{Title: "Kept"})
+
+	doc := &Document{
+		SchemaVersion: SCHEMA_VERSION,
+		Books:         []models.Book{{ID: 1, Title: "A"}, {ID: 1, Title: "Duplicated ID"}},
+	}
+	_, err := Restore(db, doc)
+	assert.NotNil(t, err)
+
+	assert.Len(t, books.GetAll(db), 1)
+	assert.Equal(t, books.GetAll(db)[0].Title, "Kept")
+}
+
+func TestRead(t *testing.T) {
+	_, err := Read(strings.NewReader(`{"books": []}`))
+	assert.EqualError(t, err, "Schema version is missing")
+
+	_, err = Read(strings.NewReader(`{"schema_version": 99}`))
+	assert.EqualError(t, err, "Unsupported schema version")
+
+	// Backups of older versions are restored without what they miss
+	doc, err := Read(strings.NewReader(`{"schema_version": 1, "books": [{"id": 1, "title": "A"}], "shares": []}`))
+	assert.Nil(t, err)
+	summary, err := Restore(testDB(), doc)
+	assert.Nil(t, err)
+	assert.Equal(t, summary.Books, 1)
+	assert.Equal(t, summary.ImportPresets, 0)
+
+	_, err = Read(strings.NewReader(`not json`))
+	assert.NotNil(t, err)
+}
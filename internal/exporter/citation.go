@@ -0,0 +1,203 @@
+package exporter
+
+import (
+	"cmp"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"waynezhang/buku/internal/models"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	BIBTEX_CONTENT_TYPE = "application/x-bibtex"
+	RIS_CONTENT_TYPE    = "application/x-research-info-systems"
+)
+
+// Title words skipped for citation keys.
+var citationStopWords = []string{"a", "an", "the", "of", "on", "in", "and", "to", "le", "la", "les", "der", "die", "das"}
+
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`,
+	"}", `\}`,
+	"&", `\&`,
+	"%", `\%`,
+	"$", `\$`,
+	"#", `\#`,
+	"_", `\_`,
+	"~", `\textasciitilde{}`,
+	"^", `\textasciicircum{}`,
+)
+
+// CitationKeys returns the citation key of each book of list, the last name
+// of its first author, the year, and the first significant word of its
+// title, such as "orwell2021animal". buku keeps no publication year, so the
+// year is the one the book was finished in, and is left out for unfinished
+// books, like "orwellanimal". Books sharing a key get a letter suffix in the
+// order of their IDs, like "orwell2021animalb". A key only depends on the
+// book and the books added before it sharing its key, so keys are stable
+// between exports when list holds them, as books.GetCitationPeers returns.
+func CitationKeys(list []models.Book) map[uint]string {
+	sorted := make([]*models.Book, 0, len(list))
+	for i := range list {
+		sorted = append(sorted, &list[i])
+	}
+	// The book added first keeps the bare key
+	slices.SortFunc(sorted, func(a, b *models.Book) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	keys := map[uint]string{}
+	seen := map[string]int{}
+	for _, b := range sorted {
+		key := citationKey(b)
+		n := seen[key]
+		seen[key] = n + 1
+		if n > 0 {
+			key += citationSuffix(n)
+		}
+		keys[b.ID] = key
+	}
+	return keys
+}
+
+// WriteBibTeX writes list as @book entries keyed by keys, as returned by
+// CitationKeys. buku keeps no publisher nor publication year, so entries
+// carry the authors, editors and translators, title, series and ISBN.
+// Illustrators have no BibTeX field and are left out.
+func WriteBibTeX(w io.Writer, list []models.Book, keys map[uint]string) error {
+	b := new(strings.Builder)
+	for i, book := range list {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("@book{" + keys[book.ID] + ",\n")
+		credits := models.ParseCredits(book.Author)
+		for _, field := range []struct{ name, role string }{
+			{"author", models.AUTHOR_ROLE_AUTHOR},
+			{"editor", models.AUTHOR_ROLE_EDITOR},
+			{"translator", models.AUTHOR_ROLE_TRANSLATOR},
+		} {
+			if names := citationNames(credits, field.role); len(names) > 0 {
+				writeBibTeXField(b, field.name, strings.Join(names, " and "))
+			}
+		}
+		// The braces keep the capitalization of the title
+		b.WriteString("  title = {{" + bibtexEscaper.Replace(book.Title) + "}},\n")
+		if len(book.Series) > 0 {
+			writeBibTeXField(b, "series", book.Series)
+		}
+		if isbn := models.NormalizeISBN(book.ISBN); len(isbn) > 0 {
+			writeBibTeXField(b, "isbn", isbn)
+		}
+		b.WriteString("}\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteRIS writes list as RIS BOOK records, with the citation keys of keys
+// as their IDs. Editors are ED and translators A2; illustrators are left
+// out.
+func WriteRIS(w io.Writer, list []models.Book, keys map[uint]string) error {
+	b := new(strings.Builder)
+	for _, book := range list {
+		writeRISLine(b, "TY", "BOOK")
+		writeRISLine(b, "ID", keys[book.ID])
+		credits := models.ParseCredits(book.Author)
+		for _, tag := range []struct{ name, role string }{
+			{"AU", models.AUTHOR_ROLE_AUTHOR},
+			{"ED", models.AUTHOR_ROLE_EDITOR},
+			{"A2", models.AUTHOR_ROLE_TRANSLATOR},
+		} {
+			for _, name := range citationNames(credits, tag.role) {
+				writeRISLine(b, tag.name, name)
+			}
+		}
+		writeRISLine(b, "TI", book.Title)
+		if len(book.Series) > 0 {
+			writeRISLine(b, "T2", book.Series)
+		}
+		if isbn := models.NormalizeISBN(book.ISBN); len(isbn) > 0 {
+			writeRISLine(b, "SN", isbn)
+		}
+		writeRISLine(b, "ER", "")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeBibTeXField(b *strings.Builder, name string, value string) {
+	b.WriteString("  " + name + " = {" + bibtexEscaper.Replace(value) + "},\n")
+}
+
+// writeRISLine writes a tagged line. RIS has a line per value, so line
+// breaks in value are replaced by spaces.
+func writeRISLine(b *strings.Builder, tag string, value string) {
+	value = strings.Join(strings.Fields(value), " ")
+	b.WriteString(tag + "  - " + value + "\r\n")
+}
+
+// citationNames returns the names of the credits with role as "Last, First".
+func citationNames(credits []models.Credit, role string) []string {
+	names := []string{}
+	for _, c := range credits {
+		if c.Role == role {
+			names = append(names, authorLastFirst(c.Name))
+		}
+	}
+	return names
+}
+
+// citationKey starts with the last name of the first author, or of the first
+// editor of books without authors, like BibTeX styles do.
+func citationKey(b *models.Book) string {
+	key := "anon"
+	credits := models.ParseCredits(b.Author)
+	names := citationNames(credits, models.AUTHOR_ROLE_AUTHOR)
+	if len(names) == 0 {
+		names = citationNames(credits, models.AUTHOR_ROLE_EDITOR)
+	}
+	if len(names) > 0 {
+		last, _, _ := strings.Cut(names[0], ",")
+		if k := keyWord(last); len(k) > 0 {
+			key = k
+		}
+	}
+	if b.FinishedAt != nil {
+		key += strconv.Itoa(b.FinishedAt.Year())
+	}
+
+	for _, word := range strings.Fields(b.Title) {
+		w := keyWord(word)
+		if len(w) > 0 && !slices.Contains(citationStopWords, w) {
+			return key + w
+		}
+	}
+	return key
+}
+
+// keyWord lowercases str to ASCII letters and digits, dropping accents, so
+// that "Brontë" becomes "bronte".
+func keyWord(str string) string {
+	b := new(strings.Builder)
+	for _, r := range norm.NFD.String(strings.ToLower(str)) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// citationSuffix returns "b" for the second book sharing a key, "c" for the
+// third, and "zb", "zc" past "z".
+func citationSuffix(n int) string {
+	if n < 26 {
+		return string(rune('a' + n))
+	}
+	return "z" + citationSuffix(n-25)
+}
@@ -0,0 +1,105 @@
+package exporter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+	"waynezhang/buku/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCitationKeys(t *testing.T) {
+	finished := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	list := []models.Book{
+		{ID: 10, Title: "Animal Farm", Author: "George Orwell", FinishedAt: &finished},
+		{ID: 11, Title: "Animal Farm", Author: "George Orwell"},
+		{ID: 12, Title: "An Animal Farm", Author: "George Orwell", FinishedAt: &finished},
+		{ID: 4, Title: "1984", Author: "George Orwell"},
+		{ID: 2, Title: "1984", Author: "Orwell, George"},
+		{ID: 3, Title: "The Hobbit", Author: "J. R. R. Tolkien"},
+		{ID: 5, Title: "Jane Eyre", Author: "Charlotte Brontë"},
+		{ID: 6, Title: "Good Omens", Author: "Terry Pratchett & Neil Gaiman"},
+		{ID: 7, Title: "Beowulf"},
+		{ID: 8, Title: "The Odyssey", Author: "Emily Wilson (translator) & Homer"},
+		{ID: 9, Title: "Dangerous Visions", Author: "Harlan Ellison (ed.)"},
+	}
+	keys := CitationKeys(list)
+	assert.Equal(t, keys, map[uint]string{
+		2:  "orwell1984",
+		4:  "orwell1984b",
+		3:  "tolkienhobbit",
+		5:  "brontejane",
+		6:  "pratchettgood",
+		7:  "anonbeowulf",
+		8:  "homerodyssey",
+		9:  "ellisondangerous",
+		10: "orwell2021animal",
+		11: "orwellanimal",
+		12: "orwell2021animalb",
+	})
+
+	assert.Equal(t, citationSuffix(25), "z")
+	assert.Equal(t, citationSuffix(26), "zb")
+}
+
+func TestWriteBibTeX(t *testing.T) {
+	list := []models.Book{
+		{ID: 1, Title: "Good Omens", Author: "Terry Pratchett & Neil Gaiman", ISBN: "978-0-06-085398-3"},
+		{ID: 2, Title: "R&D 100% {draft}", Series: "Notes"},
+	}
+	b := new(bytes.Buffer)
+	assert.Nil(t, WriteBibTeX(b, list, CitationKeys(list)))
+	assert.Equal(t, b.String(), "@book{pratchettgood,\n"+
+		"  author = {Pratchett, Terry and Gaiman, Neil},\n"+
+		"  title = {{Good Omens}},\n"+
+		"  isbn = {9780060853983},\n"+
+		"}\n"+
+		"\n"+
+		"@book{anonrd,\n"+
+		"  title = {{R\\&D 100\\% \\{draft\\}}},\n"+
+		"  series = {Notes},\n"+
+		"}\n")
+}
+
+func TestWriteRIS(t *testing.T) {
+	list := []models.Book{
+		{ID: 1, Title: "Good Omens", Author: "Terry Pratchett & Neil Gaiman", Series: "Discworld", ISBN: "0060853980"},
+	}
+	b := new(bytes.Buffer)
+	assert.Nil(t, WriteRIS(b, list, CitationKeys(list)))
+	assert.Equal(t, b.String(), "TY  - BOOK\r\n"+
+		"ID  - pratchettgood\r\n"+
+		"AU  - Pratchett, Terry\r\n"+
+		"AU  - Gaiman, Neil\r\n"+
+		"TI  - Good Omens\r\n"+
+		"T2  - Discworld\r\n"+
+		"SN  - 0060853980\r\n"+
+		"ER  - \r\n")
+}
+
+func TestCitationRoles(t *testing.T) {
+	list := []models.Book{
+		{ID: 1, Title: "The Odyssey", Author: "Homer & Emily Wilson (translator) & Bernard Knox (editor) & Jan Brett (illustrator)"},
+	}
+	keys := CitationKeys(list)
+
+	b := new(bytes.Buffer)
+	assert.Nil(t, WriteBibTeX(b, list, keys))
+	assert.Equal(t, b.String(), "@book{homerodyssey,\n"+
+		"  author = {Homer},\n"+
+		"  editor = {Knox, Bernard},\n"+
+		"  translator = {Wilson, Emily},\n"+
+		"  title = {{The Odyssey}},\n"+
+		"}\n")
+
+	b.Reset()
+	assert.Nil(t, WriteRIS(b, list, keys))
+	assert.Equal(t, b.String(), "TY  - BOOK\r\n"+
+		"ID  - homerodyssey\r\n"+
+		"AU  - Homer\r\n"+
+		"ED  - Knox, Bernard\r\n"+
+		"A2  - Wilson, Emily\r\n"+
+		"TI  - The Odyssey\r\n"+
+		"ER  - \r\n")
+}
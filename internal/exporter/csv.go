@@ -0,0 +1,163 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"waynezhang/buku/internal/models"
+)
+
+// Headers of the CSV export.
+const (
+	CSV_COLUMN_ID       = "ID"
+	CSV_COLUMN_Title    = "Title"
+	CSV_COLUMN_Author   = "Author"
+	CSV_COLUMN_Series   = "Series"
+	CSV_COLUMN_ISBN     = "ISBN"
+	CSV_COLUMN_Comments = "Comments"
+	CSV_COLUMN_Started  = "Started"
+	CSV_COLUMN_Finished = "Finished"
+	CSV_COLUMN_Status   = "Status"
+	CSV_COLUMN_Added    = "Added"
+	CSV_COLUMN_Updated  = "Updated"
+)
+
+// UTF8_BOM makes Excel read the export as UTF-8.
+const UTF8_BOM = "\uFEFF"
+
+// Column is a column of the CSV export, selected by its key, which is the
+// JSON field of the book.
+type Column struct {
+	Key    string
+	Header string
+	Value  func(b *models.Book) string
+}
+
+// Columns are the columns the CSV export can select.
+var Columns = []Column{
+	{"id", CSV_COLUMN_ID, func(b *models.Book) string { return strconv.FormatUint(uint64(b.ID), 10) }},
+	{"title", CSV_COLUMN_Title, func(b *models.Book) string { return b.Title }},
+	{"author", CSV_COLUMN_Author, func(b *models.Book) string { return b.Author }},
+	{"series", CSV_COLUMN_Series, func(b *models.Book) string { return b.Series }},
+	{"isbn", CSV_COLUMN_ISBN, func(b *models.Book) string { return b.ISBN }},
+	{"comments", CSV_COLUMN_Comments, func(b *models.Book) string { return b.Comments }},
+	{"started_at", CSV_COLUMN_Started, func(b *models.Book) string { return csvTime(b.StartedAt) }},
+	{"finished_at", CSV_COLUMN_Finished, func(b *models.Book) string { return csvTime(b.FinishedAt) }},
+	{"status", CSV_COLUMN_Status, func(b *models.Book) string { return b.Status }},
+	{"created_at", CSV_COLUMN_Added, func(b *models.Book) string { return csvTime(&b.CreatedAt) }},
+	{"updated_at", CSV_COLUMN_Updated, func(b *models.Book) string { return csvTime(&b.UpdatedAt) }},
+}
+
+// DefaultColumns are exported when no columns are selected. They are the
+// columns the CSV import maps by default.
+var DefaultColumns = []string{"title", "author", "series", "isbn", "comments", "started_at", "finished_at", "status"}
+
+type CSVOptions struct {
+	// Columns are the keys of the columns to export, in order.
+	// DefaultColumns when empty.
+	Columns   []string
+	Delimiter rune
+	// BOM starts the file with a UTF-8 byte order mark, for Excel.
+	BOM bool
+}
+
+var errUnknownColumn = errors.New("Column is invalid")
+
+// ParseColumns splits the comma-separated column keys of str, rejecting
+// unknown ones. It returns DefaultColumns for an empty str.
+func ParseColumns(str string) ([]string, error) {
+	if len(strings.TrimSpace(str)) == 0 {
+		return DefaultColumns, nil
+	}
+	keys := []string{}
+	for _, key := range strings.Split(str, ",") {
+		key = strings.TrimSpace(key)
+		if columnIndex(key) < 0 {
+			return nil, errUnknownColumn
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// CSVWriter writes books with the columns of its options, a batch at a
+// time, so that exports don't need the whole library in memory.
+type CSVWriter struct {
+	w       *csv.Writer
+	columns []Column
+	record  []string
+}
+
+// NewCSVWriter writes the BOM and header of opts to w. It fails before
+// writing anything when a column is unknown.
+func NewCSVWriter(w io.Writer, opts CSVOptions) (*CSVWriter, error) {
+	keys := opts.Columns
+	if len(keys) == 0 {
+		keys = DefaultColumns
+	}
+	columns := []Column{}
+	for _, key := range keys {
+		i := columnIndex(key)
+		if i < 0 {
+			return nil, errUnknownColumn
+		}
+		columns = append(columns, Columns[i])
+	}
+
+	if opts.BOM {
+		if _, err := io.WriteString(w, UTF8_BOM); err != nil {
+			return nil, err
+		}
+	}
+	cw := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		cw.Comma = opts.Delimiter
+	}
+
+	header := []string{}
+	for _, c := range columns {
+		header = append(header, c.Header)
+	}
+	if err := cw.Write(header); err != nil {
+		return nil, err
+	}
+	return &CSVWriter{w: cw, columns: columns, record: make([]string, len(columns))}, nil
+}
+
+// Write writes a row per book and flushes them to the underlying writer.
+func (cw *CSVWriter) Write(list []models.Book) error {
+	for _, b := range list {
+		for i, c := range cw.columns {
+			cw.record[i] = c.Value(&b)
+		}
+		if err := cw.w.Write(cw.record); err != nil {
+			return err
+		}
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// WriteCSV writes list with the columns of opts. Dates are RFC 3339.
+func WriteCSV(w io.Writer, list []models.Book, opts CSVOptions) error {
+	cw, err := NewCSVWriter(w, opts)
+	if err != nil {
+		return err
+	}
+	return cw.Write(list)
+}
+
+func columnIndex(key string) int {
+	return slices.IndexFunc(Columns, func(c Column) bool { return c.Key == key })
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+	"waynezhang/buku/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseColumns(t *testing.T) {
+	columns, err := ParseColumns("")
+	assert.Nil(t, err)
+	assert.Equal(t, columns, DefaultColumns)
+
+	columns, err = ParseColumns("finished_at, title")
+	assert.Nil(t, err)
+	assert.Equal(t, columns, []string{"finished_at", "title"})
+
+	_, err = ParseColumns("title,rating")
+	assert.NotNil(t, err)
+}
+
+func TestWriteCSV(t *testing.T) {
+	finished := time.Date(2025, 4, 20, 0, 0, 0, 0, time.UTC)
+	list := []models.Book{
+		{ID: 3, Title: "1984", Author: "George Orwell", Status: models.STATUS_READ, StartedAt: &finished, FinishedAt: &finished},
+		{ID: 5, Title: "Dune; Part 1", Status: models.STATUS_TO_READ},
+	}
+
+	b := new(bytes.Buffer)
+	assert.Nil(t, WriteCSV(b, list, CSVOptions{}))
+	assert.Equal(t, b.String(), "Title,Author,Series,ISBN,Comments,Started,Finished,Status\n"+
+		"1984,George Orwell,,,,2025-04-20T00:00:00Z,2025-04-20T00:00:00Z,read\n"+
+		"Dune; Part 1,,,,,,,to-read\n")
+
+	b.Reset()
+	assert.Nil(t, WriteCSV(b, list, CSVOptions{Columns: []string{"id", "title", "finished_at"}, Delimiter: ';', BOM: true}))
+	assert.Equal(t, b.String(), UTF8_BOM+"ID;Title;Finished\n"+
+		"3;1984;2025-04-20T00:00:00Z\n"+
+		"5;\"Dune; Part 1\";\n")
+
+	b.Reset()
+	assert.Nil(t, WriteCSV(b, list[:1], CSVOptions{Columns: []string{"title", "author"}, Delimiter: '\t'}))
+	assert.Equal(t, b.String(), "Title\tAuthor\n1984\tGeorge Orwell\n")
+
+	assert.NotNil(t, WriteCSV(b, list, CSVOptions{Columns: []string{"rating"}}))
+}
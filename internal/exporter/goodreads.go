@@ -0,0 +1,161 @@
+// Package exporter writes the library in the formats of other trackers.
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"waynezhang/buku/internal/models"
+)
+
+// GOODREADS_DATE is the format of the dates of a Goodreads export.
+const GOODREADS_DATE = "2006/01/02"
+
+// Goodreads shelves the read statuses map to.
+const (
+	GOODREADS_SHELF_READ              = "read"
+	GOODREADS_SHELF_CURRENTLY_READING = "currently-reading"
+	GOODREADS_SHELF_TO_READ           = "to-read"
+)
+
+// GoodreadsHeader is the header of a Goodreads library export, which
+// Goodreads, StoryGraph and other trackers import.
+var GoodreadsHeader = []string{
+	"Book Id",
+	"Title",
+	"Author",
+	"Author l-f",
+	"Additional Authors",
+	"ISBN",
+	"ISBN13",
+	"My Rating",
+	"Average Rating",
+	"Publisher",
+	"Binding",
+	"Number of Pages",
+	"Year Published",
+	"Original Publication Year",
+	"Date Read",
+	"Date Added",
+	"Bookshelves",
+	"Bookshelves with positions",
+	"Exclusive Shelf",
+	"My Review",
+	"Spoiler",
+	"Private Notes",
+	"Read Count",
+	"Owned Copies",
+}
+
+var goodreadsShelves = map[string]string{
+	models.STATUS_READ:    GOODREADS_SHELF_READ,
+	models.STATUS_READING: GOODREADS_SHELF_CURRENTLY_READING,
+	models.STATUS_TO_READ: GOODREADS_SHELF_TO_READ,
+}
+
+// WriteGoodreads writes list as a Goodreads library export. buku keeps no
+// rating, so My Rating is 0, Goodreads' "not rated". The first author is the
+// Author, and the other people credited, without their roles, the
+// Additional Authors. The comments become the review. Goodreads has no
+// series column, so series are dropped.
+func WriteGoodreads(w io.Writer, list []models.Book) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(GoodreadsHeader); err != nil {
+		return err
+	}
+	for _, b := range list {
+		if err := cw.Write(goodreadsRecord(&b)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func goodreadsRecord(b *models.Book) []string {
+	shelf := goodreadsShelves[b.Status]
+	if len(shelf) == 0 {
+		shelf = GOODREADS_SHELF_TO_READ
+	}
+	dateRead := ""
+	readCount := "0"
+	if b.Status == models.STATUS_READ {
+		readCount = "1"
+		if b.FinishedAt != nil {
+			dateRead = b.FinishedAt.Format(GOODREADS_DATE)
+		}
+	}
+
+	author, additional := goodreadsAuthors(b.Author)
+	return []string{
+		"",
+		b.Title,
+		author,
+		authorLastFirst(author),
+		strings.Join(additional, ", "),
+		goodreadsISBN(models.ISBN10(b.ISBN)),
+		goodreadsISBN(models.ISBN13(b.ISBN)),
+		"0",
+		"",
+		"",
+		"",
+		"",
+		"",
+		"",
+		dateRead,
+		dateAdded(b),
+		shelf,
+		"",
+		shelf,
+		b.Comments,
+		"",
+		"",
+		readCount,
+		"0",
+	}
+}
+
+// goodreadsISBN quotes isbn as Goodreads does, as a formula so that
+// spreadsheets keep its leading zeros.
+func goodreadsISBN(isbn string) string {
+	return `="` + isbn + `"`
+}
+
+// goodreadsAuthors returns the first author credited on author, or the
+// first person credited on books without authors, and the others.
+func goodreadsAuthors(author string) (string, []string) {
+	credits := models.ParseCredits(author)
+	if len(credits) == 0 {
+		return "", nil
+	}
+	first := slices.IndexFunc(credits, func(c models.Credit) bool { return c.Role == models.AUTHOR_ROLE_AUTHOR })
+	if first < 0 {
+		first = 0
+	}
+	others := []string{}
+	for i, c := range credits {
+		if i != first {
+			others = append(others, c.Name)
+		}
+	}
+	return credits[first].Name, others
+}
+
+// authorLastFirst turns "George Orwell" into "Orwell, George".
+func authorLastFirst(author string) string {
+	words := strings.Fields(author)
+	if len(words) < 2 || strings.Contains(author, ",") {
+		return strings.TrimSpace(author)
+	}
+	last := len(words) - 1
+	return words[last] + ", " + strings.Join(words[:last], " ")
+}
+
+func dateAdded(b *models.Book) string {
+	if b.CreatedAt.IsZero() {
+		return time.Now().Format(GOODREADS_DATE)
+	}
+	return b.CreatedAt.Format(GOODREADS_DATE)
+}
@@ -0,0 +1,53 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+	"waynezhang/buku/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteGoodreads(t *testing.T) {
+	added := time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)
+	started := time.Date(2023, 3, 30, 0, 0, 0, 0, time.UTC)
+	finished := time.Date(2023, 4, 20, 0, 0, 0, 0, time.UTC)
+	list := []models.Book{
+		{Title: "1984", Author: "George Orwell", ISBN: "0-452-28423-6", Status: models.STATUS_READ, StartedAt: &started, FinishedAt: &finished, Comments: "Bleak.", CreatedAt: added},
+		{Title: "Dune", Author: "Frank Herbert", Status: models.STATUS_READING, StartedAt: &started, CreatedAt: added},
+		{Title: "Walden", Status: models.STATUS_TO_READ, CreatedAt: added},
+		{Title: "The Odyssey", Author: "Emily Wilson (translator) & Homer", CreatedAt: added},
+	}
+
+	b := new(bytes.Buffer)
+	assert.Nil(t, WriteGoodreads(b, list))
+
+	records, err := csv.NewReader(b).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, records, 5)
+	assert.Equal(t, records[0], GoodreadsHeader)
+
+	row := map[string]string{}
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+	assert.Equal(t, row["Title"], "1984")
+	assert.Equal(t, row["Author l-f"], "Orwell, George")
+	assert.Equal(t, row["ISBN"], `="0452284236"`)
+	assert.Equal(t, row["ISBN13"], `="9780452284234"`)
+	assert.Equal(t, row["My Rating"], "0")
+	assert.Equal(t, row["Date Read"], "2023/04/20")
+	assert.Equal(t, row["Date Added"], "2023/01/02")
+	assert.Equal(t, row["Exclusive Shelf"], GOODREADS_SHELF_READ)
+	assert.Equal(t, row["Bookshelves"], GOODREADS_SHELF_READ)
+	assert.Equal(t, row["My Review"], "Bleak.")
+	assert.Equal(t, row["Read Count"], "1")
+
+	assert.Equal(t, records[2][18], GOODREADS_SHELF_CURRENTLY_READING)
+	assert.Equal(t, records[2][14], "")
+	assert.Equal(t, records[3][6], `=""`)
+	assert.Equal(t, records[3][18], GOODREADS_SHELF_TO_READ)
+	assert.Equal(t, records[4][2:5], []string{"Homer", "Homer", "Emily Wilson"})
+}
@@ -0,0 +1,167 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"waynezhang/buku/internal/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+var errNotCalibre = errors.New("Not a Calibre library")
+
+// IsSQLite reports whether head, the first bytes of a file, is the start of
+// a SQLite database such as a Calibre metadata.db.
+func IsSQLite(head []byte) bool {
+	return bytes.HasPrefix(head, sqliteMagic)
+}
+
+type calibreBook struct {
+	ID          uint
+	Title       string
+	ISBN        string
+	SeriesIndex float64
+	HasCover    bool
+}
+
+type calibreValue struct {
+	Book  uint
+	Value string
+}
+
+// ParseCalibre reads the books of the Calibre library database at path.
+// Row.Line is the Calibre book ID. Calibre has no reading state, so books
+// are imported as to-read; tags and the series index are kept in the
+// comments. Authors are joined with " & " like Calibre displays them, so
+// that each is credited on its own. Covers aren't imported, since buku has
+// nowhere to keep them; books which have one get a warning saying so.
+func ParseCalibre(path string) (*Parsed, error) {
+	db, err := gorm.Open(sqlite.Open("file:"+path+"?mode=ro"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		return nil, errNotCalibre
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	list := []calibreBook{}
+	err = db.Raw("SELECT id, title, isbn, series_index, has_cover FROM books ORDER BY id").Scan(&list).Error
+	if err != nil {
+		return nil, errNotCalibre
+	}
+
+	authors, err := calibreValues(db, `SELECT l.book, a.name AS value FROM books_authors_link l
+		JOIN authors a ON a.id = l.author ORDER BY l.id`)
+	if err != nil {
+		return nil, err
+	}
+	series, err := calibreValues(db, `SELECT l.book, s.name AS value FROM books_series_link l
+		JOIN series s ON s.id = l.series ORDER BY l.id`)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := calibreValues(db, `SELECT l.book, t.name AS value FROM books_tags_link l
+		JOIN tags t ON t.id = l.tag ORDER BY t.name`)
+	if err != nil {
+		return nil, err
+	}
+	isbns, err := calibreValues(db, "SELECT book, val AS value FROM identifiers WHERE type = 'isbn' ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	comments, err := calibreValues(db, "SELECT book, text AS value FROM comments ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	p := Parsed{Rows: []Row{}, DateFormats: map[string]string{}}
+	for _, cb := range list {
+		row := Row{
+			Line:         int(cb.ID),
+			Warnings:     []models.FieldError{},
+			DuplicateIDs: []uint{},
+		}
+		b := &row.Book
+		b.Title = strings.TrimSpace(cb.Title)
+		b.Author = strings.Join(authors[cb.ID], " & ")
+		b.ISBN = cb.ISBN
+		if len(isbns[cb.ID]) > 0 {
+			b.ISBN = isbns[cb.ID][0]
+		}
+		b.ISBN = models.NormalizeISBN(b.ISBN)
+
+		notes := []string{}
+		if len(series[cb.ID]) > 0 {
+			b.Series = series[cb.ID][0]
+			notes = append(notes, "Series index: "+strconv.FormatFloat(cb.SeriesIndex, 'f', -1, 64))
+		}
+		if len(tags[cb.ID]) > 0 {
+			notes = append(notes, "Tags: "+strings.Join(tags[cb.ID], ", "))
+		}
+
+		text := []string{}
+		if len(comments[cb.ID]) > 0 {
+			if c := htmlToText(comments[cb.ID][0]); len(c) > 0 {
+				text = append(text, c)
+			}
+		}
+		if len(notes) > 0 {
+			text = append(text, strings.Join(notes, "\n"))
+		}
+		b.Comments = strings.Join(text, "\n\n")
+
+		if cb.HasCover {
+			row.Warnings = append(row.Warnings, models.FieldError{Field: "cover", Message: "Cover not imported"})
+		}
+
+		b.FixStatus()
+		row.Errors = b.ValidateFields()
+		p.Rows = append(p.Rows, row)
+	}
+	return &p, nil
+}
+
+// calibreValues runs a query returning (book, value) pairs and groups the
+// values by book.
+func calibreValues(db *gorm.DB, query string) (map[uint][]string, error) {
+	list := []calibreValue{}
+	if err := db.Raw(query).Scan(&list).Error; err != nil {
+		return nil, err
+	}
+
+	ret := map[uint][]string{}
+	for _, v := range list {
+		if value := strings.TrimSpace(v.Value); len(value) > 0 {
+			ret[v.Book] = append(ret[v.Book], value)
+		}
+	}
+	return ret, nil
+}
+
+var (
+	htmlBreaks     = regexp.MustCompile(`(?i)<br\s*/?>|</li>`)
+	htmlParagraphs = regexp.MustCompile(`(?i)</p>|</div>`)
+	htmlTags       = regexp.MustCompile(`<[^>]*>`)
+	blankLines     = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+// htmlToText turns the HTML Calibre stores comments as into plain text,
+// keeping paragraphs.
+func htmlToText(str string) string {
+	str = htmlBreaks.ReplaceAllString(str, "\n")
+	str = htmlParagraphs.ReplaceAllString(str, "\n\n")
+	str = htmlTags.ReplaceAllString(str, "")
+	str = html.UnescapeString(str)
+	str = blankLines.ReplaceAllString(str, "\n\n")
+	return strings.TrimSpace(str)
+}
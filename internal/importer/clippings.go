@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+	"waynezhang/buku/internal/repo/highlights"
+
+	"gorm.io/gorm"
+)
+
+const CLIPPINGS_SEPARATOR = "=========="
+
+// Clipping is an entry of a Kindle "My Clippings.txt", not yet attached to a
+// book.
+type Clipping struct {
+	Title     string
+	Author    string
+	Highlight models.Highlight
+}
+
+type ClippingsResult struct {
+	Total        int `json:"total"`
+	Created      int `json:"created"`
+	Updated      int `json:"updated"`
+	Duplicates   int `json:"duplicates"`
+	BooksMatched int `json:"books_matched"`
+	BooksCreated int `json:"books_created"`
+}
+
+var (
+	clippingLocation = regexp.MustCompile(`(?i)\b(?:location|loc\.?|position|emplacement|posición|posizione)\s*([0-9]+(?:-[0-9]+)?)`)
+	clippingPage     = regexp.MustCompile(`(?i)\b(?:page|seite|página|pagina)\s*([0-9ivxlcdm]+(?:-[0-9ivxlcdm]+)?)`)
+	clippingTime     = regexp.MustCompile(`\d{1,2}:\d{2}(:\d{2})?.*$`)
+
+	clippingHighlightWords = []string{"highlight", "markierung", "surlignement", "subrayado", "evidenziazione"}
+	clippingNoteWords      = []string{"note", "notiz", "nota"}
+)
+
+// ParseClippings reads a Kindle "My Clippings.txt". Bookmarks, and clippings
+// without text, are dropped.
+func ParseClippings(r io.Reader) ([]Clipping, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	str := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	list := []Clipping{}
+	for _, block := range strings.Split(str, CLIPPINGS_SEPARATOR) {
+		lines := []string{}
+		scanner := bufio.NewScanner(strings.NewReader(block))
+		for scanner.Scan() {
+			lines = append(lines, strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff")))
+		}
+		for len(lines) > 0 && len(lines[0]) == 0 {
+			lines = lines[1:]
+		}
+		if len(lines) < 3 {
+			continue
+		}
+
+		kind := clippingKind(lines[1])
+		text := strings.TrimSpace(strings.Join(lines[2:], "\n"))
+		if len(kind) == 0 || len(text) == 0 {
+			continue
+		}
+
+		c := Clipping{Highlight: models.Highlight{Kind: kind, Text: text}}
+		c.Title, c.Author = splitClippingTitle(lines[0])
+		if m := clippingLocation.FindStringSubmatch(lines[1]); m != nil {
+			c.Highlight.Location = m[1]
+		}
+		if m := clippingPage.FindStringSubmatch(lines[1]); m != nil {
+			c.Highlight.Page = m[1]
+		}
+		parts := strings.Split(lines[1], "|")
+		c.Highlight.AddedAt = clippingDate(parts[len(parts)-1])
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// CommitClippings attaches the clippings to the books they match, fuzzily by
+// title and author, creating to-read books for the others. Clippings already
+// imported, or superseded by a longer one, are skipped; a clipping extending
+// an imported one replaces it.
+func CommitClippings(db *gorm.DB, clippings []Clipping) (*ClippingsResult, error) {
+	r := ClippingsResult{Total: len(clippings)}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		library := books.GetAll(tx)
+		bookIDs := map[string]uint{}
+		existing := map[uint][]models.Highlight{}
+
+		for _, c := range clippings {
+			key := titleKey(c.Title) + "\x00" + c.Author
+			id, ok := bookIDs[key]
+			if !ok {
+				if b := matchBook(library, c.Title, c.Author); b != nil {
+					id = b.ID
+					r.BooksMatched += 1
+				} else {
+					b, err := books.Create(tx, &models.Book{Title: c.Title, Author: c.Author})
+					if err != nil {
+						return err
+					}
+					library = append(library, *b)
+					id = b.ID
+					r.BooksCreated += 1
+				}
+				bookIDs[key] = id
+				existing[id] = highlights.GetByBook(tx, id)
+			}
+
+			h := c.Highlight
+			h.BookID = id
+			action, err := saveHighlight(tx, &h, existing[id])
+			if err != nil {
+				return err
+			}
+			switch action {
+			case HIGHLIGHT_CREATED:
+				r.Created += 1
+			case HIGHLIGHT_UPDATED:
+				r.Updated += 1
+			case HIGHLIGHT_DUPLICATE:
+				r.Duplicates += 1
+			}
+			existing[id] = append(existing[id], h)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// clippingKind returns the kind named at the start of the metadata line,
+// e.g. "- Your Highlight on page 12 | Location 180-182 | Added on ...", or
+// an empty string for bookmarks and clippings it doesn't know.
+func clippingKind(meta string) string {
+	kind, _, _ := strings.Cut(strings.ToLower(meta), "|")
+	for _, w := range clippingHighlightWords {
+		if strings.Contains(kind, w) {
+			return models.HIGHLIGHT_KIND_HIGHLIGHT
+		}
+	}
+	for _, w := range clippingNoteWords {
+		if strings.Contains(kind, w) {
+			return models.HIGHLIGHT_KIND_NOTE
+		}
+	}
+	return ""
+}
+
+// splitClippingTitle splits "Title (Author)", where the title may have
+// parentheses of its own. Kindle writes sideloaded authors as "Last, First"
+// and separates several authors with semicolons.
+func splitClippingTitle(str string) (string, string) {
+	if !strings.HasSuffix(str, ")") {
+		return str, ""
+	}
+
+	depth := 0
+	for i := len(str) - 1; i >= 0; i-- {
+		switch str[i] {
+		case ')':
+			depth += 1
+		case '(':
+			depth -= 1
+		}
+		if depth == 0 {
+			title := strings.TrimSpace(str[:i])
+			if len(title) == 0 {
+				return str, ""
+			}
+			return title, kindleAuthor(str[i+1 : len(str)-1])
+		}
+	}
+	return str, ""
+}
+
+func kindleAuthor(str string) string {
+	authors := []string{}
+	for _, a := range strings.Split(str, ";") {
+		a = strings.TrimSpace(a)
+		if last, first, ok := strings.Cut(a, ","); ok && !strings.Contains(first, ",") {
+			a = strings.TrimSpace(first) + " " + strings.TrimSpace(last)
+		}
+		if len(a) > 0 {
+			authors = append(authors, a)
+		}
+	}
+	return strings.Join(authors, ", ")
+}
+
+// clippingDate parses the "Added on Monday, 3 April 2023 10:00:00" part of
+// the metadata line. Words other than month names, and the time, are
+// ignored, which copes with the wording of most languages.
+func clippingDate(str string) *time.Time {
+	str = clippingTime.ReplaceAllString(str, "")
+	words := strings.FieldsFunc(strings.ToLower(str), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words = slices.DeleteFunc(words, func(w string) bool {
+		_, isMonth := monthNames[w]
+		return !isMonth && strings.IndexFunc(w, unicode.IsDigit) < 0
+	})
+
+	t, ok := parseMonthNameDate(strings.Join(words, " "))
+	if !ok {
+		return nil
+	}
+	return &t
+}
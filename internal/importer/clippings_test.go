@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+	"waynezhang/buku/internal/repo/highlights"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testClippings = "\ufeff1984 (Orwell, George)\r\n" +
+	"- Your Highlight on page 3 | Location 40-41 | Added on Monday, 3 April 2023 10:00:00\r\n" +
+	"\r\n" +
+	"It was a bright cold day in April\r\n" +
+	"==========\r\n" +
+	"1984 (Orwell, George)\r\n" +
+	"- Your Highlight on page 3 | Location 40-42 | Added on Monday, 3 April 2023 10:01:00\r\n" +
+	"\r\n" +
+	"It was a bright cold day in April, and the clocks were striking thirteen.\r\n" +
+	"==========\r\n" +
+	"1984 (Orwell, George)\r\n" +
+	"- Your Note on page 3 | Location 42 | Added on Monday, April 3, 2023 10:02:00 AM\r\n" +
+	"\r\n" +
+	"Thirteen!\r\n" +
+	"==========\r\n" +
+	"1984 (Orwell, George)\r\n" +
+	"- Your Bookmark on page 5 | Location 60 | Added on Monday, 3 April 2023 10:03:00\r\n" +
+	"\r\n" +
+	"\r\n" +
+	"==========\r\n" +
+	"The Hobbit (Middle-earth Book 1) (Tolkien, J. R. R.)\r\n" +
+	"- Ihre Markierung bei Position 100-101 | Hinzugefügt am Dienstag, 4. April 2023 09:00:00\r\n" +
+	"\r\n" +
+	"In a hole in the ground there lived a hobbit.\r\n" +
+	"==========\r\n"
+
+func TestParseClippings(t *testing.T) {
+	list, err := ParseClippings(strings.NewReader(testClippings))
+	assert.Nil(t, err)
+	assert.Len(t, list, 4)
+
+	c := list[0]
+	assert.Equal(t, c.Title, "1984")
+	assert.Equal(t, c.Author, "George Orwell")
+	assert.Equal(t, c.Highlight.Kind, models.HIGHLIGHT_KIND_HIGHLIGHT)
+	assert.Equal(t, c.Highlight.Text, "It was a bright cold day in April")
+	assert.Equal(t, c.Highlight.Location, "40-41")
+	assert.Equal(t, c.Highlight.Page, "3")
+	assert.Equal(t, c.Highlight.AddedAt.Format("2006-01-02"), "2023-04-03")
+
+	assert.Equal(t, list[2].Highlight.Kind, models.HIGHLIGHT_KIND_NOTE)
+	assert.Equal(t, list[2].Highlight.AddedAt.Format("2006-01-02"), "2023-04-03")
+
+	c = list[3]
+	assert.Equal(t, c.Title, "The Hobbit (Middle-earth Book 1)")
+	assert.Equal(t, c.Author, "J. R. R. Tolkien")
+	assert.Equal(t, c.Highlight.Location, "100-101")
+	assert.Equal(t, c.Highlight.AddedAt.Format("2006-01-02"), "2023-04-04")
+}
+
+func TestCommitClippings(t *testing.T) {
+	db := testDB()
+	existing, _ := books.Create(db, &models.Book{Title: "Nineteen Eighty-Four"})
+	orwell, _ := books.Create(db, &models.Book{Title: "1984: A Novel", Author: "Orwell"})
+
+	list, _ := ParseClippings(strings.NewReader(testClippings))
+	r, err := CommitClippings(db, list)
+	assert.Nil(t, err)
+	assert.Equal(t, *r, ClippingsResult{Total: 4, Created: 3, Updated: 1, BooksMatched: 1, BooksCreated: 1})
+
+	assert.Len(t, highlights.GetByBook(db, existing.ID), 0)
+	hs := highlights.GetByBook(db, orwell.ID)
+	assert.Len(t, hs, 2)
+	assert.Equal(t, hs[0].Location, "40-42")
+	assert.Contains(t, hs[0].Text, "thirteen")
+	assert.Equal(t, hs[1].Text, "Thirteen!")
+
+	hobbit := books.GetByTitleAndAuthor(db, "The Hobbit (Middle-earth Book 1)", "J. R. R. Tolkien")
+	assert.Len(t, hobbit, 1)
+	assert.Equal(t, hobbit[0].Status, models.STATUS_TO_READ)
+
+	// Importing the same file again changes nothing
+	r, err = CommitClippings(db, list)
+	assert.Nil(t, err)
+	assert.Equal(t, *r, ClippingsResult{Total: 4, Duplicates: 4, BooksMatched: 2})
+	assert.Len(t, books.GetAll(db), 3)
+	assert.Len(t, highlights.GetByBook(db, orwell.ID), 2)
+}
+
+func TestMatchBook(t *testing.T) {
+	list := []models.Book{
+		{ID: 1, Title: "Dune", Author: "Frank Herbert"},
+		{ID: 2, Title: "The Name of the Rose", Author: ""},
+	}
+	assert.Equal(t, matchBook(list, "DUNE (Dune Chronicles, Book 1)", "Herbert Frank").ID, uint(1))
+	assert.Nil(t, matchBook(list, "Dune", "Someone Else"))
+	assert.Equal(t, matchBook(list, "The Name of the Rose", "Umberto Eco").ID, uint(2))
+	assert.Nil(t, matchBook(list, "Dune Messiah", "Frank Herbert"))
+}
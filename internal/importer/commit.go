@@ -0,0 +1,293 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+	"waynezhang/buku/internal/repo/books"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CONFLICT_SKIP   = "skip"
+	CONFLICT_UPDATE = "update"
+	CONFLICT_CREATE = "create"
+
+	MATCH_ANY          = "any"
+	MATCH_ISBN         = "isbn"
+	MATCH_TITLE_AUTHOR = "title_author"
+)
+
+var (
+	ConflictPolicies = []string{CONFLICT_SKIP, CONFLICT_UPDATE, CONFLICT_CREATE}
+	MatchKeys        = []string{MATCH_ANY, MATCH_ISBN, MATCH_TITLE_AUTHOR}
+)
+
+const DEFAULT_BATCH_SIZE = 100
+
+// CommitOptions control which rows are imported and what happens to rows
+// matching a book already in the library.
+type CommitOptions struct {
+	// Accept selects the rows to import. All rows when nil.
+	Accept func(*Row) bool
+	// Conflict is one of CONFLICT_*. Defaults to CONFLICT_SKIP.
+	Conflict string
+	// Match is one of MATCH_*. MATCH_ANY tries the ISBN first and falls back
+	// to title and author. Defaults to MATCH_ANY.
+	Match string
+	// BatchSize is the number of rows written in a transaction, between
+	// cancellation checks and progress reports. Defaults to
+	// DEFAULT_BATCH_SIZE.
+	BatchSize int
+	// Progress is called after every batch with the number of processed
+	// rows and the result so far.
+	Progress func(processed int, r Result)
+}
+
+type Conflict struct {
+	Line   int    `json:"line"`
+	BookID uint   `json:"book_id"`
+	Action string `json:"action"`
+}
+
+type RowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+type Result struct {
+	Total     int        `json:"total"`
+	Succeeded int        `json:"succeeded"`
+	Created   int        `json:"created"`
+	Updated   int        `json:"updated"`
+	Failed    int        `json:"failed"`
+	Skipped   int        `json:"skipped"`
+	Conflicts []Conflict `json:"conflicts"`
+	Errors    []RowError `json:"errors"`
+}
+
+// Check marks the rows which look like books already in the library, or like
+// an earlier row of the same file.
+func Check(db *gorm.DB, rows []Row) {
+	seen := map[string]int{}
+	for i := range rows {
+		row := &rows[i]
+		if !row.Valid() {
+			continue
+		}
+
+		for _, b := range books.GetDuplicates(db, &row.Book) {
+			row.DuplicateIDs = append(row.DuplicateIDs, b.ID)
+		}
+
+		for _, key := range duplicateKeys(&row.Book) {
+			if line, ok := seen[key]; ok && row.DuplicateOfLine == 0 {
+				row.DuplicateOfLine = line
+			}
+		}
+		for _, key := range duplicateKeys(&row.Book) {
+			if _, ok := seen[key]; !ok {
+				seen[key] = row.Line
+			}
+		}
+	}
+}
+
+// Commit imports every valid row accepted by opts.Accept. Rows which aren't
+// accepted, or which match an existing book under CONFLICT_SKIP, are counted
+// as skipped; invalid ones as failed.
+//
+// Rows are written in batches of opts.BatchSize, each in its own
+// transaction, so that a large import doesn't hold the SQLite write lock
+// from other writes, such as progress syncs, until it's done. Cancelling ctx,
+// or any database error, undoes the committed batches, deleting the created
+// books and restoring the updated ones, so that a failed import leaves no
+// partial state.
+func Commit(ctx context.Context, db *gorm.DB, rows []Row, opts CommitOptions) (*Result, error) {
+	r := Result{Conflicts: []Conflict{}, Errors: []RowError{}}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DEFAULT_BATCH_SIZE
+	}
+
+	done := changes{}
+	for start := 0; start < len(rows); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, done.undo(db, err)
+		}
+
+		end := min(start+batchSize, len(rows))
+		batch := changes{}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for i := start; i < end; i++ {
+				if err := commitRow(tx, &rows[i], opts, &r, &batch); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// The batch is rolled back already
+			return nil, done.undo(db, err)
+		}
+		done.created = append(done.created, batch.created...)
+		done.updated = append(done.updated, batch.updated...)
+
+		if opts.Progress != nil {
+			opts.Progress(end, r)
+		}
+	}
+	return &r, nil
+}
+
+// changes are the books created, and the updated books as they were before,
+// by the committed batches of an import.
+type changes struct {
+	created []uint
+	updated []models.Book
+}
+
+// undo reverts the changes in a transaction, and returns cause, the error
+// the import failed with, joined with the error of the undo if any. Books
+// deleted since aren't restored.
+func (c *changes) undo(db *gorm.DB, cause error) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// In reverse, so that books updated twice end up as they were first
+		for i := len(c.updated) - 1; i >= 0; i-- {
+			b := c.updated[i]
+			if _, err := books.Update(tx, b.ID, &b); err != nil && !errors.Is(err, repo.ErrNotFound) {
+				return err
+			}
+		}
+		for _, id := range c.created {
+			if err := books.Delete(tx, id); err != nil && !errors.Is(err, repo.ErrNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
+
+// commitRow records the outcome of one row in r, and what it changed in c.
+// Only database errors are returned; rows failing validation are counted as
+// failed.
+func commitRow(tx *gorm.DB, row *Row, opts CommitOptions, r *Result, c *changes) error {
+	r.Total += 1
+
+	if opts.Accept != nil && !opts.Accept(row) {
+		r.Skipped += 1
+		return nil
+	}
+	if !row.Valid() {
+		r.fail(row, row.Errors)
+		return nil
+	}
+
+	b := row.Book
+	existing := findExisting(tx, &b, opts.Match)
+	if existing == nil || opts.Conflict == CONFLICT_CREATE {
+		if existing != nil {
+			r.Conflicts = append(r.Conflicts, Conflict{row.Line, existing.ID, CONFLICT_CREATE})
+		}
+		if _, err := books.Create(tx, &b); err != nil {
+			return r.failOrAbort(row, err)
+		}
+		c.created = append(c.created, b.ID)
+		r.Created += 1
+		r.Succeeded += 1
+		return nil
+	}
+
+	if opts.Conflict != CONFLICT_UPDATE {
+		r.Conflicts = append(r.Conflicts, Conflict{row.Line, existing.ID, CONFLICT_SKIP})
+		r.Skipped += 1
+		return nil
+	}
+
+	r.Conflicts = append(r.Conflicts, Conflict{row.Line, existing.ID, CONFLICT_UPDATE})
+	original := *existing
+	merge(existing, &b)
+	if _, err := books.Update(tx, existing.ID, existing); err != nil {
+		return r.failOrAbort(row, err)
+	}
+	c.updated = append(c.updated, original)
+	r.Updated += 1
+	r.Succeeded += 1
+	return nil
+}
+
+func (r *Result) fail(row *Row, err error) {
+	r.Failed += 1
+	r.Errors = append(r.Errors, RowError{Line: row.Line, Message: err.Error()})
+}
+
+func (r *Result) failOrAbort(row *Row, err error) error {
+	var validationErr models.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+	r.fail(row, validationErr)
+	return nil
+}
+
+func findExisting(db *gorm.DB, b *models.Book, match string) *models.Book {
+	found := []models.Book{}
+	if match != MATCH_TITLE_AUTHOR {
+		found = books.GetByISBN(db, b.ISBN)
+	}
+	if len(found) == 0 && match != MATCH_ISBN {
+		found = books.GetByTitleAndAuthor(db, b.Title, b.Author)
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	return &found[0]
+}
+
+// merge copies the non-empty fields of the imported draft over the existing
+// book, so that columns left unmapped don't wipe existing data.
+func merge(existing *models.Book, imported *models.Book) {
+	if len(imported.Title) > 0 {
+		existing.Title = imported.Title
+	}
+	if len(imported.Author) > 0 {
+		existing.Author = imported.Author
+	}
+	if len(imported.Series) > 0 {
+		existing.Series = imported.Series
+	}
+	if len(imported.ISBN) > 0 {
+		existing.ISBN = imported.ISBN
+	}
+	if len(imported.Comments) > 0 {
+		existing.Comments = imported.Comments
+	}
+	if imported.StartedAt != nil {
+		existing.StartedAt = imported.StartedAt
+	}
+	if imported.FinishedAt != nil {
+		existing.FinishedAt = imported.FinishedAt
+	}
+	// An undated status only comes from the status column
+	if imported.StartedAt == nil && imported.FinishedAt == nil && imported.Status != models.STATUS_TO_READ {
+		existing.Status = imported.Status
+	}
+}
+
+func duplicateKeys(b *models.Book) []string {
+	keys := []string{
+		"title:" + strings.ToLower(strings.TrimSpace(b.Title)) + "\x00" + strings.ToLower(strings.TrimSpace(b.Author)),
+	}
+	if isbn := models.NormalizeISBN(b.ISBN); len(isbn) > 0 {
+		keys = append(keys, "isbn:"+isbn)
+	}
+	return keys
+}
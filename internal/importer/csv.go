@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"waynezhang/buku/internal/models"
+)
+
+// Upload formats. FORMAT_CSV uses a column mapping, the others are parsed
+// by their own readers.
+const (
+	FORMAT_CSV        = "csv"
+	FORMAT_STORYGRAPH = "storygraph"
+	FORMAT_CALIBRE    = "calibre"
+)
+
+// Mapping names the CSV column used for each book field. Empty or unknown
+// columns leave the field blank.
+type Mapping struct {
+	Title    string
+	Author   string
+	Series   string
+	ISBN     string
+	Comments string
+	Started  string
+	Finished string
+	Status   string
+}
+
+// Options control how records are turned into books.
+type Options struct {
+	Mapping Mapping
+	// DateFormat is one of DATE_FORMAT_* or a Go reference layout. Empty or
+	// DATE_FORMAT_AUTO detects the format of each date column separately.
+	DateFormat string
+	// StatusRules maps status column values to STATUS_*, on top of
+	// DefaultStatusRules.
+	StatusRules map[string]string
+	// StatusConflict is one of STATUS_CONFLICT_*. Defaults to
+	// STATUS_CONFLICT_DATES.
+	StatusConflict string
+}
+
+// Parsed holds the rows of a file and the date format used for each date
+// field ("started_at", "finished_at"), empty when the column has no
+// parsable value.
+type Parsed struct {
+	Rows        []Row
+	DateFormats map[string]string
+}
+
+func NewCSVReader(r io.Reader, delimiter rune) *csv.Reader {
+	reader := csv.NewReader(r)
+	if delimiter != 0 {
+		reader.Comma = delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader
+}
+
+// Parse reads the header and every record of r and turns each record into a
+// validated draft. Nothing is written to the database.
+func Parse(r *csv.Reader, opts Options) (*Parsed, error) {
+	mapping := opts.Mapping
+	columns, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	titleIdx := slices.Index(columns, mapping.Title)
+	authorIdx := slices.Index(columns, mapping.Author)
+	seriesIdx := slices.Index(columns, mapping.Series)
+	isbnIdx := slices.Index(columns, mapping.ISBN)
+	commentsIdx := slices.Index(columns, mapping.Comments)
+	startedIdx := slices.Index(columns, mapping.Started)
+	finishedIdx := slices.Index(columns, mapping.Finished)
+	statusIdx := slices.Index(columns, mapping.Status)
+
+	records := [][]string{}
+	lines := []int{}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		records = append(records, rec)
+		lines = append(lines, line)
+	}
+
+	p := Parsed{
+		Rows: []Row{},
+		DateFormats: map[string]string{
+			"started_at":  dateFormatOf(records, startedIdx, opts.DateFormat),
+			"finished_at": dateFormatOf(records, finishedIdx, opts.DateFormat),
+		},
+	}
+	for i, rec := range records {
+		row := Row{
+			Line:         lines[i],
+			Warnings:     []models.FieldError{},
+			DuplicateIDs: []uint{},
+		}
+		b := &row.Book
+		b.Title = getStrVal(titleIdx, rec)
+		b.Author = getStrVal(authorIdx, rec)
+		b.Series = getStrVal(seriesIdx, rec)
+		b.ISBN = getStrVal(isbnIdx, rec)
+		b.Comments = getStrVal(commentsIdx, rec)
+		b.StartedAt = row.parseDate("started_at", getStrVal(startedIdx, rec), p.DateFormats["started_at"])
+		b.FinishedAt = row.parseDate("finished_at", getStrVal(finishedIdx, rec), p.DateFormats["finished_at"])
+		row.applyStatus(getStrVal(statusIdx, rec), opts)
+		b.FixStatus()
+		row.Errors = b.ValidateFields()
+
+		p.Rows = append(p.Rows, row)
+	}
+	return &p, nil
+}
+
+// dateFormatOf returns format, or the format detected from the values of the
+// column when format is empty or DATE_FORMAT_AUTO.
+func dateFormatOf(records [][]string, idx int, format string) string {
+	if len(format) > 0 && format != DATE_FORMAT_AUTO {
+		return format
+	}
+
+	values := []string{}
+	for _, rec := range records {
+		if v := getStrVal(idx, rec); len(v) > 0 {
+			values = append(values, v)
+		}
+	}
+	return detectDateFormat(values)
+}
+
+func getStrVal(idx int, record []string) string {
+	if idx < 0 || idx >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[idx])
+}
+
+// parseDate returns nil for empty values, and records a warning for values
+// which are not empty but can't be parsed. ISO dates are always accepted.
+func (row *Row) parseDate(field string, str string, format string) *time.Time {
+	if len(str) == 0 {
+		return nil
+	}
+	for _, f := range []string{format, DATE_FORMAT_ISO} {
+		if len(f) == 0 {
+			continue
+		}
+		if t, ok := parseDateAs(f, str); ok {
+			return &t
+		}
+	}
+	row.Warnings = append(row.Warnings, models.FieldError{
+		Field:   field,
+		Message: "Unparsable date \"" + str + "\"",
+	})
+	return nil
+}
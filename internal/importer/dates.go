@@ -0,0 +1,181 @@
+package importer
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	DATE_FORMAT_AUTO       = "auto"
+	DATE_FORMAT_ISO        = "yyyy-mm-dd"
+	DATE_FORMAT_YMD        = "yyyy/mm/dd"
+	DATE_FORMAT_DMY        = "dd/mm/yyyy"
+	DATE_FORMAT_MDY        = "mm/dd/yyyy"
+	DATE_FORMAT_MONTH_NAME = "month_name"
+	DATE_FORMAT_EXCEL      = "excel"
+)
+
+// DateFormats lists the named formats in the order auto-detection prefers
+// them, which decides ambiguous columns such as "01/02/2024" in favour of
+// day first.
+var DateFormats = []string{
+	DATE_FORMAT_ISO,
+	DATE_FORMAT_YMD,
+	DATE_FORMAT_DMY,
+	DATE_FORMAT_MDY,
+	DATE_FORMAT_MONTH_NAME,
+	DATE_FORMAT_EXCEL,
+}
+
+// Month names and abbreviations in the languages we've seen exports in.
+// Matching is case-insensitive and ignores trailing dots.
+var monthNames = map[string]time.Month{}
+
+func init() {
+	names := [][]string{
+		{"january", "jan", "januar", "jänner", "janvier", "janv", "enero", "ene", "gennaio", "gen", "januari", "janeiro"},
+		{"february", "feb", "februar", "février", "fevrier", "févr", "fevr", "febrero", "febbraio", "februari", "fevereiro", "fev"},
+		{"march", "mar", "märz", "maerz", "mär", "mars", "marzo", "maart", "mrt", "março", "marco"},
+		{"april", "apr", "avril", "avr", "abril", "abr", "aprile"},
+		{"may", "mai", "mayo", "maggio", "mag", "mei", "maio"},
+		{"june", "jun", "juni", "juin", "junio", "giugno", "giu", "junho"},
+		{"july", "jul", "juli", "juillet", "juil", "julio", "luglio", "lug", "julho"},
+		{"august", "aug", "août", "aout", "agosto", "ago", "augustus"},
+		{"september", "sep", "sept", "septembre", "septiembre", "settembre", "set", "setembro"},
+		{"october", "oct", "oktober", "okt", "octobre", "octubre", "ottobre", "ott", "outubro", "out"},
+		{"november", "nov", "novembre", "noviembre", "novembro"},
+		{"december", "dec", "dezember", "dez", "décembre", "decembre", "déc", "diciembre", "dic", "dicembre", "dezembro"},
+	}
+	for i, list := range names {
+		for _, name := range list {
+			monthNames[name] = time.Month(i + 1)
+		}
+	}
+}
+
+// parseDateAs parses str in a named format. Any other format is taken as a
+// Go reference layout.
+func parseDateAs(format string, str string) (time.Time, bool) {
+	switch format {
+	case DATE_FORMAT_ISO:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, str); err == nil {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	case DATE_FORMAT_YMD:
+		return parseNumericDate(str, 0, 1, 2)
+	case DATE_FORMAT_DMY:
+		return parseNumericDate(str, 2, 1, 0)
+	case DATE_FORMAT_MDY:
+		return parseNumericDate(str, 2, 0, 1)
+	case DATE_FORMAT_MONTH_NAME:
+		return parseMonthNameDate(str)
+	case DATE_FORMAT_EXCEL:
+		return parseExcelDate(str)
+	}
+
+	t, err := time.Parse(format, str)
+	return t, err == nil
+}
+
+// detectDateFormat returns the named format parsing most of values, or an
+// empty string when none parses any.
+func detectDateFormat(values []string) string {
+	best := ""
+	bestCount := 0
+	for _, format := range DateFormats {
+		count := 0
+		for _, v := range values {
+			if _, ok := parseDateAs(format, v); ok {
+				count += 1
+			}
+		}
+		if count > bestCount {
+			best = format
+			bestCount = count
+		}
+	}
+	return best
+}
+
+// parseNumericDate parses three numbers separated by "/", "-" or ".", the
+// year, month and day being at the given positions. A time part after a
+// space is ignored.
+func parseNumericDate(str string, year int, month int, day int) (time.Time, bool) {
+	str, _, _ = strings.Cut(str, " ")
+	parts := strings.FieldsFunc(str, func(r rune) bool {
+		return r == '/' || r == '-' || r == '.'
+	})
+	if len(parts) != 3 || len(parts[year]) != 4 {
+		return time.Time{}, false
+	}
+
+	nums := [3]int{}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}, false
+		}
+		nums[i] = n
+	}
+	return validDate(nums[year], nums[month], nums[day])
+}
+
+// parseMonthNameDate parses dates such as "31 January 2024", "Jan 31, 2024"
+// or "31. März 2024": one month name, one day and one four digit year in any
+// order.
+func parseMonthNameDate(str string) (time.Time, bool) {
+	fields := strings.FieldsFunc(strings.ToLower(str), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	year, day := 0, 0
+	var month time.Month
+	for _, f := range fields {
+		if m, ok := monthNames[f]; ok && month == 0 {
+			month = m
+			continue
+		}
+		// Ordinal suffixes, as in "1st" or "22nd"
+		f = strings.TrimRight(f, "stndrh")
+		n, err := strconv.Atoi(f)
+		switch {
+		case err != nil:
+			return time.Time{}, false
+		case len(f) == 4 && year == 0:
+			year = n
+		case day == 0:
+			day = n
+		default:
+			return time.Time{}, false
+		}
+	}
+	return validDate(year, int(month), day)
+}
+
+// parseExcelDate parses spreadsheet serial numbers, the days since
+// 1899-12-30. The time of day, if any, is dropped.
+func parseExcelDate(str string) (time.Time, bool) {
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil || f < 1 || f > 2958465 {
+		return time.Time{}, false
+	}
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return epoch.AddDate(0, 0, int(math.Floor(f))), true
+}
+
+func validDate(year int, month int, day int) (time.Time, bool) {
+	if year < 1 || month < 1 || month > 12 || day < 1 {
+		return time.Time{}, false
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day {
+		return time.Time{}, false
+	}
+	return t, true
+}
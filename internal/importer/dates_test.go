@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDateAs(t *testing.T) {
+	tests := []struct {
+		format string
+		str    string
+		want   string
+	}{
+		{DATE_FORMAT_ISO, "2024-01-31", "2024-01-31"},
+		{DATE_FORMAT_ISO, "2024-01-31T10:00:00Z", "2024-01-31"},
+		{DATE_FORMAT_YMD, "2024/1/31", "2024-01-31"},
+		{DATE_FORMAT_DMY, "31/01/2024", "2024-01-31"},
+		{DATE_FORMAT_DMY, "31.1.2024", "2024-01-31"},
+		{DATE_FORMAT_DMY, "31-01-2024 10:00", "2024-01-31"},
+		{DATE_FORMAT_MDY, "01/31/2024", "2024-01-31"},
+		{DATE_FORMAT_MONTH_NAME, "31 January 2024", "2024-01-31"},
+		{DATE_FORMAT_MONTH_NAME, "Jan 31, 2024", "2024-01-31"},
+		{DATE_FORMAT_MONTH_NAME, "January 31st, 2024", "2024-01-31"},
+		{DATE_FORMAT_MONTH_NAME, "31. März 2024", "2024-03-31"},
+		{DATE_FORMAT_MONTH_NAME, "1 févr. 2024", "2024-02-01"},
+		{DATE_FORMAT_EXCEL, "45322", "2024-01-31"},
+		{DATE_FORMAT_EXCEL, "45322.75", "2024-01-31"},
+		{"02 Jan 06", "31 Jan 24", "2024-01-31"},
+	}
+	for _, tt := range tests {
+		got, ok := parseDateAs(tt.format, tt.str)
+		assert.True(t, ok, tt.str)
+		assert.Equal(t, got.Format("2006-01-02"), tt.want, tt.str)
+	}
+
+	invalid := []struct {
+		format string
+		str    string
+	}{
+		{DATE_FORMAT_DMY, "31/02/2024"},
+		{DATE_FORMAT_DMY, "2024/01/31"},
+		{DATE_FORMAT_MDY, "31/01/2024"},
+		{DATE_FORMAT_MONTH_NAME, "31 Foo 2024"},
+		{DATE_FORMAT_MONTH_NAME, "January 2024"},
+		{DATE_FORMAT_EXCEL, "abc"},
+	}
+	for _, tt := range invalid {
+		_, ok := parseDateAs(tt.format, tt.str)
+		assert.False(t, ok, tt.str)
+	}
+}
+
+func TestDetectDateFormat(t *testing.T) {
+	assert.Equal(t, detectDateFormat([]string{"2024-01-31", "2024-02-01"}), DATE_FORMAT_ISO)
+	assert.Equal(t, detectDateFormat([]string{"01/02/2024", "31/01/2024"}), DATE_FORMAT_DMY)
+	assert.Equal(t, detectDateFormat([]string{"01/02/2024", "01/31/2024"}), DATE_FORMAT_MDY)
+	// Ambiguous columns are read day first
+	assert.Equal(t, detectDateFormat([]string{"01/02/2024"}), DATE_FORMAT_DMY)
+	assert.Equal(t, detectDateFormat([]string{"Jan 31, 2024"}), DATE_FORMAT_MONTH_NAME)
+	assert.Equal(t, detectDateFormat([]string{"45322"}), DATE_FORMAT_EXCEL)
+	assert.Equal(t, detectDateFormat([]string{"someday"}), "")
+}
+
+func TestParseDetectsEachColumn(t *testing.T) {
+	csv := "Name,Start,End\nBook 1,31/01/2024,45330\nBook 2,02/01/2024,someday\n"
+	parsed, err := Parse(NewCSVReader(strings.NewReader(csv), ','), Options{
+		Mapping: Mapping{Title: "Name", Started: "Start", Finished: "End"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, parsed.DateFormats["started_at"], DATE_FORMAT_DMY)
+	assert.Equal(t, parsed.DateFormats["finished_at"], DATE_FORMAT_EXCEL)
+
+	rows := parsed.Rows
+	assert.Equal(t, rows[0].Book.FinishedAt.Format("2006-01-02"), "2024-02-08")
+	assert.Equal(t, rows[1].Book.StartedAt.Format("2006-01-02"), "2024-01-02")
+	assert.Equal(t, rows[1].Warnings[0].Message, `Unparsable date "someday"`)
+}
@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/highlights"
+
+	"gorm.io/gorm"
+)
+
+// What saveHighlight did with a highlight.
+const (
+	HIGHLIGHT_CREATED   = "created"
+	HIGHLIGHT_UPDATED   = "updated"
+	HIGHLIGHT_DUPLICATE = "duplicate"
+)
+
+// saveHighlight saves h unless one of list, the highlights the book already
+// has, covers it. The first of list covered by h is updated in place, and
+// dropped from list by zeroing its ID; the others are deleted.
+func saveHighlight(tx *gorm.DB, h *models.Highlight, list []models.Highlight) (string, error) {
+	replaced := uint(0)
+	for i := range list {
+		e := &list[i]
+		if e.ID == 0 {
+			continue
+		}
+		if e.Covers(h) {
+			return HIGHLIGHT_DUPLICATE, nil
+		}
+		if !h.Covers(e) {
+			continue
+		}
+
+		if replaced == 0 {
+			if _, err := highlights.Update(tx, e.ID, h); err != nil {
+				return "", err
+			}
+			replaced = e.ID
+		} else if err := highlights.Delete(tx, e.ID); err != nil {
+			return "", err
+		}
+		e.ID = 0
+	}
+
+	if replaced > 0 {
+		return HIGHLIGHT_UPDATED, nil
+	}
+	if _, err := highlights.Create(tx, h); err != nil {
+		return "", err
+	}
+	return HIGHLIGHT_CREATED, nil
+}
+
+// matchBook finds the book with the same title, ignoring case, punctuation,
+// subtitles and parenthesized parts such as the series, and sharing a name
+// with the author. Books, or e-reader entries, without an author match on
+// the title alone.
+func matchBook(list []models.Book, title string, author string) *models.Book {
+	key := titleKey(title)
+	if len(key) == 0 {
+		return nil
+	}
+	names := authorNames(author)
+	for i := range list {
+		if titleKey(list[i].Title) != key {
+			continue
+		}
+		other := authorNames(list[i].Author)
+		if len(names) == 0 || len(other) == 0 || slices.ContainsFunc(names, func(n string) bool {
+			return slices.Contains(other, n)
+		}) {
+			return &list[i]
+		}
+	}
+	return nil
+}
+
+var bracketed = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+
+func titleKey(title string) string {
+	title = bracketed.ReplaceAllString(strings.ToLower(title), " ")
+	title, _, _ = strings.Cut(title, ":")
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// authorNames returns the words of author longer than an initial.
+func authorNames(author string) []string {
+	words := strings.FieldsFunc(strings.ToLower(author), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return slices.DeleteFunc(words, func(w string) bool { return len([]rune(w)) < 2 })
+}
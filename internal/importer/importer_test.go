@@ -0,0 +1,395 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func testDB() *gorm.DB {
+	db, _ := database.Load(":memory:")
+	return db
+}
+
+var testMapping = Mapping{
+	Title:    "Name",
+	Author:   "Writer",
+	ISBN:     "ISBN",
+	Started:  "Start",
+	Finished: "End",
+}
+
+const testCSV = `Name,Writer,ISBN,Start,End
+Book 1,Author 1,978-0-14-303943-3,2024-01-01,2024-02-01
+,Author 2,,,
+Book 3,Author 3,,someday,
+"Book
+4",Author 4,,,
+book 1,author 1,,,
+Book 6,Author 6,9780143039433,,
+`
+
+func parseRows(r *csv.Reader, opts Options) []Row {
+	parsed, _ := Parse(r, opts)
+	return parsed.Rows
+}
+
+func TestParse(t *testing.T) {
+	parsed, err := Parse(NewCSVReader(strings.NewReader(testCSV), ','), Options{Mapping: testMapping})
+	assert.Nil(t, err)
+	assert.Equal(t, parsed.DateFormats["started_at"], DATE_FORMAT_ISO)
+
+	rows := parsed.Rows
+	assert.Len(t, rows, 6)
+
+	assert.Equal(t, rows[0].Line, 2)
+	assert.Equal(t, rows[0].Book.Title, "Book 1")
+	assert.Equal(t, rows[0].Book.Status, models.STATUS_READ)
+	assert.True(t, rows[0].Valid())
+
+	assert.False(t, rows[1].Valid())
+	assert.Equal(t, rows[1].Errors[0].Field, "title")
+
+	assert.True(t, rows[2].Valid())
+	assert.Nil(t, rows[2].Book.StartedAt)
+	assert.Equal(t, rows[2].Warnings[0].Field, "started_at")
+	assert.Equal(t, rows[2].Warnings[0].Message, `Unparsable date "someday"`)
+
+	assert.Equal(t, rows[3].Line, 5)
+	assert.Equal(t, rows[4].Line, 7)
+}
+
+func TestParseDateFormat(t *testing.T) {
+	csv := "Name;Start\nBook 1;31.01.2024\nBook 2;2024-02-01\n"
+	rows := parseRows(NewCSVReader(strings.NewReader(csv), ';'), Options{
+		Mapping:    Mapping{Title: "Name", Started: "Start"},
+		DateFormat: "02.01.2006",
+	})
+	assert.Equal(t, rows[0].Book.StartedAt.Format("2006-01-02"), "2024-01-31")
+	assert.Equal(t, rows[1].Book.StartedAt.Format("2006-01-02"), "2024-02-01")
+}
+
+func TestCheck(t *testing.T) {
+	db := testDB()
+	existing, _ := books.Create(db, &models.Book{Title: "BOOK 3", Author: "author 3"})
+
+	rows := parseRows(NewCSVReader(strings.NewReader(testCSV), ','), Options{Mapping: testMapping})
+	Check(db, rows)
+
+	assert.False(t, rows[0].Duplicate())
+	assert.False(t, rows[1].Duplicate())
+	assert.Equal(t, rows[2].DuplicateIDs, []uint{existing.ID})
+	assert.Equal(t, rows[4].DuplicateOfLine, 2)
+	assert.Equal(t, rows[5].DuplicateOfLine, 2)
+
+	p := NewPreview(&Parsed{Rows: rows})
+	assert.Equal(t, p.Total, 6)
+	assert.Equal(t, p.Valid, 5)
+	assert.Equal(t, p.Invalid, 1)
+	assert.Equal(t, p.Duplicates, 3)
+}
+
+func TestCommit(t *testing.T) {
+	db := testDB()
+
+	rows := parseRows(NewCSVReader(strings.NewReader(testCSV), ','), Options{Mapping: testMapping})
+	r, _ := Commit(context.Background(), db, rows, CommitOptions{
+		Accept:   func(row *Row) bool { return row.Line != 4 },
+		Conflict: CONFLICT_CREATE,
+	})
+	assert.Equal(t, r.Total, 6)
+	assert.Equal(t, r.Succeeded, 4)
+	assert.Equal(t, r.Created, 4)
+	assert.Equal(t, r.Failed, 1)
+	assert.Equal(t, r.Skipped, 1)
+	assert.Len(t, r.Conflicts, 2)
+	assert.Equal(t, r.Errors, []RowError{{Line: 3, Message: "Title is required"}})
+	assert.Len(t, books.GetAll(db), 4)
+}
+
+func TestCommitProgress(t *testing.T) {
+	db := testDB()
+
+	rows := parseRows(NewCSVReader(strings.NewReader(testCSV), ','), Options{Mapping: testMapping})
+	processed := []int{}
+	_, err := Commit(context.Background(), db, rows, CommitOptions{
+		BatchSize: 4,
+		Progress:  func(n int, r Result) { processed = append(processed, n) },
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, processed, []int{4, 6})
+}
+
+func TestCommitCancelRollsBack(t *testing.T) {
+	db := testDB()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows := parseRows(NewCSVReader(strings.NewReader(testCSV), ','), Options{Mapping: testMapping})
+	r, err := Commit(ctx, db, rows, CommitOptions{
+		BatchSize: 2,
+		Progress:  func(n int, r Result) { cancel() },
+	})
+	assert.Nil(t, r)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, books.GetAll(db), 0)
+}
+
+func TestCommitCancelRestoresUpdates(t *testing.T) {
+	db := testDB()
+	existing, _ := books.Create(db, &models.Book{Title: "Book 1", Author: "Author 1", Comments: "kept"})
+
+	csv := "Name,Writer,Notes\nBook 1,Author 1,changed\nBook 2,Author 2,\nBook 1,Author 1,changed again\nBook 3,Author 3,\n"
+	rows := parseRows(NewCSVReader(strings.NewReader(csv), ','), Options{Mapping: Mapping{Title: "Name", Author: "Writer", Comments: "Notes"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	visible := []int{}
+	_, err := Commit(ctx, db, rows, CommitOptions{
+		Conflict:  CONFLICT_UPDATE,
+		BatchSize: 2,
+		Progress: func(n int, r Result) {
+			// Batches are committed as they go
+			visible = append(visible, len(books.GetAll(db)))
+			if n == 2 {
+				cancel()
+			}
+		},
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, visible, []int{2})
+
+	assert.Len(t, books.GetAll(db), 1)
+	assert.Equal(t, books.GetByID(db, existing.ID).Comments, "kept")
+}
+
+func TestCommitSkip(t *testing.T) {
+	db := testDB()
+
+	rows := parseRows(NewCSVReader(strings.NewReader(testCSV), ','), Options{Mapping: testMapping})
+	r, _ := Commit(context.Background(), db, rows, CommitOptions{})
+	assert.Equal(t, r.Created, 3)
+	assert.Equal(t, r.Skipped, 2)
+	assert.Equal(t, r.Conflicts[0], Conflict{Line: 7, BookID: 1, Action: CONFLICT_SKIP})
+	assert.Equal(t, r.Conflicts[1], Conflict{Line: 8, BookID: 1, Action: CONFLICT_SKIP})
+
+	// Importing the same file again is a no-op
+	rows = parseRows(NewCSVReader(strings.NewReader(testCSV), ','), Options{Mapping: testMapping})
+	r, _ = Commit(context.Background(), db, rows, CommitOptions{})
+	assert.Equal(t, r.Created, 0)
+	assert.Equal(t, r.Skipped, 5)
+	assert.Len(t, books.GetAll(db), 3)
+}
+
+func TestCommitUpdate(t *testing.T) {
+	db := testDB()
+	existing, _ := books.Create(db, &models.Book{Title: "Book 3", Author: "Author 3", Comments: "kept"})
+
+	csv := "Name,Writer,Series\nbook 3,author 3,Series 3\n"
+	rows := parseRows(NewCSVReader(strings.NewReader(csv), ','), Options{Mapping: Mapping{Title: "Name", Author: "Writer", Series: "Series"}})
+	r, _ := Commit(context.Background(), db, rows, CommitOptions{Conflict: CONFLICT_UPDATE})
+	assert.Equal(t, r.Updated, 1)
+	assert.Equal(t, r.Created, 0)
+
+	updated := books.GetByID(db, existing.ID)
+	assert.Equal(t, updated.Title, "book 3")
+	assert.Equal(t, updated.Series, "Series 3")
+	assert.Equal(t, updated.Comments, "kept")
+}
+
+func TestCommitMatch(t *testing.T) {
+	db := testDB()
+	_, _ = books.Create(db, &models.Book{Title: "Other Title", ISBN: "9780143039433"})
+
+	csv := "Name,ISBN\nBook 1,978-0-14-303943-3\n"
+	opts := Options{Mapping: Mapping{Title: "Name", ISBN: "ISBN"}}
+
+	rows := parseRows(NewCSVReader(strings.NewReader(csv), ','), opts)
+	r, _ := Commit(context.Background(), db, rows, CommitOptions{Match: MATCH_TITLE_AUTHOR})
+	assert.Equal(t, r.Created, 1)
+
+	rows = parseRows(NewCSVReader(strings.NewReader(csv), ','), opts)
+	r, _ = Commit(context.Background(), db, rows, CommitOptions{Match: MATCH_ISBN})
+	assert.Equal(t, r.Skipped, 1)
+}
+
+func TestJobs(t *testing.T) {
+	db := testDB()
+	jobs := NewJobs()
+
+	j, err := jobs.Start(db, func() ([]Row, error) {
+		return parseRows(NewCSVReader(strings.NewReader(testCSV), ','), Options{Mapping: testMapping}), nil
+	}, CommitOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, j.Status, JOB_STATUS_QUEUED)
+
+	j = jobs.Wait(j.ID)
+	assert.Equal(t, j.Status, JOB_STATUS_SUCCEEDED)
+	assert.Equal(t, j.Total, 6)
+	assert.Equal(t, j.Processed, 6)
+	assert.Equal(t, j.Result.Created, 3)
+	assert.NotNil(t, j.FinishedAt)
+	assert.Len(t, books.GetAll(db), 3)
+
+	// Canceling a finished job keeps its result
+	j = jobs.Cancel(j.ID)
+	assert.Equal(t, j.Status, JOB_STATUS_SUCCEEDED)
+
+	// Rows which can't be loaded fail the job
+	j, _ = jobs.Start(db, func() ([]Row, error) { return nil, errors.New("bad file") }, CommitOptions{})
+	j = jobs.Wait(j.ID)
+	assert.Equal(t, j.Status, JOB_STATUS_FAILED)
+	assert.Equal(t, j.Error, "bad file")
+
+	assert.Nil(t, jobs.Get("unknown"))
+	assert.Nil(t, jobs.Cancel("unknown"))
+}
+
+func TestParseStatus(t *testing.T) {
+	csv := `Name,Start,End,State
+Book 1,,,Finished
+Book 2,,,dnf
+Book 3,2024-01-01,,to-read
+Book 4,2024-01-01,2024-02-01,currently-reading
+Book 5,2024-01-01,,read
+Book 6,,,gibberish
+`
+	mapping := Mapping{Title: "Name", Started: "Start", Finished: "End", Status: "State"}
+
+	rows := parseRows(NewCSVReader(strings.NewReader(csv), ','), Options{
+		Mapping:     mapping,
+		StatusRules: map[string]string{"DNF": models.STATUS_READ},
+	})
+	assert.Equal(t, rows[0].Book.Status, models.STATUS_READ)
+	assert.Nil(t, rows[0].Book.FinishedAt)
+	assert.Equal(t, rows[1].Book.Status, models.STATUS_READ)
+	assert.Equal(t, rows[2].Book.Status, models.STATUS_READING)
+	assert.Equal(t, rows[2].Warnings[0].Message, `Status "to-read" ignored, the dates say reading`)
+	assert.Equal(t, rows[3].Book.Status, models.STATUS_READ)
+	assert.Equal(t, rows[5].Book.Status, models.STATUS_TO_READ)
+	assert.Equal(t, rows[5].Warnings[0].Message, `Unknown status "gibberish"`)
+
+	rows = parseRows(NewCSVReader(strings.NewReader(csv), ','), Options{
+		Mapping:        mapping,
+		StatusConflict: models.STATUS_CONFLICT_STATUS,
+	})
+	assert.Equal(t, rows[1].Book.Status, models.STATUS_TO_READ)
+	assert.Equal(t, rows[2].Book.Status, models.STATUS_TO_READ)
+	assert.Nil(t, rows[2].Book.StartedAt)
+	assert.Equal(t, rows[3].Book.Status, models.STATUS_READING)
+	assert.Nil(t, rows[3].Book.FinishedAt)
+	assert.Equal(t, rows[4].Book.Status, models.STATUS_READ)
+	assert.Equal(t, rows[4].Book.FinishedAt, rows[4].Book.StartedAt)
+
+	db := testDB()
+	r, _ := Commit(context.Background(), db, rows, CommitOptions{})
+	assert.Equal(t, r.Created, 6)
+	assert.Equal(t, books.GetByID(db, 1).Status, models.STATUS_READ)
+}
+
+const testStoryGraphCSV = `Title,Authors,Contributors,ISBN/UID,Format,Read Status,Date Added,Last Date Read,Dates Read,Read Count,Moods,Pace,Star Rating,Review,Content Warnings,Tags,Owned?
+Book 1,Author 1,,9780143039433,paperback,read,2024/01/01,2024/03/10,"2023/01/05-2023/01/20, 2024/03/01-2024/03/10",2,"dark, reflective",slow,4.5,Loved it,,"classics, favourites",Yes
+Book 2,Author 2,,storygraph-uid-123,audio,currently-reading,2024/01/01,,,0,,,,,,,No
+Book 3,Author 3,,,ebook,to-read,2024/01/01,,,0,,,,,,,No
+Book 4,Author 4,,,,did-not-finish,2024/01/01,2024/02/01,,1,,,,,,,No
+`
+
+func TestParseStoryGraph(t *testing.T) {
+	header, _ := NewCSVReader(strings.NewReader(testStoryGraphCSV), ',').Read()
+	assert.True(t, IsStoryGraph(header))
+	assert.False(t, IsStoryGraph([]string{"Title", "Author"}))
+
+	parsed, err := ParseStoryGraph(NewCSVReader(strings.NewReader(testStoryGraphCSV), ','))
+	assert.Nil(t, err)
+	rows := parsed.Rows
+	assert.Len(t, rows, 4)
+
+	b := rows[0].Book
+	assert.Equal(t, b.Title, "Book 1")
+	assert.Equal(t, b.ISBN, "9780143039433")
+	assert.Equal(t, b.Status, models.STATUS_READ)
+	assert.Equal(t, b.StartedAt.Format("2006-01-02"), "2024-03-01")
+	assert.Equal(t, b.FinishedAt.Format("2006-01-02"), "2024-03-10")
+	assert.Equal(t, b.Comments, "Loved it\n\nRating: 4.5\nTags: classics, favourites\nMoods: dark, reflective\nFormat: paperback\nRead count: 2\nEarlier reads: 2023-01-05 – 2023-01-20")
+
+	assert.Equal(t, rows[1].Book.ISBN, "")
+	assert.Equal(t, rows[1].Book.Status, models.STATUS_READING)
+	assert.Nil(t, rows[1].Book.StartedAt)
+	assert.Equal(t, rows[2].Book.Status, models.STATUS_TO_READ)
+
+	assert.Equal(t, rows[3].Book.Status, models.STATUS_TO_READ)
+	assert.Nil(t, rows[3].Book.StartedAt)
+	assert.Nil(t, rows[3].Book.FinishedAt)
+	assert.Equal(t, rows[3].Book.Comments, "Did not finish: 2024-02-01")
+	for _, row := range rows {
+		assert.Len(t, row.Warnings, 0)
+	}
+}
+
+const testCalibreSchema = `
+CREATE TABLE books (id INTEGER PRIMARY KEY, title TEXT, isbn TEXT DEFAULT '', series_index REAL DEFAULT 1.0, has_cover BOOL DEFAULT 0);
+CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE books_authors_link (id INTEGER PRIMARY KEY, book INTEGER, author INTEGER);
+CREATE TABLE series (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE books_series_link (id INTEGER PRIMARY KEY, book INTEGER, series INTEGER);
+CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE books_tags_link (id INTEGER PRIMARY KEY, book INTEGER, tag INTEGER);
+CREATE TABLE identifiers (id INTEGER PRIMARY KEY, book INTEGER, type TEXT, val TEXT);
+CREATE TABLE comments (id INTEGER PRIMARY KEY, book INTEGER, text TEXT);
+INSERT INTO books VALUES (1, 'Book 1', '', 2.5, 1), (2, 'Book 2', '0-14-303943-3', 1.0, 0);
+INSERT INTO authors VALUES (1, 'Homer'), (2, 'Emily Wilson');
+INSERT INTO books_authors_link VALUES (1, 1, 1), (2, 1, 2), (3, 2, 2);
+INSERT INTO series VALUES (1, 'Series 1');
+INSERT INTO books_series_link VALUES (1, 1, 1);
+INSERT INTO tags VALUES (1, 'Fiction'), (2, 'Classics');
+INSERT INTO books_tags_link VALUES (1, 1, 1), (2, 1, 2);
+INSERT INTO identifiers VALUES (1, 1, 'isbn', '9780143039433'), (2, 1, 'goodreads', '1');
+INSERT INTO comments VALUES (1, 1, '<div><p>First &amp; <b>best</b></p><p>Second<br/>line</p></div>');
+`
+
+func TestParseCalibre(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Exec(testCalibreSchema).Error)
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+
+	head, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.True(t, IsSQLite(head))
+	assert.False(t, IsSQLite([]byte("Title,Author\n")))
+
+	parsed, err := ParseCalibre(path)
+	assert.Nil(t, err)
+	rows := parsed.Rows
+	assert.Len(t, rows, 2)
+
+	b := rows[0].Book
+	assert.Equal(t, rows[0].Line, 1)
+	assert.Equal(t, b.Title, "Book 1")
+	assert.Equal(t, b.Author, "Homer & Emily Wilson")
+	assert.Len(t, models.ParseCredits(b.Author), 2)
+	assert.Equal(t, b.Series, "Series 1")
+	assert.Equal(t, b.ISBN, "9780143039433")
+	assert.Equal(t, b.Status, models.STATUS_TO_READ)
+	assert.Equal(t, b.Comments, "First & best\n\nSecond\nline\n\nSeries index: 2.5\nTags: Classics, Fiction")
+	assert.Equal(t, rows[0].Warnings, []models.FieldError{{Field: "cover", Message: "Cover not imported"}})
+
+	b = rows[1].Book
+	assert.Equal(t, b.Author, "Emily Wilson")
+	assert.Equal(t, b.ISBN, "0143039433")
+	assert.Equal(t, b.Comments, "")
+	assert.Len(t, rows[1].Warnings, 0)
+
+	_, err = ParseCalibre(filepath.Join(t.TempDir(), "empty.db"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,192 @@
+package importer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	JOB_STATUS_QUEUED    = "queued"
+	JOB_STATUS_RUNNING   = "running"
+	JOB_STATUS_SUCCEEDED = "succeeded"
+	JOB_STATUS_FAILED    = "failed"
+	JOB_STATUS_CANCELED  = "canceled"
+)
+
+// Finished jobs are kept around for this long so that clients can pick up
+// the result, then dropped on the next Start.
+const JOB_RETENTION = time.Hour
+
+// Job is a snapshot of a background import. Total is known once the rows are
+// loaded. Result holds the progress so far while the job is running; it is
+// rolled back, and reset, if the job fails or is canceled.
+type Job struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Result     Result     `json:"result"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+func (j *Job) Finished() bool {
+	return j.FinishedAt != nil
+}
+
+type job struct {
+	Job
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Jobs runs imports in the background and keeps track of their progress.
+type Jobs struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func NewJobs() *Jobs {
+	return &Jobs{jobs: map[string]*job{}}
+}
+
+// Start loads the rows with load, e.g. by parsing an upload, and commits them
+// in a new goroutine. It returns the queued job.
+func (js *Jobs) Start(db *gorm.DB, load func() ([]Row, error), opts CommitOptions) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		Job: Job{
+			ID:        id,
+			Status:    JOB_STATUS_QUEUED,
+			Result:    Result{Conflicts: []Conflict{}, Errors: []RowError{}},
+			CreatedAt: time.Now(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	js.mu.Lock()
+	js.prune()
+	js.jobs[id] = j
+	snapshot := j.Job
+	js.mu.Unlock()
+
+	go js.run(ctx, j, db, load, opts)
+	return &snapshot, nil
+}
+
+// Get returns a snapshot of the job, nil when it doesn't exist.
+func (js *Jobs) Get(id string) *Job {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	j, ok := js.jobs[id]
+	if !ok {
+		return nil
+	}
+	snapshot := j.Job
+	return &snapshot
+}
+
+// Cancel stops the job and rolls back everything it imported. Canceling a
+// finished job is a no-op.
+func (js *Jobs) Cancel(id string) *Job {
+	js.mu.Lock()
+	j, ok := js.jobs[id]
+	js.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	j.cancel()
+	<-j.done
+	return js.Get(id)
+}
+
+// Wait blocks until the job is finished.
+func (js *Jobs) Wait(id string) *Job {
+	js.mu.Lock()
+	j, ok := js.jobs[id]
+	js.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	<-j.done
+	return js.Get(id)
+}
+
+func (js *Jobs) run(ctx context.Context, j *job, db *gorm.DB, load func() ([]Row, error), opts CommitOptions) {
+	defer close(j.done)
+	defer j.cancel()
+
+	js.update(j, func(j *Job) { j.Status = JOB_STATUS_RUNNING })
+
+	opts.Progress = func(processed int, r Result) {
+		js.update(j, func(j *Job) {
+			j.Processed = processed
+			j.Result = r
+		})
+	}
+
+	var r *Result
+	rows, err := load()
+	if err == nil {
+		js.update(j, func(j *Job) { j.Total = len(rows) })
+		r, err = Commit(ctx, db, rows, opts)
+	}
+	js.update(j, func(j *Job) {
+		now := time.Now()
+		j.FinishedAt = &now
+
+		switch {
+		case err == nil:
+			j.Status = JOB_STATUS_SUCCEEDED
+			j.Processed = j.Total
+			j.Result = *r
+		case errors.Is(err, context.Canceled):
+			j.Status = JOB_STATUS_CANCELED
+			j.Result = Result{Conflicts: []Conflict{}, Errors: []RowError{}}
+		default:
+			j.Status = JOB_STATUS_FAILED
+			j.Error = err.Error()
+			j.Result = Result{Conflicts: []Conflict{}, Errors: []RowError{}}
+		}
+	})
+}
+
+func (js *Jobs) update(j *job, fn func(*Job)) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	fn(&j.Job)
+}
+
+// prune drops jobs finished more than JOB_RETENTION ago. Must be called with
+// the lock held.
+func (js *Jobs) prune() {
+	for id, j := range js.jobs {
+		if j.Finished() && time.Since(*j.FinishedAt) > JOB_RETENTION {
+			delete(js.jobs, id)
+		}
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
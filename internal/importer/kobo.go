@@ -0,0 +1,332 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+	"waynezhang/buku/internal/repo/highlights"
+	"waynezhang/buku/internal/repo/progress"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ReadStatus of the Kobo content table.
+const (
+	KOBO_STATUS_UNREAD   = 0
+	KOBO_STATUS_READING  = 1
+	KOBO_STATUS_FINISHED = 2
+)
+
+// Documents recorded from a Kobo are named after the Kobo content ID, so
+// that they don't collide with the hashes KOReader syncs.
+const KOBO_DOCUMENT_PREFIX = "kobo:"
+
+var errNotKobo = errors.New("Not a Kobo database")
+
+// KoboBook is a book of a KoboReader.sqlite, not yet attached to a buku book.
+type KoboBook struct {
+	ContentID  string
+	Title      string
+	Author     string
+	ISBN       string
+	ReadStatus int
+	// Percentage is the percent read, between 0 and 1.
+	Percentage float64
+	// ReadingTime is the time spent reading in seconds.
+	ReadingTime int
+	LastRead    *time.Time
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+	Highlights  []models.Highlight
+}
+
+type KoboResult struct {
+	Total             int `json:"total"`
+	BooksMatched      int `json:"books_matched"`
+	BooksCreated      int `json:"books_created"`
+	BooksUpdated      int `json:"books_updated"`
+	BooksSkipped      int `json:"books_skipped"`
+	HighlightsCreated int `json:"highlights_created"`
+	HighlightsUpdated int `json:"highlights_updated"`
+	Duplicates        int `json:"duplicates"`
+}
+
+type koboContent struct {
+	ContentID               string
+	Title                   string
+	Attribution             string
+	ISBN                    string
+	ReadStatus              int
+	PercentRead             int
+	DateLastRead            string
+	TimeSpentReading        int
+	LastTimeStartedReading  string
+	LastTimeFinishedReading string
+}
+
+type koboBookmark struct {
+	VolumeID    string
+	Text        string
+	Annotation  string
+	DateCreated string
+	Type        string
+}
+
+// ParseKobo reads the books and highlights of the KoboReader.sqlite at path.
+// Columns added by later firmware are optional. Bookmarks without text, and
+// highlights hidden on the device, are dropped.
+func ParseKobo(path string) ([]KoboBook, error) {
+	db, err := gorm.Open(sqlite.Open("file:"+path+"?mode=ro"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		return nil, errNotKobo
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	columns := koboColumns(db, "content")
+	if !columns["ContentID"] || !columns["ReadStatus"] {
+		return nil, errNotKobo
+	}
+	contents := []koboContent{}
+	err = db.Raw(`SELECT ContentID AS content_id, ` +
+		koboColumn(columns, "Title", "title", "''") + `, ` +
+		koboColumn(columns, "Attribution", "attribution", "''") + `, ` +
+		koboColumn(columns, "ISBN", "isbn", "''") + `, ` +
+		koboColumn(columns, "ReadStatus", "read_status", "0") + `, ` +
+		koboColumn(columns, "___PercentRead", "percent_read", "0") + `, ` +
+		koboColumn(columns, "DateLastRead", "date_last_read", "''") + `, ` +
+		koboColumn(columns, "TimeSpentReading", "time_spent_reading", "0") + `, ` +
+		koboColumn(columns, "LastTimeStartedReading", "last_time_started_reading", "''") + `, ` +
+		koboColumn(columns, "LastTimeFinishedReading", "last_time_finished_reading", "''") + `
+		FROM content WHERE ContentType = '6' ORDER BY ContentID`).Scan(&contents).Error
+	if err != nil {
+		return nil, errNotKobo
+	}
+
+	list := []KoboBook{}
+	byID := map[string]int{}
+	for _, c := range contents {
+		kb := KoboBook{
+			ContentID:   c.ContentID,
+			Title:       strings.TrimSpace(c.Title),
+			Author:      strings.TrimSpace(c.Attribution),
+			ISBN:        models.NormalizeISBN(c.ISBN),
+			ReadStatus:  c.ReadStatus,
+			Percentage:  min(max(float64(c.PercentRead)/100, 0), 1),
+			ReadingTime: c.TimeSpentReading,
+			LastRead:    koboDate(c.DateLastRead),
+			StartedAt:   koboDate(c.LastTimeStartedReading),
+			FinishedAt:  koboDate(c.LastTimeFinishedReading),
+			Highlights:  []models.Highlight{},
+		}
+		// Finished books count as read through, wherever they were left open
+		if kb.ReadStatus == KOBO_STATUS_FINISHED {
+			kb.Percentage = 1
+		}
+		byID[kb.ContentID] = len(list)
+		list = append(list, kb)
+	}
+
+	columns = koboColumns(db, "Bookmark")
+	if !columns["VolumeID"] {
+		return list, nil
+	}
+	hidden := "1 = 1"
+	if columns["Hidden"] {
+		hidden = "COALESCE(Hidden, 'false') NOT IN ('true', '1')"
+	}
+	bookmarks := []koboBookmark{}
+	err = db.Raw(`SELECT VolumeID AS volume_id, ` +
+		koboColumn(columns, "Text", "text", "''") + `, ` +
+		koboColumn(columns, "Annotation", "annotation", "''") + `, ` +
+		koboColumn(columns, "DateCreated", "date_created", "''") + `, ` +
+		koboColumn(columns, "Type", "type", "''") + `
+		FROM Bookmark WHERE ` + hidden + ` ORDER BY DateCreated`).Scan(&bookmarks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bm := range bookmarks {
+		i, ok := byID[bm.VolumeID]
+		if !ok || bm.Type == "dogear" {
+			continue
+		}
+		added := koboDate(bm.DateCreated)
+		if text := strings.TrimSpace(bm.Text); len(text) > 0 {
+			list[i].Highlights = append(list[i].Highlights, models.Highlight{
+				Kind:    models.HIGHLIGHT_KIND_HIGHLIGHT,
+				Text:    text,
+				AddedAt: added,
+			})
+		}
+		if note := strings.TrimSpace(bm.Annotation); len(note) > 0 {
+			list[i].Highlights = append(list[i].Highlights, models.Highlight{
+				Kind:    models.HIGHLIGHT_KIND_NOTE,
+				Text:    note,
+				AddedAt: added,
+			})
+		}
+	}
+	return list, nil
+}
+
+// CommitKobo attaches the Kobo books to the books they match, by ISBN, then
+// fuzzily by title and author. Books opened or highlighted on the device are
+// created when they don't match. Matched books are only moved forward,
+// dated from the device, and highlights already imported are skipped.
+func CommitKobo(db *gorm.DB, list []KoboBook) (*KoboResult, error) {
+	r := KoboResult{Total: len(list)}
+	now := time.Now()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		library := books.GetAll(tx)
+
+		for _, kb := range list {
+			opened := kb.ReadStatus != KOBO_STATUS_UNREAD || kb.Percentage > 0
+			if len(kb.Title) == 0 || (!opened && len(kb.Highlights) == 0) {
+				r.BooksSkipped += 1
+				continue
+			}
+
+			var b *models.Book
+			if matched := books.GetByISBN(tx, kb.ISBN); len(matched) > 0 {
+				b = &matched[0]
+			} else {
+				b = matchBook(library, kb.Title, kb.Author)
+			}
+
+			if b == nil {
+				b = &models.Book{Title: kb.Title, Author: kb.Author, ISBN: kb.ISBN, Status: models.STATUS_TO_READ}
+				kb.apply(b, now)
+				if _, err := books.Create(tx, b); err != nil {
+					return err
+				}
+				library = append(library, *b)
+				r.BooksCreated += 1
+			} else {
+				r.BooksMatched += 1
+				if kb.apply(b, now) {
+					if _, err := books.Update(tx, b.ID, b); err != nil {
+						return err
+					}
+					r.BooksUpdated += 1
+				}
+			}
+
+			if opened {
+				p := &models.DocumentProgress{
+					Document:    KOBO_DOCUMENT_PREFIX + kb.ContentID,
+					BookID:      &b.ID,
+					Percentage:  kb.Percentage,
+					Device:      "Kobo",
+					ReadingTime: kb.ReadingTime,
+				}
+				if kb.LastRead != nil {
+					p.SyncedAt = *kb.LastRead
+				}
+				if _, err := progress.Record(tx, p); err != nil {
+					return err
+				}
+			}
+
+			existing := highlights.GetByBook(tx, b.ID)
+			for _, h := range kb.Highlights {
+				h.BookID = b.ID
+				action, err := saveHighlight(tx, &h, existing)
+				if err != nil {
+					return err
+				}
+				switch action {
+				case HIGHLIGHT_CREATED:
+					r.HighlightsCreated += 1
+				case HIGHLIGHT_UPDATED:
+					r.HighlightsUpdated += 1
+				case HIGHLIGHT_DUPLICATE:
+					r.Duplicates += 1
+				}
+				existing = append(existing, h)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// apply moves b forward to the reading state on the Kobo, dated from the
+// device when it recorded the dates. Books are never moved back. Returns
+// whether b changed.
+func (kb *KoboBook) apply(b *models.Book, now time.Time) bool {
+	switch {
+	case kb.ReadStatus == KOBO_STATUS_FINISHED && b.Status != models.STATUS_READ:
+		finished := firstDate(kb.FinishedAt, kb.LastRead, &now)
+		if b.StartedAt == nil {
+			b.StartedAt = firstDate(kb.StartedAt, finished)
+		}
+		if b.StartedAt.After(*finished) {
+			b.StartedAt = finished
+		}
+		b.FinishedAt = finished
+		b.Status = models.STATUS_READ
+	case kb.ReadStatus == KOBO_STATUS_READING && b.Status == models.STATUS_TO_READ:
+		b.StartedAt = firstDate(kb.StartedAt, kb.LastRead, &now)
+		b.FinishedAt = nil
+		b.Status = models.STATUS_READING
+	default:
+		return false
+	}
+	return true
+}
+
+func koboColumns(db *gorm.DB, table string) map[string]bool {
+	list := []struct{ Name string }{}
+	_ = db.Raw(fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table)).Scan(&list)
+
+	columns := map[string]bool{}
+	for _, c := range list {
+		columns[c.Name] = true
+	}
+	return columns
+}
+
+// koboColumn selects column as alias, or zero when the firmware which wrote
+// the database didn't have it.
+func koboColumn(columns map[string]bool, column string, alias string, zero string) string {
+	if columns[column] {
+		return "COALESCE(" + column + ", " + zero + ") AS " + alias
+	}
+	return zero + " AS " + alias
+}
+
+// koboDate parses the date of the timestamps Kobo firmware writes, such as
+// "2023-04-20T21:10:00Z", "2023-04-20T21:10:00.000" and
+// "2023-04-20 21:10:00.000+00:00".
+func koboDate(str string) *time.Time {
+	if len(str) < 10 {
+		return nil
+	}
+	t, err := time.Parse(time.DateOnly, str[:10])
+	if err != nil || t.Year() < 2000 {
+		return nil
+	}
+	return &t
+}
+
+func firstDate(dates ...*time.Time) *time.Time {
+	for _, d := range dates {
+		if d != nil {
+			return d
+		}
+	}
+	return nil
+}
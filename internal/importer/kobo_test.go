@@ -0,0 +1,94 @@
+package importer
+
+import (
+	"testing"
+	"time"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+	"waynezhang/buku/internal/repo/highlights"
+	"waynezhang/buku/internal/repo/progress"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Generated from testdata/kobo.sql
+const testKoboDB = "testdata/KoboReader.sqlite"
+
+func TestParseKobo(t *testing.T) {
+	list, err := ParseKobo(testKoboDB)
+	assert.Nil(t, err)
+	assert.Len(t, list, 4)
+
+	kb := list[0]
+	assert.Equal(t, kb.Title, "The Hobbit: or There and Back Again")
+	assert.Equal(t, kb.ReadStatus, KOBO_STATUS_READING)
+	assert.Equal(t, kb.Percentage, 0.35)
+	assert.Equal(t, kb.ReadingTime, 7200)
+	assert.Equal(t, kb.StartedAt.Format(time.DateOnly), "2023-05-01")
+	assert.Nil(t, kb.FinishedAt)
+	assert.Len(t, kb.Highlights, 1)
+
+	assert.Equal(t, list[1].Title, "Dune")
+	assert.Equal(t, list[1].ReadStatus, KOBO_STATUS_UNREAD)
+
+	kb = list[2]
+	assert.Equal(t, kb.Title, "1984")
+	assert.Equal(t, kb.Author, "George Orwell")
+	assert.Equal(t, kb.ISBN, "9780452284234")
+	assert.Equal(t, kb.ReadStatus, KOBO_STATUS_FINISHED)
+	assert.Equal(t, kb.Percentage, 1.0)
+	assert.Equal(t, kb.FinishedAt.Format(time.DateOnly), "2023-04-20")
+	assert.Len(t, kb.Highlights, 3)
+	assert.Equal(t, kb.Highlights[1].Text, "Big Brother is watching you.")
+	assert.Equal(t, kb.Highlights[2].Kind, models.HIGHLIGHT_KIND_NOTE)
+	assert.Equal(t, kb.Highlights[2].Text, "Thirteen!")
+
+	assert.Equal(t, list[3].Title, "Walden")
+	assert.Equal(t, list[3].LastRead.Format(time.DateOnly), "2022-09-10")
+
+	_, err = ParseKobo("testdata/kobo.sql")
+	assert.NotNil(t, err)
+}
+
+func TestCommitKobo(t *testing.T) {
+	db := testDB()
+	started := time.Date(2023, 3, 30, 0, 0, 0, 0, time.UTC)
+	orwell, _ := books.Create(db, &models.Book{Title: "Nineteen Eighty-Four", ISBN: "0452284236"})
+	orwell, _ = books.Create(db, &models.Book{Title: "1984", Author: "Orwell", ISBN: "9780452284234", StartedAt: &started})
+	walden, _ := books.Create(db, &models.Book{Title: "Walden", Author: "Thoreau", Status: models.STATUS_READ})
+	_, _ = highlights.Create(db, &models.Highlight{BookID: orwell.ID, Kind: models.HIGHLIGHT_KIND_HIGHLIGHT, Text: "It was a bright cold day in April"})
+
+	list, _ := ParseKobo(testKoboDB)
+	r, err := CommitKobo(db, list)
+	assert.Nil(t, err)
+	assert.Equal(t, *r, KoboResult{Total: 4, BooksMatched: 2, BooksCreated: 1, BooksUpdated: 1, BooksSkipped: 1, HighlightsCreated: 3, HighlightsUpdated: 1})
+
+	b := books.GetByID(db, orwell.ID)
+	assert.Equal(t, b.Status, models.STATUS_READ)
+	assert.Equal(t, b.StartedAt.Format(time.DateOnly), "2023-03-30")
+	assert.Equal(t, b.FinishedAt.Format(time.DateOnly), "2023-04-20")
+	hs := highlights.GetByBook(db, orwell.ID)
+	assert.Len(t, hs, 3)
+	assert.Contains(t, hs[0].Text, "thirteen")
+
+	// Read books stay read
+	assert.Equal(t, books.GetByID(db, walden.ID).Status, models.STATUS_READ)
+	docs := progress.GetByBook(db, walden.ID)
+	assert.Len(t, docs, 1)
+	assert.Equal(t, docs[0].Document, "kobo:file:///mnt/onboard/Walden.epub")
+	assert.Equal(t, docs[0].Percentage, 0.12)
+	assert.Equal(t, docs[0].ReadingTime, 1800)
+
+	hobbit := books.GetByTitleAndAuthor(db, "The Hobbit: or There and Back Again", "J. R. R. Tolkien")
+	assert.Len(t, hobbit, 1)
+	assert.Equal(t, hobbit[0].Status, models.STATUS_READING)
+	assert.Equal(t, hobbit[0].StartedAt.Format(time.DateOnly), "2023-05-01")
+	assert.Len(t, books.GetByTitleAndAuthor(db, "Dune", "Frank Herbert"), 0)
+
+	// Importing the same database again changes nothing
+	r, err = CommitKobo(db, list)
+	assert.Nil(t, err)
+	assert.Equal(t, *r, KoboResult{Total: 4, BooksMatched: 3, BooksSkipped: 1, Duplicates: 4})
+	assert.Len(t, books.GetAll(db), 4)
+	assert.Len(t, progress.GetAll(db), 3)
+}
@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"waynezhang/buku/internal/models"
+)
+
+// Row is the draft built from one CSV record together with everything the
+// user should know before importing it.
+type Row struct {
+	Line            int                    `json:"line"`
+	Book            models.Book            `json:"book"`
+	Errors          models.ValidationError `json:"errors"`
+	Warnings        []models.FieldError    `json:"warnings"`
+	DuplicateIDs    []uint                 `json:"duplicate_ids"`
+	DuplicateOfLine int                    `json:"duplicate_of_line"`
+}
+
+// Valid reports whether the row can be imported at all.
+func (r *Row) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Duplicate reports whether the row looks like a book already in the
+// library or an earlier row of the same file.
+func (r *Row) Duplicate() bool {
+	return len(r.DuplicateIDs) > 0 || r.DuplicateOfLine > 0
+}
+
+type Preview struct {
+	Total       int               `json:"total"`
+	Valid       int               `json:"valid"`
+	Invalid     int               `json:"invalid"`
+	Duplicates  int               `json:"duplicates"`
+	DateFormats map[string]string `json:"date_formats"`
+	Rows        []Row             `json:"rows"`
+}
+
+func NewPreview(parsed *Parsed) *Preview {
+	rows := parsed.Rows
+	p := Preview{Rows: rows, DateFormats: parsed.DateFormats}
+	for i := range rows {
+		p.Total += 1
+		if rows[i].Valid() {
+			p.Valid += 1
+		} else {
+			p.Invalid += 1
+		}
+		if rows[i].Duplicate() {
+			p.Duplicates += 1
+		}
+	}
+	return &p
+}
@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"strings"
+	"waynezhang/buku/internal/models"
+)
+
+// DefaultStatusRules maps the status values used by common trackers and
+// spreadsheets. Keys are lower case.
+var DefaultStatusRules = map[string]string{
+	"to-read":           models.STATUS_TO_READ,
+	"to read":           models.STATUS_TO_READ,
+	"want to read":      models.STATUS_TO_READ,
+	"want-to-read":      models.STATUS_TO_READ,
+	"tbr":               models.STATUS_TO_READ,
+	"unread":            models.STATUS_TO_READ,
+	"reading":           models.STATUS_READING,
+	"currently reading": models.STATUS_READING,
+	"currently-reading": models.STATUS_READING,
+	"in progress":       models.STATUS_READING,
+	"read":              models.STATUS_READ,
+	"finished":          models.STATUS_READ,
+	"completed":         models.STATUS_READ,
+	"done":              models.STATUS_READ,
+}
+
+// lookupStatus maps a status column value with rules, falling back to
+// DefaultStatusRules. Matching is case-insensitive.
+func lookupStatus(rules map[string]string, value string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(value))
+	for k, v := range rules {
+		if strings.ToLower(strings.TrimSpace(k)) == key {
+			return v, true
+		}
+	}
+	status, ok := DefaultStatusRules[key]
+	return status, ok
+}
+
+// applyStatus sets the status of the row from the status column value. When
+// the dates say otherwise opts.StatusConflict decides which one wins, and a
+// warning explains what was dropped.
+func (row *Row) applyStatus(value string, opts Options) {
+	if len(value) == 0 {
+		return
+	}
+
+	status, ok := lookupStatus(opts.StatusRules, value)
+	if !ok {
+		row.Warnings = append(row.Warnings, models.FieldError{
+			Field:   "status",
+			Message: "Unknown status \"" + value + "\"",
+		})
+		return
+	}
+
+	b := &row.Book
+	fromDates := models.Book{StartedAt: b.StartedAt, FinishedAt: b.FinishedAt}
+	fromDates.FixStatus()
+	if b.StartedAt == nil && b.FinishedAt == nil || fromDates.Status == status {
+		b.Status = status
+		return
+	}
+
+	if opts.StatusConflict != models.STATUS_CONFLICT_STATUS {
+		row.Warnings = append(row.Warnings, models.FieldError{
+			Field:   "status",
+			Message: "Status \"" + value + "\" ignored, the dates say " + fromDates.Status,
+		})
+		return
+	}
+
+	b.Status = status
+	switch status {
+	case models.STATUS_TO_READ:
+		b.StartedAt = nil
+		b.FinishedAt = nil
+		row.Warnings = append(row.Warnings, models.FieldError{
+			Field:   "status",
+			Message: "Dates dropped, the status is " + status,
+		})
+	case models.STATUS_READING:
+		b.FinishedAt = nil
+		row.Warnings = append(row.Warnings, models.FieldError{
+			Field:   "finished_at",
+			Message: "Finish date dropped, the status is " + status,
+		})
+	case models.STATUS_READ:
+		b.FinishedAt = b.StartedAt
+		row.Warnings = append(row.Warnings, models.FieldError{
+			Field:   "finished_at",
+			Message: "Finish date missing, using the start date",
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package importer
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"waynezhang/buku/internal/models"
+)
+
+// Columns of a StoryGraph export used to recognise it.
+var storyGraphColumns = []string{"Title", "Authors", "Read Status", "Dates Read", "Star Rating"}
+
+// storyGraphStatusRules maps the StoryGraph read statuses which aren't in
+// DefaultStatusRules. Unfinished books go back on the to-read list rather
+// than counting as read.
+var storyGraphStatusRules = map[string]string{
+	"did-not-finish": models.STATUS_TO_READ,
+	"paused":         models.STATUS_READING,
+}
+
+// IsStoryGraph reports whether header is the one of a StoryGraph export.
+func IsStoryGraph(header []string) bool {
+	for _, column := range storyGraphColumns {
+		if !slices.Contains(header, column) {
+			return false
+		}
+	}
+	return true
+}
+
+type readSession struct {
+	started  *time.Time
+	finished *time.Time
+}
+
+// ParseStoryGraph reads a StoryGraph export. Finished books keep the most
+// recent read session; the review, rating, tags, moods, format, earlier
+// sessions and unfinished ones, which buku has no field for, are kept in
+// the comments.
+func ParseStoryGraph(r *csv.Reader) (*Parsed, error) {
+	columns, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	idx := func(name string) int { return slices.Index(columns, name) }
+	titleIdx := idx("Title")
+	authorsIdx := idx("Authors")
+	isbnIdx := idx("ISBN/UID")
+	formatIdx := idx("Format")
+	statusIdx := idx("Read Status")
+	lastReadIdx := idx("Last Date Read")
+	datesReadIdx := idx("Dates Read")
+	readCountIdx := idx("Read Count")
+	moodsIdx := idx("Moods")
+	ratingIdx := idx("Star Rating")
+	reviewIdx := idx("Review")
+	tagsIdx := idx("Tags")
+
+	p := Parsed{
+		Rows: []Row{},
+		DateFormats: map[string]string{
+			"started_at":  DATE_FORMAT_YMD,
+			"finished_at": DATE_FORMAT_YMD,
+		},
+	}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+
+		row := Row{
+			Line:         line,
+			Warnings:     []models.FieldError{},
+			DuplicateIDs: []uint{},
+		}
+		b := &row.Book
+		b.Title = getStrVal(titleIdx, rec)
+		b.Author = getStrVal(authorsIdx, rec)
+		if isbn := models.NormalizeISBN(getStrVal(isbnIdx, rec)); len(isbn) == 10 || len(isbn) == 13 {
+			b.ISBN = isbn
+		}
+
+		sessions := row.parseSessions(getStrVal(datesReadIdx, rec))
+		if len(sessions) == 0 {
+			if finished := row.parseDate("finished_at", getStrVal(lastReadIdx, rec), DATE_FORMAT_YMD); finished != nil {
+				sessions = append(sessions, readSession{finished: finished})
+			}
+		}
+		status := getStrVal(statusIdx, rec)
+		notes := []string{}
+		if status == "did-not-finish" {
+			// The dates of the abandoned session would make the book read
+			abandoned := "Did not finish"
+			if len(sessions) > 0 {
+				abandoned += ": " + sessions[len(sessions)-1].String()
+			}
+			notes = append(notes, abandoned)
+		} else if len(sessions) > 0 {
+			last := sessions[len(sessions)-1]
+			b.StartedAt = last.started
+			b.FinishedAt = last.finished
+		}
+
+		row.applyStatus(status, Options{StatusRules: storyGraphStatusRules})
+		b.FixStatus()
+
+		if rating := getStrVal(ratingIdx, rec); len(rating) > 0 {
+			notes = append(notes, "Rating: "+rating)
+		}
+		if tags := getStrVal(tagsIdx, rec); len(tags) > 0 {
+			notes = append(notes, "Tags: "+tags)
+		}
+		if moods := getStrVal(moodsIdx, rec); len(moods) > 0 {
+			notes = append(notes, "Moods: "+moods)
+		}
+		if format := getStrVal(formatIdx, rec); len(format) > 0 {
+			notes = append(notes, "Format: "+format)
+		}
+		if count := getStrVal(readCountIdx, rec); len(count) > 0 && count != "0" && count != "1" {
+			notes = append(notes, "Read count: "+count)
+		}
+		if len(sessions) > 1 {
+			earlier := []string{}
+			for _, s := range sessions[:len(sessions)-1] {
+				earlier = append(earlier, s.String())
+			}
+			notes = append(notes, "Earlier reads: "+strings.Join(earlier, ", "))
+		}
+
+		comments := []string{}
+		if review := getStrVal(reviewIdx, rec); len(review) > 0 {
+			comments = append(comments, review)
+		}
+		if len(notes) > 0 {
+			comments = append(comments, strings.Join(notes, "\n"))
+		}
+		b.Comments = strings.Join(comments, "\n\n")
+
+		row.Errors = b.ValidateFields()
+		p.Rows = append(p.Rows, row)
+	}
+	return &p, nil
+}
+
+// parseSessions parses "Dates Read", a comma separated list of
+// "2024/01/05-2024/01/20" ranges or single finish dates, oldest first.
+func (row *Row) parseSessions(str string) []readSession {
+	sessions := []readSession{}
+	for _, part := range strings.Split(str, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		s := readSession{}
+		start, end, isRange := strings.Cut(part, "-")
+		if _, ok := parseDateAs(DATE_FORMAT_ISO, part); ok {
+			isRange = false
+		}
+		if isRange {
+			s.started = row.parseDate("started_at", strings.TrimSpace(start), DATE_FORMAT_YMD)
+			s.finished = row.parseDate("finished_at", strings.TrimSpace(end), DATE_FORMAT_YMD)
+		} else {
+			s.finished = row.parseDate("finished_at", part, DATE_FORMAT_YMD)
+		}
+		if s.started != nil || s.finished != nil {
+			sessions = append(sessions, s)
+		}
+	}
+
+	slices.SortStableFunc(sessions, func(a, b readSession) int {
+		return a.date().Compare(b.date())
+	})
+	return sessions
+}
+
+func (s readSession) date() time.Time {
+	if s.finished != nil {
+		return *s.finished
+	}
+	return *s.started
+}
+
+func (s readSession) String() string {
+	format := func(t *time.Time) string {
+		if t == nil {
+			return "?"
+		}
+		return t.Format("2006-01-02")
+	}
+	if s.started == nil {
+		return format(s.finished)
+	}
+	return format(s.started) + " – " + format(s.finished)
+}
@@ -0,0 +1,61 @@
+-- Fixture for TestParseKobo and TestCommitKobo, a trimmed KoboReader.sqlite.
+-- Regenerate with: sqlite3 KoboReader.sqlite < kobo.sql
+CREATE TABLE content (
+	ContentID TEXT NOT NULL,
+	ContentType TEXT NOT NULL,
+	MimeType TEXT NOT NULL,
+	BookID TEXT,
+	BookTitle TEXT,
+	Title TEXT,
+	Attribution TEXT,
+	ISBN TEXT,
+	DateLastRead TEXT,
+	ReadStatus INTEGER,
+	___PercentRead INTEGER,
+	TimeSpentReading INTEGER DEFAULT 0,
+	LastTimeStartedReading TEXT,
+	LastTimeFinishedReading TEXT,
+	PRIMARY KEY (ContentID)
+);
+CREATE TABLE Bookmark (
+	BookmarkID TEXT NOT NULL,
+	VolumeID TEXT NOT NULL,
+	ContentID TEXT NOT NULL,
+	Text TEXT,
+	Annotation TEXT,
+	DateCreated TEXT,
+	DateModified TEXT,
+	Hidden BOOL DEFAULT 'false' NOT NULL,
+	Type TEXT,
+	PRIMARY KEY (BookmarkID)
+);
+
+INSERT INTO content VALUES
+	('file:///mnt/onboard/Orwell/1984.epub', '6', 'application/epub+zip', NULL, NULL,
+	 '1984', 'George Orwell', '978-0-452-28423-4', '2023-04-20T21:10:00Z', 2, 100, 36000,
+	 '2023-04-01T20:00:00Z', '2023-04-20T21:10:00Z'),
+	('file:///mnt/onboard/Orwell/1984.epub#(1)OEBPS/ch1.html', '9', 'application/xhtml+xml',
+	 'file:///mnt/onboard/Orwell/1984.epub', '1984', 'Part One', NULL, NULL, NULL, 0, 0, 0, NULL, NULL),
+	('0b3c4e5f-hobbit', '6', 'application/x-kobo-epub+zip', NULL, NULL,
+	 'The Hobbit: or There and Back Again', 'J. R. R. Tolkien', '', '2023-05-02T08:00:00.000', 1, 35, 7200,
+	 '2023-05-01T19:30:00.000', NULL),
+	('file:///mnt/onboard/Dune.epub', '6', 'application/epub+zip', NULL, NULL,
+	 'Dune', 'Frank Herbert', NULL, NULL, 0, 0, 0, NULL, NULL),
+	('file:///mnt/onboard/Walden.epub', '6', 'application/epub+zip', NULL, NULL,
+	 'Walden', 'Henry David Thoreau', NULL, '2022-09-10 12:00:00.000+00:00', 1, 12, 1800,
+	 NULL, NULL);
+
+INSERT INTO Bookmark VALUES
+	('b1', 'file:///mnt/onboard/Orwell/1984.epub', 'file:///mnt/onboard/Orwell/1984.epub#(1)OEBPS/ch1.html',
+	 'It was a bright cold day in April, and the clocks were striking thirteen.', NULL,
+	 '2023-04-01T20:05:00Z', NULL, 'false', 'highlight'),
+	('b2', 'file:///mnt/onboard/Orwell/1984.epub', 'file:///mnt/onboard/Orwell/1984.epub#(1)OEBPS/ch1.html',
+	 'Big Brother is watching you.', 'Thirteen!',
+	 '2023-04-01T20:10:00Z', NULL, 'false', 'note'),
+	('b3', 'file:///mnt/onboard/Orwell/1984.epub', 'file:///mnt/onboard/Orwell/1984.epub#(1)OEBPS/ch1.html',
+	 NULL, NULL, '2023-04-02T20:00:00Z', NULL, 'false', 'dogear'),
+	('b4', 'file:///mnt/onboard/Orwell/1984.epub', 'file:///mnt/onboard/Orwell/1984.epub#(1)OEBPS/ch1.html',
+	 'Deleted on the device.', NULL, '2023-04-03T20:00:00Z', NULL, 'true', 'highlight'),
+	('b5', '0b3c4e5f-hobbit', '0b3c4e5f-hobbit!OEBPS!ch1.xhtml',
+	 'In a hole in the ground there lived a hobbit.', NULL,
+	 '2023-05-01T19:35:00.000', NULL, 'false', 'highlight');
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/joho/godotenv"
@@ -15,24 +16,49 @@ type Config struct {
 	Password          string
 	AuthDisabled      bool
 	GoogleBooksAPIKey string
+
+	// Security
+	ContentSecurityPolicy string
+	FrameAncestors        string
+	ReferrerPolicy        string
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	CookieSecure          bool
 }
 
+const defaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'unsafe-inline' 'unsafe-eval' https://cdn.tailwindcss.com https://cdn.jsdelivr.net https://unpkg.com; " +
+	"style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data: https:; " +
+	// The service worker, which gets this policy too, fetches the CDN scripts
+	// to cache them
+	"connect-src 'self' https://cdn.tailwindcss.com https://cdn.jsdelivr.net https://unpkg.com; " +
+	"object-src 'none'; " +
+	"base-uri 'self'"
+
 func Load() *Config {
 	_ = godotenv.Load(".env")
-	
+
 	// Check if auth environment variables are set
 	_, usernameSet := os.LookupEnv("BUKU_USERNAME")
 	_, passwordSet := os.LookupEnv("BUKU_PASSWORD")
 	authDisabled := !usernameSet && !passwordSet
-	
+
 	config := Config{
-		DatabasePath: getEnv("DB_PATH", "./db.sqlite"),
-		Debug:        getEnv("DEBUG", "false") == "true",
-		ListenPort:   getEnv("LISTEN_PORT", ":9000"),
+		DatabasePath:      getEnv("DB_PATH", "./db.sqlite"),
+		Debug:             getEnv("DEBUG", "false") == "true",
+		ListenPort:        getEnv("LISTEN_PORT", ":9000"),
 		Username:          getEnv("BUKU_USERNAME", "admin"),
 		Password:          getEnv("BUKU_PASSWORD", "password"),
 		AuthDisabled:      authDisabled,
 		GoogleBooksAPIKey: getEnv("GOOGLE_BOOKS_API_KEY", ""),
+
+		ContentSecurityPolicy: getEnv("SECURITY_CSP", defaultContentSecurityPolicy),
+		FrameAncestors:        getEnv("SECURITY_FRAME_ANCESTORS", "'none'"),
+		ReferrerPolicy:        getEnv("SECURITY_REFERRER_POLICY", "same-origin"),
+		HSTSMaxAge:            getEnvInt("SECURITY_HSTS_MAX_AGE", 0),
+		HSTSIncludeSubdomains: getEnv("SECURITY_HSTS_INCLUDE_SUBDOMAINS", "false") == "true",
+		CookieSecure:          getEnv("COOKIE_SECURE", "false") == "true",
 	}
 	log.Debugf("Config: DatabasePath=%s, Debug=%t, ListenPort=%s, Username=%s, AuthDisabled=%t",
 		config.DatabasePath, config.Debug, config.ListenPort, config.Username, config.AuthDisabled)
@@ -47,3 +73,11 @@ func getEnv(key string, fallback string) string {
 	}
 	return value
 }
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
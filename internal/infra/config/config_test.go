@@ -1,19 +1,37 @@
 package config
 
 import (
-	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestLoad(t *testing.T) {
-	os.Setenv("DB_PATH", "/db-path")
-	os.Setenv("DEBUG", "true")
-	os.Setenv("LISTEN_PORT", ":9999")
+	t.Setenv("DB_PATH", "/db-path")
+	t.Setenv("DEBUG", "true")
+	t.Setenv("LISTEN_PORT", ":9999")
 
 	c := Load()
 	assert.Equal(t, c.DatabasePath, "/db-path")
 	assert.Equal(t, c.Debug, true)
 	assert.Equal(t, c.ListenPort, ":9999")
 }
+
+func TestLoadSecurity(t *testing.T) {
+	t.Setenv("SECURITY_FRAME_ANCESTORS", "'self'")
+	t.Setenv("SECURITY_HSTS_MAX_AGE", "31536000")
+	t.Setenv("SECURITY_HSTS_INCLUDE_SUBDOMAINS", "true")
+	t.Setenv("COOKIE_SECURE", "true")
+
+	c := Load()
+	assert.Contains(t, c.ContentSecurityPolicy, "default-src 'self'")
+	assert.Equal(t, c.FrameAncestors, "'self'")
+	assert.Equal(t, c.ReferrerPolicy, "same-origin")
+	assert.Equal(t, c.HSTSMaxAge, 31536000)
+	assert.Equal(t, c.HSTSIncludeSubdomains, true)
+	assert.Equal(t, c.CookieSecure, true)
+
+	t.Setenv("SECURITY_HSTS_MAX_AGE", "forever")
+	c = Load()
+	assert.Equal(t, c.HSTSMaxAge, 0)
+}
@@ -8,12 +8,12 @@ import (
 )
 
 func Load(path string) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(path))
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&models.Book{})
+	err = db.AutoMigrate(&models.Book{}, &models.Share{}, &models.ImportPreset{}, &models.Highlight{}, &models.DocumentProgress{}, &models.Author{}, &models.BookAuthor{})
 	if err != nil {
 		return nil, err
 	}
@@ -21,5 +21,9 @@ func Load(path string) (*gorm.DB, error) {
 }
 
 func Nuke(db *gorm.DB) {
+	db.Where("true").Delete(&models.Highlight{})
+	db.Where("true").Delete(&models.BookAuthor{})
+	db.Where("true").Delete(&models.Author{})
+	db.Model(&models.DocumentProgress{}).Where("true").Update("book_id", nil)
 	db.Where("true").Delete(&models.Book{})
 }
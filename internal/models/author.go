@@ -0,0 +1,106 @@
+package models
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Roles of the people credited on a book.
+const (
+	AUTHOR_ROLE_AUTHOR      = "author"
+	AUTHOR_ROLE_EDITOR      = "editor"
+	AUTHOR_ROLE_TRANSLATOR  = "translator"
+	AUTHOR_ROLE_ILLUSTRATOR = "illustrator"
+)
+
+var AuthorRoles = []string{AUTHOR_ROLE_AUTHOR, AUTHOR_ROLE_EDITOR, AUTHOR_ROLE_TRANSLATOR, AUTHOR_ROLE_ILLUSTRATOR}
+
+// Author is a person credited on books, shared by all of their books.
+type Author struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name" gorm:"uniqueIndex"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BookAuthor credits an author on a book with a role. Position is the order
+// of the credit on the book.
+type BookAuthor struct {
+	BookID   uint   `json:"book_id" gorm:"primaryKey"`
+	AuthorID uint   `json:"author_id" gorm:"primaryKey;index"`
+	Role     string `json:"role" gorm:"primaryKey"`
+	Position int    `json:"position"`
+}
+
+// Credit is a person credited on a book, by name.
+type Credit struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// Role suffixes of the names of the author of a book, such as "Emily Wilson
+// (translator)". Names without one are authors.
+var creditRole = regexp.MustCompile(`(?i)^(.*?)\s*\((translator|translated|trans\.?|tr\.?|editor|edited|eds?\.?|illustrator|illustrated|illus\.?|ill\.?)\)$`)
+
+// ParseCredits splits the author of a book into credits, as SplitAuthors
+// does, reading the role of each name from its suffix. Names credited twice
+// with the same role are dropped.
+func ParseCredits(author string) []Credit {
+	credits := []Credit{}
+	for _, name := range SplitAuthors(author) {
+		c := Credit{Name: name, Role: AUTHOR_ROLE_AUTHOR}
+		if m := creditRole.FindStringSubmatch(name); m != nil && len(m[1]) > 0 {
+			c.Name = m[1]
+			c.Role = roleOf(m[2])
+		}
+		if !slices.ContainsFunc(credits, func(o Credit) bool {
+			return o.Role == c.Role && strings.EqualFold(o.Name, c.Name)
+		}) {
+			credits = append(credits, c)
+		}
+	}
+	return credits
+}
+
+// FormatCredits joins credits into the author of a book, which
+// ParseCredits reads back.
+func FormatCredits(credits []Credit) string {
+	names := []string{}
+	for _, c := range credits {
+		name := strings.TrimSpace(c.Name)
+		if len(name) == 0 {
+			continue
+		}
+		if len(c.Role) > 0 && c.Role != AUTHOR_ROLE_AUTHOR {
+			name += " (" + c.Role + ")"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, " & ")
+}
+
+// ValidateCredits checks the names and roles of credits.
+func ValidateCredits(credits []Credit) ValidationError {
+	errors := ValidationError{}
+	for _, c := range credits {
+		if len(strings.TrimSpace(c.Name)) == 0 {
+			errors = append(errors, FieldError{"credits", "Author name is required"})
+		} else if len(c.Role) > 0 && !slices.Contains(AuthorRoles, c.Role) {
+			errors = append(errors, FieldError{"credits", "Author role is invalid"})
+		}
+	}
+	return errors
+}
+
+func roleOf(suffix string) string {
+	switch strings.ToLower(suffix)[:2] {
+	case "tr":
+		return AUTHOR_ROLE_TRANSLATOR
+	case "ed":
+		return AUTHOR_ROLE_EDITOR
+	case "il":
+		return AUTHOR_ROLE_ILLUSTRATOR
+	}
+	return AUTHOR_ROLE_AUTHOR
+}
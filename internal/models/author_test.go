@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCredits(t *testing.T) {
+	assert.Equal(t, ParseCredits("George Orwell"), []Credit{{"George Orwell", AUTHOR_ROLE_AUTHOR}})
+	assert.Equal(t, ParseCredits("Homer & Emily Wilson (translator)"), []Credit{
+		{"Homer", AUTHOR_ROLE_AUTHOR},
+		{"Emily Wilson", AUTHOR_ROLE_TRANSLATOR},
+	})
+	assert.Equal(t, ParseCredits("Ann VanderMeer (ed.); Jeff VanderMeer (Editor); Ann Vandermeer (eds.)"), []Credit{
+		{"Ann VanderMeer", AUTHOR_ROLE_EDITOR},
+		{"Jeff VanderMeer", AUTHOR_ROLE_EDITOR},
+	})
+	assert.Equal(t, ParseCredits("Roald Dahl & Quentin Blake (illus.)")[1], Credit{"Quentin Blake", AUTHOR_ROLE_ILLUSTRATOR})
+	assert.Equal(t, ParseCredits("The Anonymous (Collective)"), []Credit{{"The Anonymous (Collective)", AUTHOR_ROLE_AUTHOR}})
+	assert.Equal(t, ParseCredits(""), []Credit{})
+}
+
+func TestFormatCredits(t *testing.T) {
+	credits := []Credit{{"Homer", AUTHOR_ROLE_AUTHOR}, {"Emily Wilson", AUTHOR_ROLE_TRANSLATOR}, {" ", ""}}
+	assert.Equal(t, FormatCredits(credits), "Homer & Emily Wilson (translator)")
+	assert.Equal(t, ParseCredits(FormatCredits(credits)), credits[:2])
+}
+
+func TestValidateCredits(t *testing.T) {
+	assert.Len(t, ValidateCredits([]Credit{{"Homer", ""}, {"Emily Wilson", AUTHOR_ROLE_TRANSLATOR}}), 0)
+	errs := ValidateCredits([]Credit{{"", AUTHOR_ROLE_AUTHOR}, {"Homer", "narrator"}})
+	assert.Equal(t, errs.Messages(), []string{"Author name is required", "Author role is invalid"})
+}
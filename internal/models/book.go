@@ -1,6 +1,8 @@
 package models
 
 import (
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -11,6 +13,8 @@ const (
 	STATUS_READ    = "read"
 )
 
+var Statuses = []string{STATUS_TO_READ, STATUS_READING, STATUS_READ}
+
 type Book struct {
 	ID         uint       `json:"id"`
 	Title      string     `json:"title"`
@@ -23,24 +27,38 @@ type Book struct {
 	FinishedAt *time.Time `json:"finished_at" gorm:"type:date"`
 	CreatedAt  time.Time  `json:"created_at"`
 	UpdatedAt  time.Time  `json:"updated_at"`
+	// Credits are the people credited on the book, which Author is the
+	// credit line of. They are only loaded by the book API, and replace
+	// Author when given to books.Create or books.Update.
+	Credits []Credit `json:"credits,omitempty" gorm:"-"`
 }
 
 func (b *Book) Validate() []string {
-	errors := []string{}
+	return b.ValidateFields().Messages()
+}
+
+func (b *Book) ValidateFields() ValidationError {
+	errors := ValidationError{}
 
 	if len(strings.TrimSpace(b.Title)) == 0 {
-		errors = append(errors, "Title is required")
+		errors = append(errors, FieldError{"title", "Title is required"})
 	}
 	if b.StartedAt != nil && b.FinishedAt != nil && b.StartedAt.After(*b.FinishedAt) {
-		errors = append(errors, "Date format is invalid")
+		errors = append(errors, FieldError{"finished_at", "Date format is invalid"})
 	}
+	errors = append(errors, ValidateCredits(b.Credits)...)
 
 	return errors
 }
 
+// FixStatus derives the status from the dates. Without any date an explicit
+// read or reading status is kept, so that books can be recorded without
+// knowing when they were read.
 func (b *Book) FixStatus() {
 	if b.StartedAt == nil && b.FinishedAt == nil {
-		b.Status = STATUS_TO_READ
+		if b.Status != STATUS_READ && b.Status != STATUS_READING {
+			b.Status = STATUS_TO_READ
+		}
 	} else if b.StartedAt != nil && b.FinishedAt == nil {
 		b.Status = STATUS_READING
 	} else if b.StartedAt == nil && b.FinishedAt != nil {
@@ -50,3 +68,81 @@ func (b *Book) FixStatus() {
 		b.Status = STATUS_READ
 	}
 }
+
+// NormalizeISBN strips separators so that "978-0-14-303943-3" and
+// "9780143039433" compare equal.
+func NormalizeISBN(isbn string) string {
+	isbn = strings.ReplaceAll(isbn, "-", "")
+	isbn = strings.ReplaceAll(isbn, " ", "")
+	return strings.ToUpper(isbn)
+}
+
+// ISBN13 returns the normalized isbn as an ISBN-13, converting ISBN-10s. It
+// returns "" for anything else.
+func ISBN13(isbn string) string {
+	isbn = NormalizeISBN(isbn)
+	switch {
+	case len(isbn) == 13 && isDigits(isbn):
+		return isbn
+	case len(isbn) == 10 && isDigits(isbn[:9]):
+		isbn = "978" + isbn[:9]
+		sum := 0
+		for i, r := range isbn {
+			d := int(r - '0')
+			if i%2 == 1 {
+				d *= 3
+			}
+			sum += d
+		}
+		return isbn + strconv.Itoa((10-sum%10)%10)
+	}
+	return ""
+}
+
+// ISBN10 returns the normalized isbn as an ISBN-10, converting ISBN-13s
+// starting with 978. It returns "" for anything else, as 979 ISBNs have no
+// ISBN-10.
+func ISBN10(isbn string) string {
+	isbn = NormalizeISBN(isbn)
+	switch {
+	case len(isbn) == 10 && isDigits(isbn[:9]):
+		return isbn
+	case len(isbn) == 13 && isDigits(isbn) && strings.HasPrefix(isbn, "978"):
+		isbn = isbn[3:12]
+		sum := 0
+		for i, r := range isbn {
+			sum += (10 - i) * int(r-'0')
+		}
+		check := (11 - sum%11) % 11
+		if check == 10 {
+			return isbn + "X"
+		}
+		return isbn + strconv.Itoa(check)
+	}
+	return ""
+}
+
+func isDigits(str string) bool {
+	for _, r := range str {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+var authorSeparators = regexp.MustCompile(`\s*[&;]\s*`)
+
+// SplitAuthors splits the names of the co-authors of author, such as
+// "Neil Gaiman & Terry Pratchett". Only "&" and ";" separate names: commas
+// are kept, as in "Le Guin, Ursula K.", and so are "and" and "with", which
+// are part of names such as "Simon and Schuster".
+func SplitAuthors(author string) []string {
+	names := []string{}
+	for _, name := range authorSeparators.Split(author, -1) {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -44,6 +44,11 @@ func TestFixStatus(t *testing.T) {
 	assert.Nil(t, b.FinishedAt)
 	assert.Equal(t, b.Status, STATUS_TO_READ)
 
+	// Undated books keep an explicit status
+	b.Status = STATUS_READ
+	b.FixStatus()
+	assert.Equal(t, b.Status, STATUS_READ)
+
 	b.Status = ""
 	b.StartedAt = &now
 	b.FixStatus()
@@ -67,3 +72,62 @@ func TestFixStatus(t *testing.T) {
 	assert.NotNil(t, b.FinishedAt)
 	assert.Equal(t, b.Status, STATUS_READ)
 }
+
+func TestValidateFields(t *testing.T) {
+	b := Book{}
+
+	errs := b.ValidateFields()
+	assert.Len(t, errs, 1)
+	assert.Equal(t, errs[0].Field, "title")
+	assert.Equal(t, errs.Error(), "Title is required")
+
+	t1 := time.Now()
+	t2 := t1.Add(-1)
+	b.StartedAt = &t1
+	b.FinishedAt = &t2
+	errs = b.ValidateFields()
+	assert.Len(t, errs, 2)
+	assert.Equal(t, errs[1].Field, "finished_at")
+	assert.Equal(t, errs.String(), "Title is required, Date format is invalid")
+}
+
+func TestNormalizeISBN(t *testing.T) {
+	assert.Equal(t, NormalizeISBN("978-0-14-303943-3"), "9780143039433")
+	assert.Equal(t, NormalizeISBN("0 14 303943 x"), "014303943X")
+	assert.Equal(t, NormalizeISBN(""), "")
+}
+
+func TestISBN(t *testing.T) {
+	assert.Equal(t, ISBN13("0-452-28423-6"), "9780452284234")
+	assert.Equal(t, ISBN13("080442957X"), "9780804429573")
+	assert.Equal(t, ISBN13("9780452284234"), "9780452284234")
+	assert.Equal(t, ISBN13("B00ABC"), "")
+
+	assert.Equal(t, ISBN10("978-0-452-28423-4"), "0452284236")
+	assert.Equal(t, ISBN10("9780804429573"), "080442957X")
+	assert.Equal(t, ISBN10("9791032305690"), "")
+	assert.Equal(t, ISBN10(""), "")
+}
+
+func TestSplitAuthors(t *testing.T) {
+	tests := []struct {
+		author string
+		names  []string
+	}{
+		{"George Orwell", []string{"George Orwell"}},
+		{"Orwell, George", []string{"Orwell, George"}},
+		{"Le Guin, Ursula K.", []string{"Le Guin, Ursula K."}},
+		{"Tolkien, J. R. R.", []string{"Tolkien, J. R. R."}},
+		{"Neil Gaiman & Terry Pratchett", []string{"Neil Gaiman", "Terry Pratchett"}},
+		{"Le Guin, Ursula K.; Orwell, George", []string{"Le Guin, Ursula K.", "Orwell, George"}},
+		{"Simon and Schuster", []string{"Simon and Schuster"}},
+		{"Brian Kernighan and Dennis Ritchie", []string{"Brian Kernighan and Dennis Ritchie"}},
+		{"Crosby, Stills & Nash", []string{"Crosby, Stills", "Nash"}},
+		{"Peter Paul with Mary", []string{"Peter Paul with Mary"}},
+		{" & ", []string{}},
+		{"", []string{}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, SplitAuthors(test.author), test.names, test.author)
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Books are marked as read once a synced document gets this far, which
+// leaves room for the index and notes at the end of most e-books.
+const FINISHED_PERCENTAGE = 0.98
+
+// DocumentProgress is the reading position of a document synced by
+// KOReader, keyed by the document hash it computes, or imported from an
+// e-reader. Linking it to a book lets syncs move the book along.
+type DocumentProgress struct {
+	ID       uint   `json:"id"`
+	Document string `json:"document" gorm:"uniqueIndex"`
+	BookID   *uint  `json:"book_id" gorm:"index"`
+	// Progress is KOReader's own position, an XPointer or a page number.
+	Progress   string  `json:"progress"`
+	Percentage float64 `json:"percentage"`
+	Device     string  `json:"device"`
+	DeviceID   string  `json:"device_id"`
+	// ReadingTime is the time spent reading in seconds, when the device
+	// tracks it.
+	ReadingTime int       `json:"reading_time"`
+	SyncedAt    time.Time `json:"synced_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (p *DocumentProgress) Validate() []string {
+	return p.ValidateFields().Messages()
+}
+
+func (p *DocumentProgress) ValidateFields() ValidationError {
+	errors := ValidationError{}
+
+	if len(strings.TrimSpace(p.Document)) == 0 {
+		errors = append(errors, FieldError{"document", "Document is required"})
+	}
+	if p.Percentage < 0 || p.Percentage > 1 {
+		errors = append(errors, FieldError{"percentage", "Percentage must be between 0 and 1"})
+	}
+
+	return errors
+}
+
+// Apply moves b forward to match the progress: a to-read book which has
+// been opened becomes reading, and one read to FINISHED_PERCENTAGE becomes
+// read. Books are never moved back. Returns whether b changed.
+func (p *DocumentProgress) Apply(b *Book, now time.Time) bool {
+	switch {
+	case p.Percentage >= FINISHED_PERCENTAGE && b.Status != STATUS_READ:
+		if b.StartedAt == nil {
+			b.StartedAt = &now
+		}
+		b.FinishedAt = &now
+		b.Status = STATUS_READ
+	case p.Percentage > 0 && b.Status == STATUS_TO_READ:
+		b.StartedAt = &now
+		b.FinishedAt = nil
+		b.Status = STATUS_READING
+	default:
+		return false
+	}
+	return true
+}
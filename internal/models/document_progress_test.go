@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentProgressValidate(t *testing.T) {
+	p := DocumentProgress{Percentage: 1.5}
+	errs := p.Validate()
+	assert.Len(t, errs, 2)
+	assert.Equal(t, errs[0], "Document is required")
+	assert.Equal(t, errs[1], "Percentage must be between 0 and 1")
+
+	p = DocumentProgress{Document: "abc", Percentage: 1}
+	assert.Len(t, p.Validate(), 0)
+}
+
+func TestDocumentProgressApply(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	earlier := now.AddDate(0, 0, -10)
+
+	b := Book{Status: STATUS_TO_READ}
+	assert.False(t, (&DocumentProgress{Percentage: 0}).Apply(&b, now))
+	assert.True(t, (&DocumentProgress{Percentage: 0.1}).Apply(&b, now))
+	assert.Equal(t, b.Status, STATUS_READING)
+	assert.Equal(t, *b.StartedAt, now)
+
+	b = Book{Status: STATUS_READING, StartedAt: &earlier}
+	assert.False(t, (&DocumentProgress{Percentage: 0.5}).Apply(&b, now))
+	assert.True(t, (&DocumentProgress{Percentage: 0.99}).Apply(&b, now))
+	assert.Equal(t, b.Status, STATUS_READ)
+	assert.Equal(t, *b.StartedAt, earlier)
+	assert.Equal(t, *b.FinishedAt, now)
+
+	// Read books stay read when an earlier position syncs
+	assert.False(t, (&DocumentProgress{Percentage: 0.2}).Apply(&b, now))
+	assert.Equal(t, b.Status, STATUS_READ)
+
+	b = Book{Status: STATUS_TO_READ}
+	assert.True(t, (&DocumentProgress{Percentage: 1}).Apply(&b, now))
+	assert.Equal(t, b.Status, STATUS_READ)
+	assert.Equal(t, *b.StartedAt, now)
+}
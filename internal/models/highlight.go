@@ -0,0 +1,89 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HIGHLIGHT_KIND_HIGHLIGHT = "highlight"
+	HIGHLIGHT_KIND_NOTE      = "note"
+)
+
+// Highlight is a passage marked, or a note written, while reading a book.
+// Location and Page are kept as the reader displays them, e.g. "180-182".
+type Highlight struct {
+	ID        uint       `json:"id"`
+	BookID    uint       `json:"book_id" gorm:"index"`
+	Kind      string     `json:"kind"`
+	Text      string     `json:"text"`
+	Location  string     `json:"location"`
+	Page      string     `json:"page"`
+	AddedAt   *time.Time `json:"added_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (h *Highlight) Validate() []string {
+	return h.ValidateFields().Messages()
+}
+
+func (h *Highlight) ValidateFields() ValidationError {
+	errors := ValidationError{}
+
+	if h.BookID == 0 {
+		errors = append(errors, FieldError{"book_id", "Book is required"})
+	}
+	if h.Kind != HIGHLIGHT_KIND_HIGHLIGHT && h.Kind != HIGHLIGHT_KIND_NOTE {
+		errors = append(errors, FieldError{"kind", "Kind is invalid"})
+	}
+	if len(strings.TrimSpace(h.Text)) == 0 {
+		errors = append(errors, FieldError{"text", "Text is required"})
+	}
+
+	return errors
+}
+
+// Covers reports whether other is a copy of h, possibly an earlier, shorter
+// one: Kindle appends a new clipping every time a highlight is extended, and
+// again for every device the book is read on.
+func (h *Highlight) Covers(other *Highlight) bool {
+	if h.BookID != other.BookID || h.Kind != other.Kind {
+		return false
+	}
+	if h.Text == other.Text {
+		return h.Location == other.Location || len(h.Location) == 0 || len(other.Location) == 0
+	}
+	if h.Kind == HIGHLIGHT_KIND_NOTE || !strings.Contains(h.Text, other.Text) {
+		return false
+	}
+
+	start, end, ok := locationRange(h.Location)
+	otherStart, otherEnd, otherOK := locationRange(other.Location)
+	if !ok || !otherOK {
+		return h.Location == other.Location
+	}
+	return start <= otherEnd && otherStart <= end
+}
+
+// locationRange parses "180-182", and the abbreviated "180-82" older Kindles
+// write, into its first and last location.
+func locationRange(str string) (int, int, bool) {
+	first, last, isRange := strings.Cut(str, "-")
+	start, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !isRange {
+		return start, start, true
+	}
+
+	end, err := strconv.Atoi(last)
+	if err != nil {
+		return 0, 0, false
+	}
+	if end < start && len(last) < len(first) {
+		end, _ = strconv.Atoi(first[:len(first)-len(last)] + last)
+	}
+	return start, end, true
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlightValidate(t *testing.T) {
+	h := Highlight{}
+	errs := h.Validate()
+	assert.Len(t, errs, 3)
+	assert.Equal(t, errs[0], "Book is required")
+	assert.Equal(t, errs[1], "Kind is invalid")
+	assert.Equal(t, errs[2], "Text is required")
+
+	h = Highlight{BookID: 1, Kind: HIGHLIGHT_KIND_NOTE, Text: "Note"}
+	assert.Len(t, h.Validate(), 0)
+}
+
+func TestHighlightCovers(t *testing.T) {
+	h := Highlight{BookID: 1, Kind: HIGHLIGHT_KIND_HIGHLIGHT, Text: "It was a bright cold day", Location: "180-182"}
+
+	assert.True(t, h.Covers(&Highlight{BookID: 1, Kind: HIGHLIGHT_KIND_HIGHLIGHT, Text: "It was a bright cold day", Location: "180-182"}))
+	assert.True(t, h.Covers(&Highlight{BookID: 1, Kind: HIGHLIGHT_KIND_HIGHLIGHT, Text: "bright cold", Location: "181"}))
+	assert.True(t, h.Covers(&Highlight{BookID: 1, Kind: HIGHLIGHT_KIND_HIGHLIGHT, Text: "bright cold", Location: "175-80"}))
+	assert.False(t, h.Covers(&Highlight{BookID: 1, Kind: HIGHLIGHT_KIND_HIGHLIGHT, Text: "bright cold", Location: "300"}))
+	assert.False(t, h.Covers(&Highlight{BookID: 2, Kind: HIGHLIGHT_KIND_HIGHLIGHT, Text: "bright cold", Location: "181"}))
+	assert.False(t, h.Covers(&Highlight{BookID: 1, Kind: HIGHLIGHT_KIND_NOTE, Text: "bright cold", Location: "181"}))
+
+	// The longer one covers the shorter, not the other way around
+	short := Highlight{BookID: 1, Kind: HIGHLIGHT_KIND_HIGHLIGHT, Text: "bright cold", Location: "181"}
+	assert.False(t, short.Covers(&h))
+
+	note := Highlight{BookID: 1, Kind: HIGHLIGHT_KIND_NOTE, Text: "Great opening", Location: "182"}
+	assert.True(t, note.Covers(&Highlight{BookID: 1, Kind: HIGHLIGHT_KIND_NOTE, Text: "Great opening", Location: "182"}))
+	assert.False(t, note.Covers(&Highlight{BookID: 1, Kind: HIGHLIGHT_KIND_NOTE, Text: "Great", Location: "182"}))
+}
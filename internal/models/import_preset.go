@@ -0,0 +1,94 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// What wins when an imported status disagrees with the imported dates.
+const (
+	STATUS_CONFLICT_DATES  = "dates"
+	STATUS_CONFLICT_STATUS = "status"
+)
+
+// ImportPreset is a named set of CSV import settings, so that files with a
+// known layout can be imported without mapping the columns again.
+type ImportPreset struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name" gorm:"uniqueIndex"`
+	Delimiter string `json:"delimiter"`
+	// Columns maps book fields ("Title", "Author", ...) to CSV columns.
+	Columns    map[string]string `json:"columns" gorm:"serializer:json"`
+	DateFormat string            `json:"date_format"`
+	// StatusRules maps values of the status column to STATUS_*, on top of
+	// the built-in ones.
+	StatusRules    map[string]string `json:"status_rules,omitempty" gorm:"serializer:json"`
+	StatusConflict string            `json:"status_conflict,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+func (p *ImportPreset) Validate() []string {
+	return p.ValidateFields().Messages()
+}
+
+func (p *ImportPreset) ValidateFields() ValidationError {
+	errors := ValidationError{}
+
+	if len(strings.TrimSpace(p.Name)) == 0 {
+		errors = append(errors, FieldError{"name", "Name is required"})
+	}
+	if len(p.Delimiter) > 0 && utf8.RuneCountInString(p.Delimiter) != 1 {
+		errors = append(errors, FieldError{"delimiter", "Delimiter must be a single character"})
+	}
+	if len(p.MappedColumns()) == 0 {
+		errors = append(errors, FieldError{"columns", "At least one column must be mapped"})
+	}
+	for _, status := range p.StatusRules {
+		if !slices.Contains(Statuses, status) {
+			errors = append(errors, FieldError{"status_rules", "Status \"" + status + "\" is invalid"})
+			break
+		}
+	}
+	if len(p.StatusConflict) > 0 && p.StatusConflict != STATUS_CONFLICT_DATES && p.StatusConflict != STATUS_CONFLICT_STATUS {
+		errors = append(errors, FieldError{"status_conflict", "Status conflict rule is invalid"})
+	}
+
+	return errors
+}
+
+// MappedColumns returns the CSV columns used by the preset. Fields mapped to
+// nothing, or to "-", are skipped.
+func (p *ImportPreset) MappedColumns() []string {
+	columns := []string{}
+	for _, column := range p.Columns {
+		if len(column) == 0 || column == "-" || slices.Contains(columns, column) {
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// Match returns how many columns of header the preset uses, or 0 when any
+// of them is missing.
+func (p *ImportPreset) Match(header []string) int {
+	columns := p.MappedColumns()
+	for _, column := range columns {
+		if !slices.Contains(header, column) {
+			return 0
+		}
+	}
+	return len(columns)
+}
+
+// DelimiterRune defaults to a comma.
+func (p *ImportPreset) DelimiterRune() rune {
+	if len(p.Delimiter) == 0 {
+		return ','
+	}
+	r, _ := utf8.DecodeRuneInString(p.Delimiter)
+	return r
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportPresetValidate(t *testing.T) {
+	p := ImportPreset{Delimiter: ";;", Columns: map[string]string{"Title": "-"}}
+	errs := p.Validate()
+	assert.Len(t, errs, 3)
+	assert.Equal(t, errs[0], "Name is required")
+	assert.Equal(t, errs[1], "Delimiter must be a single character")
+	assert.Equal(t, errs[2], "At least one column must be mapped")
+
+	p = ImportPreset{
+		Name:           "Sheet",
+		Columns:        map[string]string{"Title": "Name"},
+		StatusRules:    map[string]string{"dnf": "abandoned"},
+		StatusConflict: "never",
+	}
+	errs = p.Validate()
+	assert.Len(t, errs, 2)
+	assert.Equal(t, errs[0], `Status "abandoned" is invalid`)
+	assert.Equal(t, errs[1], "Status conflict rule is invalid")
+
+	p = ImportPreset{
+		Name:           "Sheet",
+		Delimiter:      ";",
+		Columns:        map[string]string{"Title": "Name"},
+		StatusRules:    map[string]string{"dnf": STATUS_READ},
+		StatusConflict: STATUS_CONFLICT_STATUS,
+	}
+	assert.Len(t, p.Validate(), 0)
+	assert.Equal(t, p.DelimiterRune(), ';')
+}
+
+func TestImportPresetMatch(t *testing.T) {
+	p := ImportPreset{Columns: map[string]string{"Title": "Name", "Author": "Writer", "ISBN": "-"}}
+
+	assert.Equal(t, p.Match([]string{"Writer", "Name", "Other"}), 2)
+	assert.Equal(t, p.Match([]string{"Name"}), 0)
+	assert.Equal(t, p.DelimiterRune(), ',')
+}
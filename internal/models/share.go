@@ -0,0 +1,95 @@
+package models
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SHARE_KIND_YEAR   = "year"
+	SHARE_KIND_SERIES = "series"
+	SHARE_KIND_AUTHOR = "author"
+	SHARE_KIND_STATUS = "status"
+	SHARE_KIND_RECENT = "recent"
+)
+
+// Values of recent shares, listing the latest books by the date they were
+// finished, started or added.
+const (
+	SHARE_RECENT_FINISHED = "finished"
+	SHARE_RECENT_STARTED  = "started"
+	SHARE_RECENT_ADDED    = "added"
+)
+
+var ShareRecents = []string{SHARE_RECENT_FINISHED, SHARE_RECENT_STARTED, SHARE_RECENT_ADDED}
+
+// Share is a public, read-only view of a filtered book list. It is
+// addressed by an unguessable token and revoked by deleting the row.
+type Share struct {
+	ID           uint      `json:"id"`
+	Token        string    `json:"token" gorm:"uniqueIndex"`
+	Title        string    `json:"title"`
+	Kind         string    `json:"kind"`
+	Value        string    `json:"value"`
+	ShowComments bool      `json:"show_comments"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (s *Share) Validate() []string {
+	return s.ValidateFields().Messages()
+}
+
+func (s *Share) ValidateFields() ValidationError {
+	errors := ValidationError{}
+
+	kinds := []string{
+		SHARE_KIND_YEAR,
+		SHARE_KIND_SERIES,
+		SHARE_KIND_AUTHOR,
+		SHARE_KIND_STATUS,
+		SHARE_KIND_RECENT,
+	}
+	if !slices.Contains(kinds, s.Kind) {
+		errors = append(errors, FieldError{"kind", "Kind is invalid"})
+	}
+
+	value := strings.TrimSpace(s.Value)
+	if len(value) == 0 {
+		errors = append(errors, FieldError{"value", "Value is required"})
+	} else {
+		switch s.Kind {
+		case SHARE_KIND_YEAR:
+			if _, err := strconv.Atoi(value); err != nil {
+				errors = append(errors, FieldError{"value", "Year is invalid"})
+			}
+		case SHARE_KIND_STATUS:
+			if !slices.Contains(Statuses, value) {
+				errors = append(errors, FieldError{"value", "Status is invalid"})
+			}
+		case SHARE_KIND_RECENT:
+			if !slices.Contains(ShareRecents, value) {
+				errors = append(errors, FieldError{"value", "Recent list is invalid"})
+			}
+		}
+	}
+
+	return errors
+}
+
+// DisplayTitle falls back to a title derived from the filter when none is set.
+func (s *Share) DisplayTitle() string {
+	if len(strings.TrimSpace(s.Title)) > 0 {
+		return s.Title
+	}
+	switch s.Kind {
+	case SHARE_KIND_YEAR:
+		return "Read in " + s.Value
+	case SHARE_KIND_STATUS:
+		return "Books: " + s.Value
+	case SHARE_KIND_RECENT:
+		return "Recently " + s.Value
+	}
+	return s.Value
+}
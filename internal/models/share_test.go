@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareValidate(t *testing.T) {
+	s := Share{}
+	errs := s.Validate()
+	assert.Len(t, errs, 2)
+	assert.Equal(t, errs[0], "Kind is invalid")
+	assert.Equal(t, errs[1], "Value is required")
+
+	s = Share{Kind: SHARE_KIND_YEAR, Value: "twenty"}
+	errs = s.Validate()
+	assert.Len(t, errs, 1)
+	assert.Equal(t, errs[0], "Year is invalid")
+
+	s = Share{Kind: SHARE_KIND_STATUS, Value: "abandoned"}
+	errs = s.Validate()
+	assert.Len(t, errs, 1)
+	assert.Equal(t, errs[0], "Status is invalid")
+
+	s = Share{Kind: SHARE_KIND_RECENT, Value: "read"}
+	errs = s.Validate()
+	assert.Len(t, errs, 1)
+	assert.Equal(t, errs[0], "Recent list is invalid")
+
+	s = Share{Kind: SHARE_KIND_SERIES, Value: "Series"}
+	assert.Len(t, s.Validate(), 0)
+}
+
+func TestShareDisplayTitle(t *testing.T) {
+	s := Share{Kind: SHARE_KIND_YEAR, Value: "2025"}
+	assert.Equal(t, s.DisplayTitle(), "Read in 2025")
+
+	s.Title = "My 2025"
+	assert.Equal(t, s.DisplayTitle(), "My 2025")
+
+	s = Share{Kind: SHARE_KIND_SERIES, Value: "Series"}
+	assert.Equal(t, s.DisplayTitle(), "Series")
+
+	s = Share{Kind: SHARE_KIND_RECENT, Value: SHARE_RECENT_FINISHED}
+	assert.Equal(t, s.DisplayTitle(), "Recently finished")
+}
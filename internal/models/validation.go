@@ -0,0 +1,31 @@
+package models
+
+import "strings"
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError carries every failed field of a model so that callers can
+// report all of them at once instead of only the first.
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	if len(e) == 0 {
+		return "Validation failed"
+	}
+	return e.String()
+}
+
+func (e ValidationError) Messages() []string {
+	messages := []string{}
+	for _, f := range e {
+		messages = append(messages, f.Message)
+	}
+	return messages
+}
+
+func (e ValidationError) String() string {
+	return strings.Join(e.Messages(), ", ")
+}
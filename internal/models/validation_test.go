@@ -0,0 +1,18 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationError(t *testing.T) {
+	assert.EqualError(t, ValidationError{}, "Validation failed")
+
+	err := ValidationError{
+		{Field: "title", Message: "Title is required"},
+		{Field: "isbn", Message: "ISBN is invalid"},
+	}
+	assert.EqualError(t, err, "Title is required, ISBN is invalid")
+	assert.Equal(t, err.Error(), err.String())
+}
@@ -0,0 +1,194 @@
+// Package authors keeps the people credited on books, linked to the books
+// by a join with the role of each credit. The author of a book is the
+// credit line the join is derived from.
+package authors
+
+import (
+	"strings"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// GetAll returns the name and book count of the credited authors whose name
+// contains name, or of every author when name is empty, like repo.GetAll.
+func GetAll(db *gorm.DB, name string, order string) []map[string]any {
+	result := []map[string]any{}
+
+	q := db.Model(&models.Author{}).
+		Select("authors.name AS name", "COUNT(DISTINCT book_authors.book_id) AS count").
+		Joins("JOIN book_authors ON book_authors.author_id = authors.id")
+	if name = strings.TrimSpace(name); len(name) > 0 {
+		q = q.Where("authors.name LIKE ?", "%"+name+"%")
+	}
+	q.Group("authors.id").
+		Order("authors.name COLLATE NOCASE " + utils.SortOrder(order)).
+		Find(&result)
+	return result
+}
+
+// GetByBook returns the credits of the book, in order.
+func GetByBook(db *gorm.DB, bookID uint) []models.Credit {
+	credits := []models.Credit{}
+	db.Model(&models.BookAuthor{}).
+		Select("authors.name AS name", "book_authors.role AS role").
+		Joins("JOIN authors ON authors.id = book_authors.author_id").
+		Where("book_authors.book_id = ?", bookID).
+		Order("book_authors.position").
+		Scan(&credits)
+	return credits
+}
+
+// BookIDs selects the IDs of the books crediting the author with name, in
+// any role, for use as a subquery.
+func BookIDs(db *gorm.DB, name string) *gorm.DB {
+	return db.Model(&models.BookAuthor{}).
+		Select("book_authors.book_id").
+		Joins("JOIN authors ON authors.id = book_authors.author_id").
+		Where("authors.name = ?", name)
+}
+
+// Link replaces the credits of the book with the ones parsed from its
+// author. Authors are matched by name case-insensitively, and created when
+// they aren't credited on any book yet. Authors still credited keep their ID.
+func Link(db *gorm.DB, book *models.Book) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		lost, err := authorIDs(tx, book.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("book_id = ?", book.ID).Delete(&models.BookAuthor{}).Error; err != nil {
+			return err
+		}
+
+		for i, c := range models.ParseCredits(book.Author) {
+			author := models.Author{}
+			ret := tx.Where("name = ? COLLATE NOCASE", c.Name).Limit(1).Find(&author)
+			if ret.Error != nil {
+				return ret.Error
+			}
+			if ret.RowsAffected == 0 {
+				author.Name = c.Name
+				if err := tx.Create(&author).Error; err != nil {
+					return err
+				}
+			}
+
+			credit := models.BookAuthor{BookID: book.ID, AuthorID: author.ID, Role: c.Role, Position: i}
+			if err := tx.Create(&credit).Error; err != nil {
+				return err
+			}
+		}
+		return prune(tx, lost)
+	})
+}
+
+// Unlink removes the credits of the book, together with the authors left
+// without any book.
+func Unlink(db *gorm.DB, bookID uint) error {
+	lost, err := authorIDs(db, bookID)
+	if err != nil {
+		return err
+	}
+	if err := db.Where("book_id = ?", bookID).Delete(&models.BookAuthor{}).Error; err != nil {
+		return err
+	}
+	return prune(db, lost)
+}
+
+func authorIDs(db *gorm.DB, bookID uint) ([]uint, error) {
+	ids := []uint{}
+	err := db.Model(&models.BookAuthor{}).Where("book_id = ?", bookID).Distinct().Pluck("author_id", &ids).Error
+	return ids, err
+}
+
+// prune removes the authors among ids which aren't credited on any book.
+func prune(db *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return db.Where("id IN ? AND NOT EXISTS (?)", ids,
+		db.Model(&models.BookAuthor{}).Select("1").Where("book_authors.author_id = authors.id"),
+	).Delete(&models.Author{}).Error
+}
+
+// LinkMissing links the books which have an author but no credits, such as
+// the books saved before authors were kept, or restored from old backups.
+func LinkMissing(db *gorm.DB) error {
+	list := []models.Book{}
+	err := db.Model(&models.Book{}).
+		Where("author IS NOT NULL AND author != ''").
+		Where("id NOT IN (?)", db.Model(&models.BookAuthor{}).Select("book_id")).
+		Find(&list).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for i := range list {
+			if err := Link(tx, &list[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Rename renames the author, merging them into the author already named
+// newName, if any. The author of the books crediting them is rewritten from
+// their credits.
+func Rename(db *gorm.DB, oldName string, newName string) error {
+	oldName = strings.TrimSpace(oldName)
+	newName = strings.TrimSpace(newName)
+	if len(oldName) == 0 || len(newName) == 0 {
+		return models.ValidationError{{Field: "name", Message: "Invalid column name"}}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		author := models.Author{}
+		ret := tx.Where("name = ?", oldName).Limit(1).Find(&author)
+		if ret.Error != nil || ret.RowsAffected == 0 {
+			return ret.Error
+		}
+
+		bookIDs := []uint{}
+		if err := tx.Model(&models.BookAuthor{}).Where("author_id = ?", author.ID).Distinct().Pluck("book_id", &bookIDs).Error; err != nil {
+			return err
+		}
+
+		target := models.Author{}
+		ret = tx.Where("name = ? COLLATE NOCASE AND id != ?", newName, author.ID).Limit(1).Find(&target)
+		if ret.Error != nil {
+			return ret.Error
+		}
+		if ret.RowsAffected == 0 {
+			if err := tx.Model(&author).Update("name", newName).Error; err != nil {
+				return err
+			}
+		} else {
+			// Credits the target already has on a book are dropped
+			err := tx.Where("author_id = ? AND EXISTS (?)", author.ID,
+				tx.Table("book_authors AS t").Select("1").
+					Where("t.book_id = book_authors.book_id AND t.role = book_authors.role AND t.author_id = ?", target.ID),
+			).Delete(&models.BookAuthor{}).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&models.BookAuthor{}).Where("author_id = ?", author.ID).Update("author_id", target.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&author).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, id := range bookIDs {
+			author := models.FormatCredits(GetByBook(tx, id))
+			if err := tx.Model(&models.Book{}).Where("id = ?", id).Update("author", author).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,127 @@
+package authors
+
+import (
+	"testing"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func testDB() *gorm.DB {
+	db, _ := database.Load(":memory:")
+	return db
+}
+
+func createBook(t *testing.T, db *gorm.DB, title string, author string) *models.Book {
+	b := &models.Book{Title: title, Author: author}
+	assert.Nil(t, db.Create(b).Error)
+	assert.Nil(t, Link(db, b))
+	return b
+}
+
+func authorOf(db *gorm.DB, id uint) string {
+	b := models.Book{}
+	db.First(&b, id)
+	return b.Author
+}
+
+func TestLink(t *testing.T) {
+	db := testDB()
+
+	b1 := createBook(t, db, "The Odyssey", "Homer & Emily Wilson (translator)")
+	b2 := createBook(t, db, "The Iliad", "homer; Emily Wilson (trans.)")
+
+	assert.Equal(t, GetByBook(db, b1.ID), []models.Credit{
+		{Name: "Homer", Role: models.AUTHOR_ROLE_AUTHOR},
+		{Name: "Emily Wilson", Role: models.AUTHOR_ROLE_TRANSLATOR},
+	})
+	// Authors are shared case-insensitively
+	assert.Equal(t, GetByBook(db, b2.ID)[0].Name, "Homer")
+
+	all := GetAll(db, "", "asc")
+	assert.Len(t, all, 2)
+	assert.Equal(t, all[0]["name"], "Emily Wilson")
+	assert.EqualValues(t, all[0]["count"], 2)
+	assert.Len(t, GetAll(db, "hom", "asc"), 1)
+
+	b2.Author = "Homer"
+	assert.Nil(t, Link(db, b2))
+	assert.Len(t, GetByBook(db, b2.ID), 1)
+	assert.EqualValues(t, GetAll(db, "Wilson", "asc")[0]["count"], 1)
+
+	assert.Nil(t, Unlink(db, b1.ID))
+	assert.Len(t, GetByBook(db, b1.ID), 0)
+	// Authors without books are removed
+	assert.Len(t, GetAll(db, "", "asc"), 1)
+	count := int64(0)
+	db.Model(&models.Author{}).Count(&count)
+	assert.EqualValues(t, count, 1)
+}
+
+func TestLinkKeepsAuthors(t *testing.T) {
+	db := testDB()
+
+	b := createBook(t, db, "Test", "Author 1 & Author 2")
+	author := models.Author{}
+	db.Where("name = ?", "Author 1").First(&author)
+	orphan := models.Author{Name: "Author 4"}
+	assert.Nil(t, db.Create(&orphan).Error)
+
+	b.Author = "Author 1 & Author 3"
+	assert.Nil(t, Link(db, b))
+	relinked := models.Author{}
+	db.Where("name = ?", "Author 1").First(&relinked)
+	assert.Equal(t, relinked.ID, author.ID)
+
+	// Only the authors the book lost are removed
+	names := []string{}
+	db.Model(&models.Author{}).Order("name").Pluck("name", &names)
+	assert.Equal(t, names, []string{"Author 1", "Author 3", "Author 4"})
+}
+
+func TestLinkMissing(t *testing.T) {
+	db := testDB()
+
+	linked := createBook(t, db, "Linked", "Author 1")
+	old := &models.Book{Title: "Old", Author: "Author 1; Author 2"}
+	assert.Nil(t, db.Create(old).Error)
+	assert.Nil(t, db.Create(&models.Book{Title: "Anonymous"}).Error)
+
+	assert.Nil(t, LinkMissing(db))
+	assert.Len(t, GetByBook(db, linked.ID), 1)
+	assert.Equal(t, GetByBook(db, old.ID), []models.Credit{
+		{Name: "Author 1", Role: models.AUTHOR_ROLE_AUTHOR},
+		{Name: "Author 2", Role: models.AUTHOR_ROLE_AUTHOR},
+	})
+
+	ids := []uint{}
+	db.Raw("?", BookIDs(db, "Author 1")).Scan(&ids)
+	assert.ElementsMatch(t, ids, []uint{linked.ID, old.ID})
+}
+
+func TestRename(t *testing.T) {
+	db := testDB()
+
+	b1 := createBook(t, db, "Test 1", "Author 1 & Editor 1 (ed.)")
+	b2 := createBook(t, db, "Test 2", "Author 2")
+	b3 := createBook(t, db, "Test 3", "Author 1 & Author 2")
+
+	err := Rename(db, " ", "Author 3")
+	assert.IsType(t, models.ValidationError{}, err)
+
+	assert.Nil(t, Rename(db, "Author 1", "Author 3"))
+	assert.Equal(t, authorOf(db, b1.ID), "Author 3 & Editor 1 (editor)")
+
+	// Renaming to an existing author merges them
+	assert.Nil(t, Rename(db, "Author 3", "author 2"))
+	assert.Equal(t, authorOf(db, b1.ID), "Author 2 & Editor 1 (editor)")
+	assert.Equal(t, authorOf(db, b2.ID), "Author 2")
+	assert.Equal(t, authorOf(db, b3.ID), "Author 2")
+	assert.EqualValues(t, GetAll(db, "Author", "asc")[0]["count"], 3)
+	assert.Len(t, GetAll(db, "Author 3", "asc"), 0)
+
+	// Unknown authors are left alone
+	assert.Nil(t, Rename(db, "Nobody", "Author 4"))
+}
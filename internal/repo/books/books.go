@@ -5,6 +5,8 @@ import (
 	"slices"
 	"strings"
 	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+	"waynezhang/buku/internal/repo/authors"
 	"waynezhang/buku/internal/utils"
 
 	"gorm.io/gorm"
@@ -24,56 +26,105 @@ type YearRecord struct {
 	Ratio int `json:"ratio"`
 }
 
+// Create saves the book and credits the authors of its author, which is
+// rewritten from its credits when they are given.
 func Create(db *gorm.DB, book *models.Book) (*models.Book, error) {
 	book.ID = 0
 	book.FixStatus()
 
-	errs := book.Validate()
-	if len(errs) > 0 {
-		return nil, errors.New(errs[0])
+	if errs := book.ValidateFields(); len(errs) > 0 {
+		return nil, errs
 	}
+	creditAuthor(book)
 
-	ret := db.Create(book)
-	if ret.Error != nil {
-		return nil, ret.Error
-	}
-	if ret.RowsAffected == 0 {
-		return nil, errors.New("DB error")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		ret := tx.Create(book)
+		if ret.Error != nil {
+			return ret.Error
+		}
+		if ret.RowsAffected == 0 {
+			return errors.New("DB error")
+		}
+		return authors.Link(tx, book)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return book, nil
 }
 
+// Update saves the book, and credits the authors of its author again when it
+// changed, like Create.
 func Update(db *gorm.DB, id uint, book *models.Book) (*models.Book, error) {
 	book.ID = id
 	book.FixStatus()
 
-	errs := book.Validate()
-	if len(errs) > 0 {
-		return nil, errors.New(errs[0])
+	if errs := book.ValidateFields(); len(errs) > 0 {
+		return nil, errs
 	}
+	creditAuthor(book)
 
-	ret := db.Model(&models.Book{}).
-		Where("id = ?", id).
-		Select("title", "author", "isbn", "series", "comments", "status", "started_at", "finished_at").
-		Updates(book)
-	if ret.Error != nil {
-		return nil, ret.Error
-	}
-	if ret.RowsAffected == 0 {
-		return nil, errors.New("ID not found")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		old := models.Book{}
+		ret := tx.Select("author").Where("id = ?", id).Limit(1).Find(&old)
+		if ret.Error != nil {
+			return ret.Error
+		}
+		if ret.RowsAffected == 0 {
+			return repo.ErrNotFound
+		}
+
+		ret = tx.Model(&models.Book{}).
+			Where("id = ?", id).
+			Select("title", "author", "isbn", "series", "comments", "status", "started_at", "finished_at").
+			Updates(book)
+		if ret.Error != nil {
+			return ret.Error
+		}
+		if ret.RowsAffected == 0 {
+			return repo.ErrNotFound
+		}
+		// The credits are derived from the author only
+		if old.Author == book.Author {
+			return nil
+		}
+		return authors.Link(tx, book)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return book, nil
 }
 
-func Delete(db *gorm.DB, id uint) error {
-	ret := db.Delete(&models.Book{}, id)
-	if ret.Error != nil {
-		return ret.Error
-	}
-	if ret.RowsAffected == 0 {
-		return errors.New("ID not found")
+// creditAuthor sets the author of the book to the credit line of its
+// credits, if any. The credits are cleared once used, so that a saved book
+// doesn't rewrite its author again when it's saved another time.
+func creditAuthor(book *models.Book) {
+	if len(book.Credits) > 0 {
+		book.Author = models.FormatCredits(book.Credits)
 	}
-	return nil
+	book.Credits = nil
+}
+
+// Delete removes the book together with its highlights and credits, and
+// unlinks the synced documents pointing at it.
+func Delete(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		ret := tx.Delete(&models.Book{}, id)
+		if ret.Error != nil {
+			return ret.Error
+		}
+		if ret.RowsAffected == 0 {
+			return repo.ErrNotFound
+		}
+		if err := tx.Where("book_id = ?", id).Delete(&models.Highlight{}).Error; err != nil {
+			return err
+		}
+		if err := authors.Unlink(tx, id); err != nil {
+			return err
+		}
+		return tx.Model(&models.DocumentProgress{}).Where("book_id = ?", id).Update("book_id", nil).Error
+	})
 }
 
 func GetAll(db *gorm.DB) []models.Book {
@@ -136,20 +187,108 @@ func CountAll(db *gorm.DB) StatRecord {
 }
 
 func GetByKeyword(db *gorm.DB, keyword string, sort string, order string, status string) []models.Book {
-	keyword = strings.TrimSpace(keyword)
+	return GetByFilter(db, Filter{Keyword: keyword, Status: status, Sort: sort, Order: order})
+}
+
+// Filter selects books like the search of the book list. Zero fields don't
+// filter; books are sorted by title unless Sort is set.
+type Filter struct {
+	// Keyword is matched against the title and author.
+	Keyword string
+	Status  string
+	// Year is the year books were finished in.
+	Year   int
+	Author string
+	Series string
+	Sort   string
+	Order  string
+}
+
+func GetByFilter(db *gorm.DB, f Filter) []models.Book {
 	books := []models.Book{}
+	f.query(db).Find(&books)
+	return books
+}
+
+// EachByFilter calls fn with the books matching f, in batches of at most
+// size books. Each batch is read by a query of its own, starting after the
+// last book of the previous one, so that no read is kept open while fn runs
+// and only one batch is in memory however large the library is.
+func EachByFilter(db *gorm.DB, f Filter, size int, fn func([]models.Book) error) error {
+	column := sortCriteria(f.Sort)
+	desc := utils.SortOrder(f.Order) == "desc"
+
+	var last *keyedBook
+	for {
+		q := f.query(db).Select("books.*", "CAST("+column+" AS TEXT) AS sort_key").Limit(size)
+		if last != nil {
+			q = q.Where(last.after(db, column, desc))
+		}
+		list := []keyedBook{}
+		if err := q.Find(&list).Error; err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return nil
+		}
+
+		batch := make([]models.Book, 0, len(list))
+		for _, b := range list {
+			batch = append(batch, b.Book)
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(list) < size {
+			return nil
+		}
+		last = &list[len(list)-1]
+	}
+}
+
+// keyedBook is a book with the value of the column books are sorted by.
+type keyedBook struct {
+	models.Book
+	SortKey *string
+}
+
+// after selects the books sorted after b, by column then by ID like
+// Filter.query sorts them. NULLs sort first in ascending order.
+func (b *keyedBook) after(db *gorm.DB, column string, desc bool) *gorm.DB {
+	key := column + " COLLATE NOCASE"
+	switch {
+	case b.SortKey == nil && desc:
+		return db.Where(column+" IS NULL AND id > ?", b.ID)
+	case b.SortKey == nil:
+		return db.Where(column+" IS NULL AND id > ?", b.ID).Or(column + " IS NOT NULL")
+	case desc:
+		return db.Where(key+" < ?", *b.SortKey).Or(key+" = ? AND id > ?", *b.SortKey, b.ID).Or(column + " IS NULL")
+	default:
+		return db.Where(key+" > ?", *b.SortKey).Or(key+" = ? AND id > ?", *b.SortKey, b.ID)
+	}
+}
+
+func (f *Filter) query(db *gorm.DB) *gorm.DB {
+	keyword := strings.TrimSpace(f.Keyword)
 	q := db.Model(&models.Book{}).
 		Where(
 			db.Where(
 				"title LIKE ?", "%"+keyword+"%").
 				Or("author LIKE ?", "%"+keyword+"%"),
 		)
-	if len(status) != 0 {
-		q = q.Where("status = ?", status)
+	if len(f.Status) != 0 {
+		q = q.Where("status = ?", f.Status)
 	}
-	q.Order(sortCriteria(sort) + " COLLATE NOCASE " + utils.SortOrder(order)).
-		Find(&books)
-	return books
+	if f.Year != 0 {
+		q = q.Where("CAST(strftime('%Y', finished_at) AS INTEGER) = ?", f.Year)
+	}
+	if len(f.Author) != 0 {
+		q = q.Where("id IN (?)", authors.BookIDs(db, f.Author))
+	}
+	if len(f.Series) != 0 {
+		q = q.Where("series = ?", f.Series)
+	}
+	return q.Order(sortCriteria(f.Sort) + " COLLATE NOCASE " + utils.SortOrder(f.Order) + ", id")
 }
 
 func GetByYear(db *gorm.DB, year int) []models.Book {
@@ -162,9 +301,10 @@ func GetByYear(db *gorm.DB, year int) []models.Book {
 	return books
 }
 
+// GetByAuthor returns the books crediting the author, in any role.
 func GetByAuthor(db *gorm.DB, name string) []models.Book {
 	books := []models.Book{}
-	db.Model(&models.Book{}).Where("author = ?", name).Find(&books)
+	db.Model(&models.Book{}).Where("id IN (?)", authors.BookIDs(db, name)).Find(&books)
 	return books
 }
 
@@ -178,6 +318,108 @@ func GetBySeries(db *gorm.DB, name string, sort string, order string) []models.B
 	return books
 }
 
+// GetRecent returns the latest books by the given date, such as finished_at,
+// at most limit of them. Books without the date are left out.
+func GetRecent(db *gorm.DB, sort string, limit int) []models.Book {
+	books := []models.Book{}
+	column := sortCriteria(sort)
+
+	db.Model(&models.Book{}).
+		Where(column + " IS NOT NULL").
+		Order(column + " DESC, id DESC").
+		Limit(limit).
+		Find(&books)
+	return books
+}
+
+// GetCitationPeers returns the books citation keys of list depend on: the
+// books finished in the same years as the books of list, or unfinished like
+// some of them, added before the last of them. Only the columns keys are
+// made of are loaded.
+func GetCitationPeers(db *gorm.DB, list []models.Book) []models.Book {
+	books := []models.Book{}
+	if len(list) == 0 {
+		return books
+	}
+
+	years := []int{}
+	undated := false
+	last := uint(0)
+	for _, b := range list {
+		if b.FinishedAt == nil {
+			undated = true
+		} else if !slices.Contains(years, b.FinishedAt.Year()) {
+			years = append(years, b.FinishedAt.Year())
+		}
+		last = max(last, b.ID)
+	}
+
+	q := db.Where("CAST(strftime('%Y', finished_at) AS INTEGER) IN ?", years)
+	if undated {
+		q = q.Or("finished_at IS NULL")
+	}
+	db.Model(&models.Book{}).
+		Select("id", "title", "author", "finished_at").
+		Where(q).
+		Where("id <= ?", last).
+		Find(&books)
+	return books
+}
+
+// GetDuplicates returns the books which share the normalized ISBN, or the
+// title and author (case-insensitively), with the given book.
+func GetDuplicates(db *gorm.DB, book *models.Book) []models.Book {
+	books := []models.Book{}
+
+	q := matchTitleAuthor(db, book)
+	if isbn := models.NormalizeISBN(book.ISBN); len(isbn) > 0 {
+		q = q.Or(matchISBN(db, isbn))
+	}
+	db.Model(&models.Book{}).
+		Where(q).
+		Where("id != ?", book.ID).
+		Order("id").
+		Find(&books)
+	return books
+}
+
+// GetByISBN returns the books whose normalized ISBN equals the given one.
+func GetByISBN(db *gorm.DB, isbn string) []models.Book {
+	books := []models.Book{}
+
+	isbn = models.NormalizeISBN(isbn)
+	if len(isbn) == 0 {
+		return books
+	}
+	db.Model(&models.Book{}).
+		Where(matchISBN(db, isbn)).
+		Order("id").
+		Find(&books)
+	return books
+}
+
+// GetByTitleAndAuthor matches both title and author case-insensitively.
+func GetByTitleAndAuthor(db *gorm.DB, title string, author string) []models.Book {
+	books := []models.Book{}
+	db.Model(&models.Book{}).
+		Where(matchTitleAuthor(db, &models.Book{Title: title, Author: author})).
+		Order("id").
+		Find(&books)
+	return books
+}
+
+func matchISBN(db *gorm.DB, normalized string) *gorm.DB {
+	return db.Where("REPLACE(REPLACE(UPPER(isbn), '-', ''), ' ', '') = ?", normalized)
+}
+
+func matchTitleAuthor(db *gorm.DB, book *models.Book) *gorm.DB {
+	return db.Where(
+		"title = ? COLLATE NOCASE AND author = ? COLLATE NOCASE",
+		strings.TrimSpace(book.Title),
+		strings.TrimSpace(book.Author),
+	)
+}
+
 func sortCriteria(str string) string {
 	if slices.Index([]string{
 		"title",
@@ -1,10 +1,14 @@
 package books
 
 import (
+	"errors"
+	"strconv"
 	"testing"
 	"time"
 	"waynezhang/buku/internal/infra/database"
 	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+	"waynezhang/buku/internal/repo/authors"
 
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
@@ -23,6 +27,7 @@ func TestCreate1(t *testing.T) {
 	created, err := Create(db, b)
 	assert.Nil(t, created)
 	assert.NotNil(t, err)
+	assert.IsType(t, models.ValidationError{}, err)
 }
 
 func TestCreate2(t *testing.T) {
@@ -115,6 +120,11 @@ func TestDelete(t *testing.T) {
 	Delete(db, b.ID)
 
 	assert.Equal(t, count(db), 0)
+
+	assert.ErrorIs(t, Delete(db, b.ID), repo.ErrNotFound)
+
+	_, err := Update(db, b.ID, &models.Book{Title: "Test"})
+	assert.ErrorIs(t, err, repo.ErrNotFound)
 }
 
 func TestGetAll(t *testing.T) {
@@ -221,6 +231,64 @@ func TestGetByAuthorAndGetBySeries(t *testing.T) {
 	assert.Equal(t, GetBySeries(db, "Series 6", "", "")[0].Title, "Test 6")
 }
 
+func TestCredits(t *testing.T) {
+	db := testDB()
+
+	b1, _ := Create(db, &models.Book{Title: "Test 1", Author: "Author 1 & Author 2 (illustrator)"})
+	b2, _ := Create(db, &models.Book{Title: "Test 2", Credits: []models.Credit{
+		{Name: "Author 2", Role: models.AUTHOR_ROLE_AUTHOR},
+		{Name: "Author 3", Role: models.AUTHOR_ROLE_EDITOR},
+	}})
+	assert.Equal(t, b2.Author, "Author 2 & Author 3 (editor)")
+	assert.Equal(t, authors.GetByBook(db, b1.ID), []models.Credit{
+		{Name: "Author 1", Role: models.AUTHOR_ROLE_AUTHOR},
+		{Name: "Author 2", Role: models.AUTHOR_ROLE_ILLUSTRATOR},
+	})
+
+	assert.Len(t, GetByAuthor(db, "Author 2"), 2)
+	assert.Len(t, GetByFilter(db, Filter{Author: "Author 3"}), 1)
+
+	_, err := Create(db, &models.Book{Title: "Test 3", Credits: []models.Credit{{Name: "Author", Role: "reader"}}})
+	assert.IsType(t, models.ValidationError{}, err)
+
+	_, _ = Update(db, b1.ID, &models.Book{Title: "Test 1", Author: "Author 1"})
+	assert.Len(t, GetByAuthor(db, "Author 2"), 1)
+
+	_ = Delete(db, b2.ID)
+	assert.Len(t, GetByAuthor(db, "Author 2"), 0)
+	assert.Len(t, authors.GetByBook(db, b1.ID), 1)
+}
+
+func TestUpdateKeepsAuthor(t *testing.T) {
+	db := testDB()
+
+	b, _ := Create(db, &models.Book{Title: "Good Omens", Author: "Terry Pratchett; Neil Gaiman"})
+	b, _ = Update(db, b.ID, b)
+	assert.Equal(t, b.Author, "Terry Pratchett; Neil Gaiman")
+
+	loaded := GetByID(db, b.ID)
+	loaded.Status = models.STATUS_READ
+	_, _ = Update(db, loaded.ID, loaded)
+	assert.Equal(t, GetByID(db, b.ID).Author, "Terry Pratchett; Neil Gaiman")
+	assert.Len(t, authors.GetByBook(db, b.ID), 2)
+}
+
+func TestUpdateRelinksChangedAuthor(t *testing.T) {
+	db := testDB()
+
+	b, _ := Create(db, &models.Book{Title: "Good Omens", Author: "Terry Pratchett & Neil Gaiman"})
+	assert.Nil(t, authors.Unlink(db, b.ID))
+
+	// Credits are left alone while the author doesn't change
+	b.Status = models.STATUS_READ
+	_, _ = Update(db, b.ID, b)
+	assert.Len(t, authors.GetByBook(db, b.ID), 0)
+
+	b.Author = "Terry Pratchett"
+	_, _ = Update(db, b.ID, b)
+	assert.Equal(t, authors.GetByBook(db, b.ID), []models.Credit{{Name: "Terry Pratchett", Role: models.AUTHOR_ROLE_AUTHOR}})
+}
+
 func TestGetByKeyword(t *testing.T) {
 	db := testDB()
 
@@ -267,6 +335,178 @@ func TestGetByKeyword(t *testing.T) {
 	assert.Equal(t, ret[1].Title, "Test 3")
 }
 
+func TestGetByFilter(t *testing.T) {
+	db := testDB()
+
+	d2024 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	d2025 := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	_, _ = Create(db, &models.Book{Title: "Test 1", Author: "Author 1", Series: "Series 1", FinishedAt: &d2025})
+	_, _ = Create(db, &models.Book{Title: "Test 2", Author: "Author 1", FinishedAt: &d2024})
+	_, _ = Create(db, &models.Book{Title: "Test 3", Author: "Author 2", Series: "Series 1", FinishedAt: &d2025})
+	_, _ = Create(db, &models.Book{Title: "Test 4", Author: "Author 2"})
+
+	ret := GetByFilter(db, Filter{Year: 2025})
+	assert.Len(t, ret, 2)
+	assert.Equal(t, ret[0].Title, "Test 1")
+
+	ret = GetByFilter(db, Filter{Year: 2025, Author: "Author 2"})
+	assert.Len(t, ret, 1)
+	assert.Equal(t, ret[0].Title, "Test 3")
+
+	ret = GetByFilter(db, Filter{Series: "Series 1", Sort: "title", Order: "desc"})
+	assert.Len(t, ret, 2)
+	assert.Equal(t, ret[0].Title, "Test 3")
+
+	ret = GetByFilter(db, Filter{Keyword: "Author 2", Status: models.STATUS_TO_READ})
+	assert.Len(t, ret, 1)
+	assert.Equal(t, ret[0].Title, "Test 4")
+
+	assert.Len(t, GetByFilter(db, Filter{}), 4)
+}
+
+func TestEachByFilter(t *testing.T) {
+	db := testDB()
+
+	for i := 1; i <= 7; i++ {
+		_, _ = Create(db, &models.Book{Title: "Test " + strconv.Itoa(i), Author: "Author " + strconv.Itoa(i%2)})
+	}
+
+	batches := [][]string{}
+	err := EachByFilter(db, Filter{Author: "Author 1", Sort: "title", Order: "desc"}, 3, func(list []models.Book) error {
+		titles := []string{}
+		for _, b := range list {
+			titles = append(titles, b.Title)
+		}
+		batches = append(batches, titles)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, batches, [][]string{{"Test 7", "Test 5", "Test 3"}, {"Test 1"}})
+
+	stop := errors.New("stop")
+	calls := 0
+	err = EachByFilter(db, Filter{}, 2, func(list []models.Book) error {
+		calls += 1
+		return stop
+	})
+	assert.Equal(t, err, stop)
+	assert.Equal(t, calls, 1)
+}
+
+func TestEachByFilterOrder(t *testing.T) {
+	db := testDB()
+
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	list := []models.Book{
+		{Title: "b", Author: "Author 2", StartedAt: day(3)},
+		{Title: "A", Author: "Author 1"},
+		{Title: "a", Author: "author 1", StartedAt: day(1)},
+		{Title: "C", Author: "Author 3"},
+		{Title: "B", Author: "Author 2", StartedAt: day(3), FinishedAt: day(5)},
+		{Title: "a", Author: "Author 1", StartedAt: day(2)},
+		{Title: "c"},
+	}
+	for i := range list {
+		_, _ = Create(db, &list[i])
+	}
+
+	// Batches pick up where the previous one stopped, across ties and NULLs
+	for _, sort := range []string{"title", "author", "started_at", "finished_at", "created_at"} {
+		for _, order := range []string{"asc", "desc"} {
+			f := Filter{Sort: sort, Order: order}
+			ids := []uint{}
+			err := EachByFilter(db, f, 2, func(list []models.Book) error {
+				for _, b := range list {
+					ids = append(ids, b.ID)
+				}
+				return nil
+			})
+			assert.Nil(t, err)
+
+			expected := []uint{}
+			for _, b := range GetByFilter(db, f) {
+				expected = append(expected, b.ID)
+			}
+			assert.Equal(t, ids, expected, sort+" "+order)
+		}
+	}
+}
+
+func TestGetCitationPeers(t *testing.T) {
+	db := testDB()
+
+	finished := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	other := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	b1, _ := Create(db, &models.Book{Title: "Test 1", Author: "Author 1", FinishedAt: &finished})
+	_, _ = Create(db, &models.Book{Title: "Test 2", FinishedAt: &other})
+	b3, _ := Create(db, &models.Book{Title: "Test 3"})
+	b4, _ := Create(db, &models.Book{Title: "Test 4", FinishedAt: &finished})
+	_, _ = Create(db, &models.Book{Title: "Test 5", FinishedAt: &finished})
+
+	peers := GetCitationPeers(db, []models.Book{*b4})
+	assert.Len(t, peers, 2)
+	assert.Equal(t, peers[0].Author, "Author 1")
+	assert.Equal(t, peers[1].ID, b4.ID)
+	assert.Len(t, GetCitationPeers(db, []models.Book{*b1, *b3}), 2)
+	assert.Len(t, GetCitationPeers(db, nil), 0)
+}
+
+func TestGetDuplicates(t *testing.T) {
+	db := testDB()
+
+	b1, _ := Create(db, &models.Book{Title: "Test 1", Author: "Author 1"})
+	b2, _ := Create(db, &models.Book{Title: "Test 2", ISBN: "978-0-14-303943-3"})
+	_, _ = Create(db, &models.Book{Title: "Test 1", Author: "Author 2"})
+
+	ret := GetDuplicates(db, &models.Book{Title: "test 1", Author: "AUTHOR 1"})
+	assert.Len(t, ret, 1)
+	assert.Equal(t, ret[0].ID, b1.ID)
+
+	ret = GetDuplicates(db, &models.Book{Title: "Other", ISBN: "9780143039433"})
+	assert.Len(t, ret, 1)
+	assert.Equal(t, ret[0].ID, b2.ID)
+
+	assert.Len(t, GetDuplicates(db, b1), 0)
+	assert.Len(t, GetDuplicates(db, &models.Book{Title: "Test 1"}), 0)
+}
+
+func TestGetByISBNAndGetByTitleAndAuthor(t *testing.T) {
+	db := testDB()
+
+	b1, _ := Create(db, &models.Book{Title: "Test 1", Author: "Author 1", ISBN: "0-14-303943-x"})
+	_, _ = Create(db, &models.Book{Title: "Test 2"})
+
+	assert.Equal(t, GetByISBN(db, "014303943X")[0].ID, b1.ID)
+	assert.Len(t, GetByISBN(db, ""), 0)
+
+	assert.Equal(t, GetByTitleAndAuthor(db, " TEST 1", "author 1")[0].ID, b1.ID)
+	assert.Len(t, GetByTitleAndAuthor(db, "Test 1", ""), 0)
+	assert.Len(t, GetByTitleAndAuthor(db, "Test 2", ""), 1)
+}
+
+func TestGetRecent(t *testing.T) {
+	db := testDB()
+
+	d1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d2 := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	_, _ = Create(db, &models.Book{Title: "Test 1", FinishedAt: &d1})
+	_, _ = Create(db, &models.Book{Title: "Test 2", FinishedAt: &d2})
+	_, _ = Create(db, &models.Book{Title: "Test 3"})
+
+	list := GetRecent(db, "finished_at", 10)
+	assert.Len(t, list, 2)
+	assert.Equal(t, list[0].Title, "Test 2")
+	assert.Len(t, GetRecent(db, "finished_at", 1), 1)
+
+	list = GetRecent(db, "created_at", 10)
+	assert.Len(t, list, 3)
+	assert.Equal(t, list[0].Title, "Test 3")
+}
+
 func TestSortCriteria(t *testing.T) {
 	assert.Equal(t, sortCriteria(""), "title")
 	assert.Equal(t, sortCriteria("xxx"), "title")
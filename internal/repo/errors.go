@@ -0,0 +1,5 @@
+package repo
+
+import "errors"
+
+var ErrNotFound = errors.New("ID not found")
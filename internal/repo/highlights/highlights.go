@@ -0,0 +1,81 @@
+package highlights
+
+import (
+	"errors"
+	"strings"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+
+	"gorm.io/gorm"
+)
+
+func Create(db *gorm.DB, h *models.Highlight) (*models.Highlight, error) {
+	h.ID = 0
+	h.Text = strings.TrimSpace(h.Text)
+
+	if errs := h.ValidateFields(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	ret := db.Create(h)
+	if ret.Error != nil {
+		return nil, ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return nil, errors.New("DB error")
+	}
+	return h, nil
+}
+
+func Update(db *gorm.DB, id uint, h *models.Highlight) (*models.Highlight, error) {
+	h.ID = id
+	h.Text = strings.TrimSpace(h.Text)
+
+	if errs := h.ValidateFields(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	ret := db.Model(&models.Highlight{}).
+		Where("id = ?", id).
+		Select("kind", "text", "location", "page", "added_at").
+		Updates(h)
+	if ret.Error != nil {
+		return nil, ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return nil, repo.ErrNotFound
+	}
+	return h, nil
+}
+
+func Delete(db *gorm.DB, id uint) error {
+	ret := db.Delete(&models.Highlight{}, id)
+	if ret.Error != nil {
+		return ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return repo.ErrNotFound
+	}
+	return nil
+}
+
+// GetByBook returns the highlights of a book in reading order.
+func GetByBook(db *gorm.DB, bookID uint) []models.Highlight {
+	list := []models.Highlight{}
+	_ = db.
+		Where("book_id = ?", bookID).
+		Order("CAST(location AS INTEGER), CAST(page AS INTEGER), id").
+		Find(&list)
+
+	return list
+}
+
+// GetAll returns every highlight, grouped by book in reading order.
+func GetAll(db *gorm.DB) []models.Highlight {
+	list := []models.Highlight{}
+	_ = db.
+		Order("book_id, CAST(location AS INTEGER), CAST(page AS INTEGER), id").
+		Find(&list)
+
+	return list
+}
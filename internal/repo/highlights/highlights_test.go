@@ -0,0 +1,64 @@
+package highlights
+
+import (
+	"testing"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+	"waynezhang/buku/internal/repo/books"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func testDB() *gorm.DB {
+	db, _ := database.Load(":memory:")
+	return db
+}
+
+func TestCreateAndGetByBook(t *testing.T) {
+	db := testDB()
+	b, _ := books.Create(db, &models.Book{Title: "Book 1"})
+
+	created, err := Create(db, &models.Highlight{BookID: b.ID, Kind: "quote", Text: "x"})
+	assert.Nil(t, created)
+	assert.NotNil(t, err)
+
+	_, err = Create(db, &models.Highlight{BookID: b.ID, Kind: models.HIGHLIGHT_KIND_HIGHLIGHT, Text: " Later ", Location: "1200-1202"})
+	assert.Nil(t, err)
+	_, err = Create(db, &models.Highlight{BookID: b.ID, Kind: models.HIGHLIGHT_KIND_NOTE, Text: "Earlier", Location: "300"})
+	assert.Nil(t, err)
+
+	list := GetByBook(db, b.ID)
+	assert.Len(t, list, 2)
+	assert.Equal(t, list[0].Text, "Earlier")
+	assert.Equal(t, list[1].Text, "Later")
+	assert.Len(t, GetByBook(db, b.ID+1), 0)
+
+	all := GetAll(db)
+	assert.Len(t, all, 2)
+	assert.Equal(t, all[0].Text, "Earlier")
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	db := testDB()
+	b, _ := books.Create(db, &models.Book{Title: "Book 1"})
+	h, _ := Create(db, &models.Highlight{BookID: b.ID, Kind: models.HIGHLIGHT_KIND_HIGHLIGHT, Text: "Short", Location: "10"})
+
+	_, err := Update(db, h.ID, &models.Highlight{BookID: b.ID, Kind: models.HIGHLIGHT_KIND_HIGHLIGHT, Text: "Short and long", Location: "10-12"})
+	assert.Nil(t, err)
+	assert.Equal(t, GetByBook(db, b.ID)[0].Location, "10-12")
+
+	assert.Nil(t, Delete(db, h.ID))
+	assert.Equal(t, Delete(db, h.ID), repo.ErrNotFound)
+	assert.Len(t, GetByBook(db, b.ID), 0)
+}
+
+func TestDeletedWithBook(t *testing.T) {
+	db := testDB()
+	b, _ := books.Create(db, &models.Book{Title: "Book 1"})
+	_, _ = Create(db, &models.Highlight{BookID: b.ID, Kind: models.HIGHLIGHT_KIND_HIGHLIGHT, Text: "Text"})
+
+	assert.Nil(t, books.Delete(db, b.ID))
+	assert.Len(t, GetByBook(db, b.ID), 0)
+}
@@ -0,0 +1,94 @@
+package presets
+
+import (
+	"errors"
+	"strings"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+
+	"gorm.io/gorm"
+)
+
+func Create(db *gorm.DB, preset *models.ImportPreset) (*models.ImportPreset, error) {
+	preset.ID = 0
+	preset.Name = strings.TrimSpace(preset.Name)
+
+	if errs := preset.ValidateFields(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	ret := db.Create(preset)
+	if ret.Error != nil {
+		return nil, ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return nil, errors.New("DB error")
+	}
+	return preset, nil
+}
+
+func Update(db *gorm.DB, id uint, preset *models.ImportPreset) (*models.ImportPreset, error) {
+	preset.ID = id
+	preset.Name = strings.TrimSpace(preset.Name)
+
+	if errs := preset.ValidateFields(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	ret := db.Model(&models.ImportPreset{}).
+		Where("id = ?", id).
+		Select("name", "delimiter", "columns", "date_format", "status_rules", "status_conflict").
+		Updates(preset)
+	if ret.Error != nil {
+		return nil, ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return nil, repo.ErrNotFound
+	}
+	return GetByID(db, id), nil
+}
+
+func Delete(db *gorm.DB, id uint) error {
+	ret := db.Delete(&models.ImportPreset{}, id)
+	if ret.Error != nil {
+		return ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return repo.ErrNotFound
+	}
+	return nil
+}
+
+func GetAll(db *gorm.DB) []models.ImportPreset {
+	presets := []models.ImportPreset{}
+	_ = db.Order("name").Find(&presets)
+
+	return presets
+}
+
+func GetByID(db *gorm.DB, id uint) *models.ImportPreset {
+	presets := []models.ImportPreset{}
+	_ = db.Find(&presets, id)
+
+	if len(presets) == 0 {
+		return nil
+	}
+	return &presets[0]
+}
+
+// Suggest returns the preset using the most columns of the uploaded file, nil
+// when none fits. header reads the first line of the file split with the
+// given delimiter, since presets don't have to share one.
+func Suggest(db *gorm.DB, header func(delimiter rune) []string) *models.ImportPreset {
+	var best *models.ImportPreset
+	bestScore := 0
+
+	presets := GetAll(db)
+	for i := range presets {
+		if score := presets[i].Match(header(presets[i].DelimiterRune())); score > bestScore {
+			best = &presets[i]
+			bestScore = score
+		}
+	}
+	return best
+}
@@ -0,0 +1,65 @@
+package presets
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func testDB() *gorm.DB {
+	db, _ := database.Load(":memory:")
+	return db
+}
+
+func TestCreateUpdateDelete(t *testing.T) {
+	db := testDB()
+
+	created, err := Create(db, &models.ImportPreset{Name: " "})
+	assert.Nil(t, created)
+	assert.NotNil(t, err)
+
+	created, err = Create(db, &models.ImportPreset{Name: " Monthly ", Columns: map[string]string{"Title": "Name"}})
+	assert.Nil(t, err)
+	assert.Equal(t, created.Name, "Monthly")
+
+	_, err = Create(db, &models.ImportPreset{Name: "Monthly", Columns: map[string]string{"Title": "Name"}})
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+	updated, err := Update(db, created.ID, &models.ImportPreset{
+		Name:       "Monthly",
+		Delimiter:  ";",
+		Columns:    map[string]string{"Title": "Titel", "Author": "Autor"},
+		DateFormat: "02.01.2006",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, updated.Columns["Author"], "Autor")
+	assert.Equal(t, GetByID(db, created.ID).DateFormat, "02.01.2006")
+
+	_, err = Update(db, 100, &models.ImportPreset{Name: "Other", Columns: map[string]string{"Title": "Name"}})
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+
+	assert.Nil(t, Delete(db, created.ID))
+	assert.Nil(t, GetByID(db, created.ID))
+	assert.Len(t, GetAll(db), 0)
+}
+
+func TestSuggest(t *testing.T) {
+	db := testDB()
+
+	_, _ = Create(db, &models.ImportPreset{Name: "Short", Columns: map[string]string{"Title": "Name"}})
+	long, _ := Create(db, &models.ImportPreset{Name: "Long", Columns: map[string]string{"Title": "Name", "Author": "Writer"}})
+	semi, _ := Create(db, &models.ImportPreset{Name: "Semicolon", Delimiter: ";", Columns: map[string]string{"Title": "Titel"}})
+
+	header := func(line string) func(rune) []string {
+		return func(delimiter rune) []string { return strings.Split(line, string(delimiter)) }
+	}
+	assert.Equal(t, Suggest(db, header("Name,Writer,Date")).ID, long.ID)
+	assert.Equal(t, Suggest(db, header("Titel;Autor")).ID, semi.ID)
+	assert.Nil(t, Suggest(db, header("Other,Columns")))
+}
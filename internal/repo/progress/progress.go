@@ -0,0 +1,152 @@
+package progress
+
+import (
+	"strings"
+	"time"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+	"waynezhang/buku/internal/repo/books"
+
+	"gorm.io/gorm"
+)
+
+// Save records the progress of a document, keeping the book it is linked
+// to, and moves that book forward.
+func Save(db *gorm.DB, p *models.DocumentProgress) (*models.DocumentProgress, error) {
+	p.Document = strings.TrimSpace(p.Document)
+	if errs := p.ValidateFields(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	var saved *models.DocumentProgress
+	err := db.Transaction(func(tx *gorm.DB) error {
+		existing := GetByDocument(tx, p.Document)
+		if existing == nil {
+			existing = &models.DocumentProgress{Document: p.Document}
+		}
+		existing.Progress = p.Progress
+		existing.Percentage = p.Percentage
+		existing.Device = p.Device
+		existing.DeviceID = p.DeviceID
+		existing.SyncedAt = time.Now()
+
+		if err := tx.Save(existing).Error; err != nil {
+			return err
+		}
+		saved = existing
+		return applyToBook(tx, existing)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Record saves the progress of a document read outside KOReader, as of
+// p.SyncedAt, without moving the book: importers set the book state from the
+// device. The link is kept when p.BookID is nil.
+func Record(db *gorm.DB, p *models.DocumentProgress) (*models.DocumentProgress, error) {
+	p.Document = strings.TrimSpace(p.Document)
+	if errs := p.ValidateFields(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	existing := GetByDocument(db, p.Document)
+	if existing == nil {
+		existing = &models.DocumentProgress{Document: p.Document}
+	}
+	if p.BookID != nil {
+		existing.BookID = p.BookID
+	}
+	existing.Progress = p.Progress
+	existing.Percentage = p.Percentage
+	existing.Device = p.Device
+	existing.DeviceID = p.DeviceID
+	existing.ReadingTime = p.ReadingTime
+	existing.SyncedAt = p.SyncedAt
+	if existing.SyncedAt.IsZero() {
+		existing.SyncedAt = time.Now()
+	}
+
+	if err := db.Save(existing).Error; err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// Link attaches the document to a book, or detaches it when bookID is nil,
+// creating the document if it hasn't synced yet. The book is moved forward
+// to the progress synced so far.
+func Link(db *gorm.DB, document string, bookID *uint) (*models.DocumentProgress, error) {
+	p := &models.DocumentProgress{Document: strings.TrimSpace(document)}
+	if errs := p.ValidateFields(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if bookID != nil && books.GetByID(tx, *bookID) == nil {
+			return repo.ErrNotFound
+		}
+		if existing := GetByDocument(tx, p.Document); existing != nil {
+			p = existing
+		}
+		p.BookID = bookID
+
+		if err := tx.Save(p).Error; err != nil {
+			return err
+		}
+		return applyToBook(tx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func Delete(db *gorm.DB, document string) error {
+	ret := db.Where("document = ?", document).Delete(&models.DocumentProgress{})
+	if ret.Error != nil {
+		return ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return repo.ErrNotFound
+	}
+	return nil
+}
+
+// GetAll returns every document, the most recently synced first.
+func GetAll(db *gorm.DB) []models.DocumentProgress {
+	list := []models.DocumentProgress{}
+	_ = db.Order("synced_at DESC, id DESC").Find(&list)
+
+	return list
+}
+
+func GetByBook(db *gorm.DB, bookID uint) []models.DocumentProgress {
+	list := []models.DocumentProgress{}
+	_ = db.Where("book_id = ?", bookID).Order("synced_at DESC, id DESC").Find(&list)
+
+	return list
+}
+
+func GetByDocument(db *gorm.DB, document string) *models.DocumentProgress {
+	list := []models.DocumentProgress{}
+	_ = db.Find(&list, "document = ?", document)
+
+	if len(list) == 0 {
+		return nil
+	}
+	return &list[0]
+}
+
+func applyToBook(tx *gorm.DB, p *models.DocumentProgress) error {
+	if p.BookID == nil {
+		return nil
+	}
+	b := books.GetByID(tx, *p.BookID)
+	if b == nil || !p.Apply(b, time.Now()) {
+		return nil
+	}
+	_, err := books.Update(tx, b.ID, b)
+	return err
+}
@@ -0,0 +1,90 @@
+package progress
+
+import (
+	"testing"
+	"time"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+	"waynezhang/buku/internal/repo/books"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func testDB() *gorm.DB {
+	db, _ := database.Load(":memory:")
+	return db
+}
+
+func TestSave(t *testing.T) {
+	db := testDB()
+
+	_, err := Save(db, &models.DocumentProgress{Document: " "})
+	assert.NotNil(t, err)
+
+	saved, err := Save(db, &models.DocumentProgress{Document: "abc", Progress: "/body/p[1]", Percentage: 0.1, Device: "Kobo"})
+	assert.Nil(t, err)
+	assert.False(t, saved.SyncedAt.IsZero())
+
+	_, err = Save(db, &models.DocumentProgress{Document: "abc", Progress: "/body/p[9]", Percentage: 0.5, Device: "Kindle"})
+	assert.Nil(t, err)
+
+	list := GetAll(db)
+	assert.Len(t, list, 1)
+	assert.Equal(t, list[0].Progress, "/body/p[9]")
+	assert.Equal(t, list[0].Device, "Kindle")
+	assert.Nil(t, GetByDocument(db, "other"))
+}
+
+func TestRecord(t *testing.T) {
+	db := testDB()
+	b, _ := books.Create(db, &models.Book{Title: "Book 1"})
+	read := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	p, err := Record(db, &models.DocumentProgress{Document: "kobo:1", BookID: &b.ID, Percentage: 0.5, ReadingTime: 600, SyncedAt: read})
+	assert.Nil(t, err)
+	assert.Equal(t, p.SyncedAt, read)
+	assert.Equal(t, books.GetByID(db, b.ID).Status, models.STATUS_TO_READ)
+
+	_, err = Record(db, &models.DocumentProgress{Document: "kobo:1", Percentage: 0.7, ReadingTime: 900})
+	assert.Nil(t, err)
+	saved := GetByDocument(db, "kobo:1")
+	assert.Equal(t, *saved.BookID, b.ID)
+	assert.Equal(t, saved.ReadingTime, 900)
+	assert.Len(t, GetAll(db), 1)
+}
+
+func TestLinkMovesBook(t *testing.T) {
+	db := testDB()
+	b, _ := books.Create(db, &models.Book{Title: "Book 1"})
+
+	_, _ = Save(db, &models.DocumentProgress{Document: "abc", Percentage: 0.3})
+	assert.Equal(t, books.GetByID(db, b.ID).Status, models.STATUS_TO_READ)
+
+	missing := b.ID + 1
+	_, err := Link(db, "abc", &missing)
+	assert.Equal(t, err, repo.ErrNotFound)
+
+	p, err := Link(db, "abc", &b.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, *p.BookID, b.ID)
+	assert.Equal(t, books.GetByID(db, b.ID).Status, models.STATUS_READING)
+
+	_, _ = Save(db, &models.DocumentProgress{Document: "abc", Percentage: 1})
+	updated := books.GetByID(db, b.ID)
+	assert.Equal(t, updated.Status, models.STATUS_READ)
+	assert.NotNil(t, updated.FinishedAt)
+	assert.Len(t, GetByBook(db, b.ID), 1)
+
+	// Deleting the book unlinks the document
+	assert.Nil(t, books.Delete(db, b.ID))
+	assert.Nil(t, GetByDocument(db, "abc").BookID)
+
+	_, err = Link(db, "new", nil)
+	assert.Nil(t, err)
+	assert.Len(t, GetAll(db), 2)
+
+	assert.Nil(t, Delete(db, "new"))
+	assert.Equal(t, Delete(db, "new"), repo.ErrNotFound)
+}
@@ -1,7 +1,6 @@
 package repo
 
 import (
-	"errors"
 	"strings"
 	"waynezhang/buku/internal/models"
 	"waynezhang/buku/internal/utils"
@@ -30,7 +29,7 @@ func Rename(db *gorm.DB, column string, oldName string, newName string) error {
 	oldName = strings.TrimSpace(oldName)
 	newName = strings.TrimSpace(newName)
 	if len(oldName) == 0 || len(newName) == 0 {
-		return errors.New("Invalid column name")
+		return models.ValidationError{{Field: "name", Message: "Invalid column name"}}
 	}
 
 	db.Model(&models.Book{}).
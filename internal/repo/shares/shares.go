@@ -0,0 +1,117 @@
+package shares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+	"waynezhang/buku/internal/repo/books"
+
+	"gorm.io/gorm"
+)
+
+const tokenBytes = 24
+
+// Recent shares list at most this many books.
+const RECENT_LIMIT = 50
+
+var recentColumns = map[string]string{
+	models.SHARE_RECENT_FINISHED: "finished_at",
+	models.SHARE_RECENT_STARTED:  "started_at",
+	models.SHARE_RECENT_ADDED:    "created_at",
+}
+
+func Create(db *gorm.DB, share *models.Share) (*models.Share, error) {
+	share.ID = 0
+	share.Value = strings.TrimSpace(share.Value)
+
+	if errs := share.ValidateFields(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	share.Token = token
+
+	ret := db.Create(share)
+	if ret.Error != nil {
+		return nil, ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return nil, errors.New("DB error")
+	}
+	return share, nil
+}
+
+func Delete(db *gorm.DB, id uint) error {
+	ret := db.Delete(&models.Share{}, id)
+	if ret.Error != nil {
+		return ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return repo.ErrNotFound
+	}
+	return nil
+}
+
+func GetAll(db *gorm.DB) []models.Share {
+	shares := []models.Share{}
+	_ = db.Order("created_at DESC").Find(&shares)
+
+	return shares
+}
+
+func GetByToken(db *gorm.DB, token string) *models.Share {
+	if len(token) == 0 {
+		return nil
+	}
+
+	shares := []models.Share{}
+	_ = db.Find(&shares, "token = ?", token)
+
+	if len(shares) == 0 {
+		return nil
+	}
+	return &shares[0]
+}
+
+// Books returns the books matched by the share filter. Comments are
+// stripped unless the share explicitly exposes them.
+func Books(db *gorm.DB, share *models.Share) []models.Book {
+	var list []models.Book
+	switch share.Kind {
+	case models.SHARE_KIND_YEAR:
+		year, _ := strconv.Atoi(share.Value)
+		list = books.GetByYear(db, year)
+	case models.SHARE_KIND_SERIES:
+		list = books.GetBySeries(db, share.Value, "finished_at", "asc")
+	case models.SHARE_KIND_AUTHOR:
+		list = books.GetByAuthor(db, share.Value)
+	case models.SHARE_KIND_STATUS:
+		list = books.GetByStatus(db, books.ReadStatus(share.Value))
+	case models.SHARE_KIND_RECENT:
+		list = books.GetRecent(db, recentColumns[share.Value], RECENT_LIMIT)
+	default:
+		list = []models.Book{}
+	}
+
+	if !share.ShowComments {
+		for i := range list {
+			list[i].Comments = ""
+		}
+	}
+	return list
+}
+
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,83 @@
+package shares
+
+import (
+	"testing"
+	"time"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func testDB() *gorm.DB {
+	db, _ := database.Load(":memory:")
+	return db
+}
+
+func TestCreate(t *testing.T) {
+	db := testDB()
+
+	created, err := Create(db, &models.Share{Kind: "tag", Value: "x"})
+	assert.Nil(t, created)
+	assert.NotNil(t, err)
+
+	created, err = Create(db, &models.Share{Kind: models.SHARE_KIND_YEAR, Value: " 2025 "})
+	assert.Nil(t, err)
+	assert.NotNil(t, created)
+	assert.Equal(t, created.Value, "2025")
+	assert.Len(t, created.Token, tokenBytes*2)
+
+	other, _ := Create(db, &models.Share{Kind: models.SHARE_KIND_YEAR, Value: "2025"})
+	assert.NotEqual(t, created.Token, other.Token)
+	assert.Len(t, GetAll(db), 2)
+}
+
+func TestGetByTokenAndDelete(t *testing.T) {
+	db := testDB()
+
+	s, _ := Create(db, &models.Share{Kind: models.SHARE_KIND_SERIES, Value: "Series 1"})
+
+	assert.Nil(t, GetByToken(db, ""))
+	assert.Nil(t, GetByToken(db, "unknown"))
+	assert.Equal(t, GetByToken(db, s.Token).ID, s.ID)
+
+	assert.Nil(t, Delete(db, s.ID))
+	assert.Nil(t, GetByToken(db, s.Token))
+	assert.NotNil(t, Delete(db, s.ID))
+}
+
+func TestBooks(t *testing.T) {
+	db := testDB()
+
+	now := time.Now()
+	_, _ = books.Create(db, &models.Book{Title: "Test 1", Comments: "secret", FinishedAt: &now})
+	_, _ = books.Create(db, &models.Book{Title: "Test 2", Series: "Series 1"})
+	_, _ = books.Create(db, &models.Book{Title: "Test 3", Author: "Author 1", StartedAt: &now})
+
+	year := &models.Share{Kind: models.SHARE_KIND_YEAR, Value: now.Format("2006")}
+	ret := Books(db, year)
+	assert.Len(t, ret, 1)
+	assert.Equal(t, ret[0].Title, "Test 1")
+	assert.Equal(t, ret[0].Comments, "")
+
+	year.ShowComments = true
+	assert.Equal(t, Books(db, year)[0].Comments, "secret")
+
+	ret = Books(db, &models.Share{Kind: models.SHARE_KIND_SERIES, Value: "Series 1"})
+	assert.Equal(t, ret[0].Title, "Test 2")
+
+	ret = Books(db, &models.Share{Kind: models.SHARE_KIND_AUTHOR, Value: "Author 1"})
+	assert.Equal(t, ret[0].Title, "Test 3")
+
+	ret = Books(db, &models.Share{Kind: models.SHARE_KIND_STATUS, Value: models.STATUS_READING})
+	assert.Equal(t, ret[0].Title, "Test 3")
+
+	ret = Books(db, &models.Share{Kind: models.SHARE_KIND_RECENT, Value: models.SHARE_RECENT_FINISHED})
+	assert.Len(t, ret, 1)
+	assert.Equal(t, ret[0].Title, "Test 1")
+
+	ret = Books(db, &models.Share{Kind: models.SHARE_KIND_RECENT, Value: models.SHARE_RECENT_ADDED})
+	assert.Len(t, ret, 3)
+}
@@ -14,24 +14,24 @@ type LoginRequest struct {
 func apiLogin(c *fiber.Ctx, cfg *config.Config) error {
 	req := new(LoginRequest)
 	if err := c.BodyParser(req); err != nil {
-		return renderJSONError(c, "Invalid request")
+		return errBadRequest("Invalid request")
 	}
 
 	if req.Username != cfg.Username || req.Password != cfg.Password {
-		return renderJSONError(c, "Invalid credentials")
+		return newAPIError(fiber.StatusUnauthorized, ERROR_INVALID_CREDENTIALS, "Invalid credentials")
 	}
 
 	// Create session
 	sess, err := store.Get(c)
 	if err != nil {
-		return renderJSONError(c, "Session error")
+		return err
 	}
 
 	sess.Set("authenticated", true)
 	sess.Set("username", req.Username)
 	
 	if err := sess.Save(); err != nil {
-		return renderJSONError(c, "Session save error")
+		return err
 	}
 
 	return c.JSON(fiber.Map{
@@ -44,12 +44,14 @@ func apiLogin(c *fiber.Ctx, cfg *config.Config) error {
 func apiLogout(c *fiber.Ctx) error {
 	sess, err := store.Get(c)
 	if err != nil {
-		return renderJSONError(c, "Session error")
+		return err
 	}
 
 	if err := sess.Destroy(); err != nil {
-		return renderJSONError(c, "Logout error")
+		return err
 	}
+	// The CSRF token lived in the destroyed session
+	c.ClearCookie(CSRF_COOKIE_NAME)
 
 	return renderJSONOKMessage(c)
 }
@@ -2,7 +2,7 @@ package route
 
 import (
 	"net/url"
-	"waynezhang/buku/internal/repo"
+	"waynezhang/buku/internal/repo/authors"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -11,8 +11,7 @@ import (
 func apiAuthors(c *fiber.Ctx, db *gorm.DB) error {
 	name := c.Query("name")
 	order := c.Query("order")
-	authors := repo.GetAll(db, "author", name, order)
-	return c.JSON(authors)
+	return c.JSON(authors.GetAll(db, name, order))
 }
 
 func apiRenameAuthor(c *fiber.Ctx, db *gorm.DB) error {
@@ -22,13 +21,13 @@ func apiRenameAuthor(c *fiber.Ctx, db *gorm.DB) error {
 
 	r := new(renameAuthorRequest)
 	if err := c.BodyParser(&r); err != nil {
-		return renderJSONError(c, err.Error())
+		return errBadRequest(err.Error())
 	}
 
 	oldName, _ := url.QueryUnescape(c.Params("name"))
 
-	if err := repo.Rename(db, "author", oldName, r.Name); err != nil {
-		return renderJSONError(c, err.Error())
+	if err := authors.Rename(db, oldName, r.Name); err != nil {
+		return err
 	}
 
 	return renderJSONOKMessage(c)
@@ -5,6 +5,7 @@ import (
 	"slices"
 	"time"
 	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/authors"
 	"waynezhang/buku/internal/repo/books"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,12 +13,11 @@ import (
 )
 
 func apiBooks(c *fiber.Ctx, db *gorm.DB) error {
-	name := c.Query("name")
-	order := c.Query("order")
-	sort := c.Query("sort", "")
-	status := c.Query("status", "")
-	books := books.GetByKeyword(db, name, sort, order, status)
-	return c.JSON(books)
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(books.GetByFilter(db, filter))
 }
 
 func apiBooksByStatus(c *fiber.Ctx, db *gorm.DB) error {
@@ -45,29 +45,33 @@ func apiBooksBySeries(c *fiber.Ctx, db *gorm.DB) error {
 // book
 func apiBookById(c *fiber.Ctx, db *gorm.DB) error {
 	return withQueryBook(db, c, func(b *models.Book) error {
+		b.Credits = authors.GetByBook(db, b.ID)
 		return c.JSON(b)
 	})
 }
 func apiCreateBook(c *fiber.Ctx, db *gorm.DB) error {
-	book, errs := parseBodyAsBook(c)
-	if len(errs) > 0 {
-		return renderJSONError(c, errs[0])
+	book, err := parseBodyAsBook(c)
+	if err != nil {
+		return err
 	}
 
-	created, _ := books.Create(db, book)
+	created, err := books.Create(db, book)
+	if err != nil {
+		return err
+	}
 
-	return c.JSON(created)
+	return c.Status(fiber.StatusCreated).JSON(created)
 }
 
 func apiUpdateBook(c *fiber.Ctx, db *gorm.DB) error {
 	return withQueryBook(db, c, func(old *models.Book) error {
-		book, errs := parseBodyAsBook(c)
-		if len(errs) > 0 {
-			return renderJSONError(c, errs[0])
+		book, err := parseBodyAsBook(c)
+		if err != nil {
+			return err
 		}
 		updated, err := books.Update(db, old.ID, book)
 		if err != nil {
-			return renderJSONError(c, err.Error())
+			return err
 		}
 
 		return c.JSON(updated)
@@ -77,10 +81,12 @@ func apiUpdateBook(c *fiber.Ctx, db *gorm.DB) error {
 func apiDeleteBookById(c *fiber.Ctx, db *gorm.DB) error {
 	id := parseID(c)
 	if id == nil {
-		return renderJSONError(c, "ID is invalid")
+		return errBadRequest("ID is invalid")
 	}
 
-	_ = books.Delete(db, *id)
+	if err := books.Delete(db, *id); err != nil {
+		return err
+	}
 
 	return renderJSONOKMessage(c)
 }
@@ -92,7 +98,9 @@ func apiBookChangeStatus(c *fiber.Ctx, db *gorm.DB) error {
 
 	return withQueryBook(db, c, func(b *models.Book) error {
 		r := bookChangeStatusRequest{}
-		_ = c.BodyParser(&r)
+		if err := c.BodyParser(&r); err != nil {
+			return errBadRequest("Invalid request body")
+		}
 		s := r.Status
 		statuses := []string{
 			models.STATUS_TO_READ,
@@ -100,10 +108,11 @@ func apiBookChangeStatus(c *fiber.Ctx, db *gorm.DB) error {
 			models.STATUS_READING,
 		}
 		if !slices.Contains(statuses, s) {
-			return renderJSONError(c, "Invalid status")
+			return errValidation("status", "Invalid status")
 		}
 
 		t := time.Now()
+		b.Status = s
 		switch s {
 		case models.STATUS_TO_READ:
 			b.StartedAt = nil
@@ -116,7 +125,7 @@ func apiBookChangeStatus(c *fiber.Ctx, db *gorm.DB) error {
 		}
 		_, err := books.Update(db, b.ID, b)
 		if err != nil {
-			return renderJSONError(c, err.Error())
+			return err
 		}
 
 		return renderJSONOKMessage(c)
@@ -126,13 +135,13 @@ func apiBookChangeStatus(c *fiber.Ctx, db *gorm.DB) error {
 func withQueryBook(db *gorm.DB, c *fiber.Ctx, fn func(*models.Book) error) error {
 	id := parseID(c)
 	if id == nil {
-		return renderJSONError(c, "Book is not found")
+		return errNotFound("Book is not found")
 	}
 
 	book := books.GetByID(db, *id)
 	if book != nil {
 		return fn(book)
 	} else {
-		return renderJSONError(c, "Book is not found")
+		return errNotFound("Book is not found")
 	}
 }
@@ -0,0 +1,46 @@
+package route
+
+import (
+	"strconv"
+	"waynezhang/buku/internal/repo/progress"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// apiDocuments lists the documents synced by KOReader, only those linked to
+// the book when "book_id" is given.
+func apiDocuments(c *fiber.Ctx, db *gorm.DB) error {
+	if str := c.Query("book_id"); len(str) > 0 {
+		id, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return errValidation("book_id", "Book ID is invalid")
+		}
+		return c.JSON(progress.GetByBook(db, uint(id)))
+	}
+	return c.JSON(progress.GetAll(db))
+}
+
+// apiLinkDocument links the document to a book, or unlinks it when
+// "book_id" is null.
+func apiLinkDocument(c *fiber.Ctx, db *gorm.DB) error {
+	r := struct {
+		BookID *uint `json:"book_id"`
+	}{}
+	if err := c.BodyParser(&r); err != nil {
+		return errBadRequest("Invalid request body")
+	}
+
+	p, err := progress.Link(db, c.Params("document"), r.BookID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(p)
+}
+
+func apiDeleteDocument(c *fiber.Ctx, db *gorm.DB) error {
+	if err := progress.Delete(db, c.Params("document")); err != nil {
+		return err
+	}
+	return renderJSONOKMessage(c)
+}
@@ -0,0 +1,75 @@
+package route
+
+import (
+	"waynezhang/buku/internal/importer"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/highlights"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func apiBookHighlights(c *fiber.Ctx, db *gorm.DB) error {
+	return withQueryBook(db, c, func(b *models.Book) error {
+		return c.JSON(highlights.GetByBook(db, b.ID))
+	})
+}
+
+func apiDeleteHighlightById(c *fiber.Ctx, db *gorm.DB) error {
+	id := parseID(c)
+	if id == nil {
+		return errBadRequest("ID is invalid")
+	}
+
+	if err := highlights.Delete(db, *id); err != nil {
+		return err
+	}
+
+	return renderJSONOKMessage(c)
+}
+
+// apiImportClippings imports the highlights and notes of an uploaded Kindle
+// "My Clippings.txt".
+func apiImportClippings(c *fiber.Ctx, db *gorm.DB) error {
+	files, err := c.FormFile("file")
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	f, err := files.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	clippings, err := importer.ParseClippings(f)
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	result, err := importer.CommitClippings(db, clippings)
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
+
+// apiImportKobo imports the reading state and highlights of an uploaded
+// KoboReader.sqlite.
+func apiImportKobo(c *fiber.Ctx, db *gorm.DB) error {
+	var list []importer.KoboBook
+	err := withUploadedDB(c, func(path string) error {
+		var err error
+		list, err = importer.ParseKobo(path)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	result, err := importer.CommitKobo(db, list)
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
@@ -1,129 +1,420 @@
 package route
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"waynezhang/buku/internal/backup"
+	"waynezhang/buku/internal/exporter"
+	"waynezhang/buku/internal/importer"
 	"waynezhang/buku/internal/models"
 	"waynezhang/buku/internal/repo/books"
+	"waynezhang/buku/internal/repo/highlights"
+	"waynezhang/buku/internal/repo/presets"
+	"waynezhang/buku/internal/vault"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
 	"gorm.io/gorm"
 )
 
+// Formats of the CSV export, which are their file extensions too.
 const (
-	CSV_COLUMN_Title    = "Title"
-	CSV_COLUMN_Author   = "Author"
-	CSV_COLUMN_Series   = "Series"
-	CSV_COLUMN_ISBN     = "ISBN"
-	CSV_COLUMN_Comments = "Comments"
-	CSV_COLUMN_Started  = "Started"
-	CSV_COLUMN_Finished = "Finished"
+	EXPORT_FORMAT_CSV = "csv"
+	EXPORT_FORMAT_TSV = "tsv"
 )
 
-func apiImportReadColumns(c *fiber.Ctx) error {
-	err := withCSVFileReader(c, func(r csv.Reader) error {
+// EXPORT_BATCH_SIZE is the number of books read from the database, and
+// flushed to the client, at a time while exporting.
+const EXPORT_BATCH_SIZE = 500
+
+// apiImportReadColumns returns the header of the uploaded CSV, together with
+// the saved preset matching it best, if any.
+func apiImportReadColumns(c *fiber.Ctx, db *gorm.DB) error {
+	if isSQLiteUpload(c) {
+		return c.JSON(map[string]interface{}{
+			"presets":          importFields,
+			"columns":          []string{},
+			"format":           importer.FORMAT_CALIBRE,
+			"suggested_preset": nil,
+		})
+	}
+
+	err := withCSVFileReader(c, formDelimiter(c), func(r *csv.Reader) error {
 		columns, err := r.Read()
 		if err != nil {
 			return err
 		}
+
+		format := importer.FORMAT_CSV
+		if importer.IsStoryGraph(columns) {
+			format = importer.FORMAT_STORYGRAPH
+		}
+
+		suggested := presets.Suggest(db, func(delimiter rune) []string {
+			header := []string{}
+			_ = withCSVFileReader(c, delimiter, func(r *csv.Reader) error {
+				header, err = r.Read()
+				return err
+			})
+			return header
+		})
+
 		return c.JSON(map[string]interface{}{
-			"presets": []string{
-				CSV_COLUMN_Title,
-				CSV_COLUMN_Author,
-				CSV_COLUMN_Series,
-				CSV_COLUMN_ISBN,
-				CSV_COLUMN_Comments,
-				CSV_COLUMN_Started,
-				CSV_COLUMN_Finished,
-			},
-			"columns": append([]string{"-"}, columns...),
+			"presets":          importFields,
+			"columns":          append([]string{"-"}, columns...),
+			"format":           format,
+			"suggested_preset": suggested,
 		})
 	})
 	if err != nil {
-		return renderJSONError(c, err.Error())
+		return errBadRequest(err.Error())
 	}
 	return nil
 }
 
+func apiImportPreview(c *fiber.Ctx, db *gorm.DB) error {
+	parsed, err := parseUpload(c, db)
+	if err != nil {
+		return err
+	}
+	importer.Check(db, parsed.Rows)
+	return c.JSON(importer.NewPreview(parsed))
+}
+
+// apiImport imports the uploaded CSV. When "accepted" is given (a comma
+// separated list of line numbers from the preview) only those rows are
+// imported, otherwise every valid row is. "conflict" and "match" decide what
+// happens to rows matching an existing book.
 func apiImport(c *fiber.Ctx, db *gorm.DB) error {
-	findColumnIdx := func(c *fiber.Ctx, name string, columns []string) int {
-		colName := c.FormValue(name)
-		return slices.Index(columns, colName)
+	opts, err := parseCommitOptions(c)
+	if err != nil {
+		return err
 	}
-	getStrVal := func(idx int, record []string) string {
-		if idx < 0 || idx >= len(record) {
-			return ""
-		}
-		return record[idx]
+	parsed, err := parseUpload(c, db)
+	if err != nil {
+		return err
+	}
+
+	result, err := importer.Commit(c.Context(), db, parsed.Rows, opts)
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
+
+// apiStartImportJob takes the same parameters as apiImport but imports in
+// the background. Progress is polled with apiImportJob. The request only
+// checks the settings and saves the upload; the job parses it and commits
+// the rows batch by batch, letting other writes through.
+func apiStartImportJob(c *fiber.Ctx, db *gorm.DB) error {
+	opts, err := parseCommitOptions(c)
+	if err != nil {
+		return err
 	}
-	getTimeVal := func(idx int, record []string) *time.Time {
-		if idx < 0 || idx >= len(record) {
-			return nil
+	upload, err := saveImportUpload(c, db)
+	if err != nil {
+		return err
+	}
+
+	job, err := importJobs.Start(db, func() ([]importer.Row, error) {
+		defer upload.remove()
+		parsed, err := upload.parse()
+		if err != nil {
+			return nil, err
 		}
-		str := record[idx]
-		t, err := time.Parse(time.RFC3339, str)
-		if err == nil {
-			return &t
+		return parsed.Rows, nil
+	}, opts)
+	if err != nil {
+		upload.remove()
+		return err
+	}
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+func apiImportJob(c *fiber.Ctx) error {
+	job := importJobs.Get(c.Params("id"))
+	if job == nil {
+		return errNotFound("Import job not found")
+	}
+	return c.JSON(job)
+}
+
+func apiCancelImportJob(c *fiber.Ctx) error {
+	job := importJobs.Cancel(c.Params("id"))
+	if job == nil {
+		return errNotFound("Import job not found")
+	}
+	return c.JSON(job)
+}
+
+func parseCommitOptions(c *fiber.Ctx) (importer.CommitOptions, error) {
+	opts := importer.CommitOptions{
+		Conflict: c.FormValue("conflict", importer.CONFLICT_SKIP),
+		Match:    c.FormValue("match", importer.MATCH_ANY),
+	}
+	if !slices.Contains(importer.ConflictPolicies, opts.Conflict) {
+		return opts, errValidation("conflict", "Invalid conflict policy")
+	}
+	if !slices.Contains(importer.MatchKeys, opts.Match) {
+		return opts, errValidation("match", "Invalid match key")
+	}
+
+	if form, err := c.MultipartForm(); err == nil && len(form.Value["accepted"]) > 0 {
+		lines := map[int]bool{}
+		for _, str := range strings.Split(form.Value["accepted"][0], ",") {
+			if len(strings.TrimSpace(str)) == 0 {
+				continue
+			}
+			line, err := strconv.Atoi(strings.TrimSpace(str))
+			if err != nil {
+				return opts, errValidation("accepted", "Invalid line number")
+			}
+			lines[line] = true
 		}
-		t, err = time.Parse("2006-01-02", str)
-		if err == nil {
-			return &t
+		opts.Accept = func(row *importer.Row) bool { return lines[row.Line] }
+	}
+	return opts, nil
+}
+
+// parseUpload parses the uploaded file, see saveImportUpload.
+func parseUpload(c *fiber.Ctx, db *gorm.DB) (*importer.Parsed, error) {
+	upload, err := saveImportUpload(c, db)
+	if err != nil {
+		return nil, err
+	}
+	defer upload.remove()
+	return upload.parse()
+}
+
+// importUpload is an uploaded import file saved to disk, so that it can be
+// parsed after the request is over, with the settings to parse it with. An
+// empty format is detected from the file.
+type importUpload struct {
+	path      string
+	format    string
+	delimiter rune
+	opts      importer.Options
+}
+
+// saveImportUpload checks the import settings and saves the uploaded file.
+// "format" picks the parser; without it, and without a preset, Calibre
+// libraries are recognised by their SQLite header and StoryGraph exports by
+// their CSV header. Everything else is read with the column mapping.
+func saveImportUpload(c *fiber.Ctx, db *gorm.DB) (*importUpload, error) {
+	format := c.FormValue("format")
+	if !slices.Contains([]string{"", importer.FORMAT_CSV, importer.FORMAT_STORYGRAPH, importer.FORMAT_CALIBRE}, format) {
+		return nil, errValidation("format", "Invalid format")
+	}
+
+	upload := &importUpload{format: format}
+	if len(format) == 0 && len(c.FormValue("preset")) > 0 {
+		upload.format = importer.FORMAT_CSV
+	}
+	if len(upload.format) == 0 && isSQLiteUpload(c) {
+		upload.format = importer.FORMAT_CALIBRE
+	}
+	if upload.format != importer.FORMAT_CALIBRE {
+		delimiter, opts, err := parseImportSettings(c, db)
+		if err != nil {
+			return nil, err
 		}
-		return nil
+		upload.delimiter = delimiter
+		upload.opts = opts
 	}
-	err := withCSVFileReader(c, func(r csv.Reader) error {
-		columns, err := r.Read()
+
+	files, err := c.FormFile("file")
+	if err != nil {
+		return nil, errBadRequest(err.Error())
+	}
+	tmp, err := os.CreateTemp("", "buku-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	upload.path = tmp.Name()
+	if err := c.SaveFile(files, upload.path); err != nil {
+		upload.remove()
+		return nil, err
+	}
+	return upload, nil
+}
+
+// parse parses the saved file. Errors are reported as bad requests.
+func (u *importUpload) parse() (*importer.Parsed, error) {
+	parsed, err := u.parseFile()
+	if err != nil {
+		return nil, errBadRequest(err.Error())
+	}
+	return parsed, nil
+}
+
+func (u *importUpload) parseFile() (*importer.Parsed, error) {
+	if u.format == importer.FORMAT_CALIBRE {
+		return importer.ParseCalibre(u.path)
+	}
+
+	f, err := os.Open(u.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	format := u.format
+	if len(format) == 0 {
+		header, err := importer.NewCSVReader(f, u.delimiter).Read()
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if importer.IsStoryGraph(header) {
+			format = importer.FORMAT_STORYGRAPH
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
 		}
-		records, err := r.ReadAll()
+	}
+
+	r := importer.NewCSVReader(f, u.delimiter)
+	if format == importer.FORMAT_STORYGRAPH {
+		return importer.ParseStoryGraph(r)
+	}
+	return importer.Parse(r, u.opts)
+}
+
+func (u *importUpload) remove() {
+	_ = os.Remove(u.path)
+}
+
+// parseImportSettings returns the delimiter and parse options of an upload,
+// taken from the saved preset when "preset" is given and from the form
+// otherwise.
+func parseImportSettings(c *fiber.Ctx, db *gorm.DB) (rune, importer.Options, error) {
+	if len(c.FormValue("preset")) > 0 {
+		id, err := strconv.Atoi(c.FormValue("preset"))
 		if err != nil {
-			return err
+			return 0, importer.Options{}, errValidation("preset", "Invalid preset")
+		}
+		preset := presets.GetByID(db, uint(id))
+		if preset == nil {
+			return 0, importer.Options{}, errValidation("preset", "Preset not found")
+		}
+		return preset.DelimiterRune(), importer.Options{
+			Mapping:        importMapping(preset.Columns),
+			DateFormat:     preset.DateFormat,
+			StatusRules:    preset.StatusRules,
+			StatusConflict: preset.StatusConflict,
+		}, nil
+	}
+
+	columns := map[string]string{}
+	for _, field := range importFields {
+		columns[field] = c.FormValue(field)
+	}
+	opts := importer.Options{
+		Mapping:        importMapping(columns),
+		DateFormat:     c.FormValue("date_format"),
+		StatusConflict: c.FormValue("status_conflict", models.STATUS_CONFLICT_DATES),
+	}
+	if opts.StatusConflict != models.STATUS_CONFLICT_DATES && opts.StatusConflict != models.STATUS_CONFLICT_STATUS {
+		return 0, importer.Options{}, errValidation("status_conflict", "Status conflict rule is invalid")
+	}
+	if rules := c.FormValue("status_rules"); len(rules) > 0 {
+		if err := json.Unmarshal([]byte(rules), &opts.StatusRules); err != nil {
+			return 0, importer.Options{}, errValidation("status_rules", "Status rules must be a JSON object")
 		}
-		titleIdx := findColumnIdx(c, CSV_COLUMN_Title, columns)
-		authorIdx := findColumnIdx(c, CSV_COLUMN_Author, columns)
-		seriesIdx := findColumnIdx(c, CSV_COLUMN_Series, columns)
-		isbnIdx := findColumnIdx(c, CSV_COLUMN_ISBN, columns)
-		commentsIdx := findColumnIdx(c, CSV_COLUMN_Comments, columns)
-		startedIdx := findColumnIdx(c, CSV_COLUMN_Started, columns)
-		finishedIdx := findColumnIdx(c, CSV_COLUMN_Finished, columns)
-
-		succeed := 0
-		failed := 0
-		total := 0
-		for _, rec := range records {
-			b := models.Book{}
-			b.Title = getStrVal(titleIdx, rec)
-			b.Author = getStrVal(authorIdx, rec)
-			b.Series = getStrVal(seriesIdx, rec)
-			b.ISBN = getStrVal(isbnIdx, rec)
-			b.Comments = getStrVal(commentsIdx, rec)
-			b.StartedAt = getTimeVal(startedIdx, rec)
-			b.FinishedAt = getTimeVal(finishedIdx, rec)
-			errs := b.Validate()
-			if len(errs) == 0 {
-				succeed += 1
-			} else {
-				failed += 1
+		for _, status := range opts.StatusRules {
+			if !slices.Contains(models.Statuses, status) {
+				return 0, importer.Options{}, errValidation("status_rules", "Status \""+status+"\" is invalid")
 			}
-			total += 1
-			_, _ = books.Create(db, &b)
 		}
-		return c.JSON(map[string]interface{}{
-			"total":     total,
-			"succeeded": succeed,
-			"failed":    failed,
-		})
-	})
+	}
+	return formDelimiter(c), opts, nil
+}
+
+var importFields = []string{
+	exporter.CSV_COLUMN_Title,
+	exporter.CSV_COLUMN_Author,
+	exporter.CSV_COLUMN_Series,
+	exporter.CSV_COLUMN_ISBN,
+	exporter.CSV_COLUMN_Comments,
+	exporter.CSV_COLUMN_Started,
+	exporter.CSV_COLUMN_Finished,
+	exporter.CSV_COLUMN_Status,
+}
+
+func importMapping(columns map[string]string) importer.Mapping {
+	return importer.Mapping{
+		Title:    columns[exporter.CSV_COLUMN_Title],
+		Author:   columns[exporter.CSV_COLUMN_Author],
+		Series:   columns[exporter.CSV_COLUMN_Series],
+		ISBN:     columns[exporter.CSV_COLUMN_ISBN],
+		Comments: columns[exporter.CSV_COLUMN_Comments],
+		Started:  columns[exporter.CSV_COLUMN_Started],
+		Finished: columns[exporter.CSV_COLUMN_Finished],
+		Status:   columns[exporter.CSV_COLUMN_Status],
+	}
+}
+
+// isSQLiteUpload reports whether the uploaded file is a SQLite database.
+func isSQLiteUpload(c *fiber.Ctx) bool {
+	files, err := c.FormFile("file")
+	if err != nil {
+		return false
+	}
+
+	f, err := files.Open()
 	if err != nil {
-		return renderJSONError(c, err.Error())
+		return false
+	}
+	defer f.Close()
+
+	head := make([]byte, 16)
+	n, _ := io.ReadFull(f, head)
+	return importer.IsSQLite(head[:n])
+}
+
+// withUploadedDB copies the uploaded SQLite database to a temporary file,
+// since SQLite can only open databases on disk, and calls fn with its path.
+// Errors of fn are reported as bad requests.
+func withUploadedDB(c *fiber.Ctx, fn func(path string) error) error {
+	files, err := c.FormFile("file")
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	tmp, err := os.CreateTemp("", "buku-upload-*.db")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := c.SaveFile(files, tmp.Name()); err != nil {
+		return err
+	}
+
+	if err := fn(tmp.Name()); err != nil {
+		return errBadRequest(err.Error())
 	}
 	return nil
 }
 
-func withCSVFileReader(c *fiber.Ctx, fn func(csv.Reader) error) error {
+func formDelimiter(c *fiber.Ctx) rune {
+	if d := []rune(c.FormValue("delimiter")); len(d) > 0 {
+		return d[0]
+	}
+	return ','
+}
+
+func withCSVFileReader(c *fiber.Ctx, delimiter rune, fn func(*csv.Reader) error) error {
 	files, err := c.FormFile("file")
 	if err != nil {
 		return err
@@ -135,50 +426,184 @@ func withCSVFileReader(c *fiber.Ctx, fn func(csv.Reader) error) error {
 	}
 	defer f.Close()
 
-	reader := csv.NewReader(f)
-	reader.Comma = []rune(c.FormValue("delimiter", ","))[0]
-	reader.FieldsPerRecord = -1
-	reader.LazyQuotes = true
-
-	return fn(*reader)
+	return fn(importer.NewCSVReader(f, delimiter))
 }
 
+// handleCSVExportRequest exports the books matching the filters of the book
+// list as CSV or TSV, with the selected columns. Rows are streamed from the
+// database to the response in batches, so memory stays flat whatever the
+// size of the library.
 func handleCSVExportRequest(c *fiber.Ctx, db *gorm.DB) error {
-	b := new(bytes.Buffer)
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		return err
+	}
+	opts, ext, err := parseCSVExportOptions(c)
+	if err != nil {
+		return err
+	}
 
-	w := csv.NewWriter(b)
-	w.Write([]string{
-		CSV_COLUMN_Title,
-		CSV_COLUMN_Author,
-		CSV_COLUMN_Series,
-		CSV_COLUMN_ISBN,
-		CSV_COLUMN_Comments,
-		CSV_COLUMN_Started,
-		CSV_COLUMN_Finished,
-	})
-	for _, b := range books.GetAll(db) {
-		startedAt := ""
-		if b.StartedAt != nil {
-			startedAt = b.StartedAt.Format(time.RFC3339)
+	now := time.Now().Format("2006-01-02")
+	c.Attachment("buku-" + now + "." + ext)
+
+	// The status is sent with the first chunk, so errors past this point
+	// can't be reported. They close the pipe with the error instead, which
+	// makes the server drop the connection before the last chunk, so that
+	// the client sees a broken download rather than a short file.
+	pr, pw := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(pw)
+		err := func() error {
+			cw, err := exporter.NewCSVWriter(w, opts)
+			if err != nil {
+				return err
+			}
+			// Start the download with the header
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			return books.EachByFilter(db, filter, EXPORT_BATCH_SIZE, func(list []models.Book) error {
+				if err := cw.Write(list); err != nil {
+					return err
+				}
+				return w.Flush()
+			})
+		}()
+		if err == nil {
+			err = w.Flush()
 		}
-		finishedAt := ""
-		if b.FinishedAt != nil {
-			finishedAt = b.FinishedAt.Format(time.RFC3339)
+		if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			log.Errorf("Export failed: %s", err.Error())
 		}
-		w.Write([]string{
-			b.Title,
-			b.Author,
-			b.Series,
-			b.ISBN,
-			b.Comments,
-			startedAt,
-			finishedAt,
-		})
+		pw.CloseWithError(err)
+	}()
+	c.Context().SetBodyStream(pr, -1)
+	return nil
+}
+
+// parseCSVExportOptions reads the columns, format, delimiter and bom of the
+// query. The delimiter overrides the one of the format. It returns the file
+// extension of the format too.
+func parseCSVExportOptions(c *fiber.Ctx) (exporter.CSVOptions, string, error) {
+	opts := exporter.CSVOptions{Delimiter: ','}
+
+	columns, err := exporter.ParseColumns(c.Query("columns"))
+	if err != nil {
+		return opts, "", errBadRequest(err.Error())
+	}
+	opts.Columns = columns
+
+	ext := EXPORT_FORMAT_CSV
+	switch c.Query("format", EXPORT_FORMAT_CSV) {
+	case EXPORT_FORMAT_CSV:
+	case EXPORT_FORMAT_TSV:
+		ext = EXPORT_FORMAT_TSV
+		opts.Delimiter = '\t'
+	default:
+		return opts, "", errBadRequest("Format is invalid")
+	}
+
+	if d := []rune(c.Query("delimiter")); len(d) > 0 {
+		if len(d) > 1 || d[0] == '"' || d[0] == '\r' || d[0] == '\n' {
+			return opts, "", errBadRequest("Delimiter is invalid")
+		}
+		opts.Delimiter = d[0]
+	}
+	opts.BOM = c.QueryBool("bom")
+	return opts, ext, nil
+}
+
+// handleGoodreadsExportRequest exports the library as a Goodreads library
+// export, for Goodreads, StoryGraph and the other trackers importing it.
+func handleGoodreadsExportRequest(c *fiber.Ctx, db *gorm.DB) error {
+	b := new(bytes.Buffer)
+	if err := exporter.WriteGoodreads(b, books.GetAll(db)); err != nil {
+		return err
+	}
+
+	now := time.Now().Format("2006-01-02")
+	c.Attachment("buku-goodreads-" + now + ".csv")
+
+	return c.Send(b.Bytes())
+}
+
+func handleJSONExportRequest(c *fiber.Ctx, db *gorm.DB) error {
+	doc, err := backup.Export(db)
+	if err != nil {
+		return err
+	}
+
+	b := new(bytes.Buffer)
+	if err := backup.Write(b, doc); err != nil {
+		return err
 	}
-	w.Flush()
 
 	now := time.Now().Format("2006-01-02")
-	c.Attachment("buku-" + now + ".csv")
+	c.Attachment("buku-" + now + ".json")
 
 	return c.Send(b.Bytes())
 }
+
+func apiImportJSON(c *fiber.Ctx, db *gorm.DB) error {
+	files, err := c.FormFile("file")
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	f, err := files.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	doc, err := backup.Read(f)
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	summary, err := backup.Restore(db, doc)
+	if err != nil {
+		return err
+	}
+	return c.JSON(summary)
+}
+
+// apiExportMarkdown exports the library as a zip of Markdown notes, to be
+// opened as an Obsidian vault.
+func apiExportMarkdown(c *fiber.Ctx, db *gorm.DB) error {
+	b := new(bytes.Buffer)
+	if err := vault.Write(b, books.GetAll(db), highlights.GetAll(db)); err != nil {
+		return err
+	}
+
+	now := time.Now().Format("2006-01-02")
+	c.Attachment("buku-" + now + ".zip")
+
+	return c.Send(b.Bytes())
+}
+
+// apiImportMarkdown applies the book notes of an uploaded vault zip, as
+// exported by apiExportMarkdown and edited in Obsidian.
+func apiImportMarkdown(c *fiber.Ctx, db *gorm.DB) error {
+	files, err := c.FormFile("file")
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	f, err := files.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	notes, err := vault.Read(f, files.Size)
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	result, err := vault.Apply(db, notes)
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
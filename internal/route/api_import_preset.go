@@ -0,0 +1,90 @@
+package route
+
+import (
+	"slices"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/presets"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func apiImportPresets(c *fiber.Ctx, db *gorm.DB) error {
+	return c.JSON(presets.GetAll(db))
+}
+
+func apiCreateImportPreset(c *fiber.Ctx, db *gorm.DB) error {
+	p, err := parseBodyAsImportPreset(c)
+	if err != nil {
+		return err
+	}
+
+	created, err := presets.Create(db, p)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+func apiUpdateImportPreset(c *fiber.Ctx, db *gorm.DB) error {
+	id := parseID(c)
+	if id == nil {
+		return errBadRequest("ID is invalid")
+	}
+
+	p, err := parseBodyAsImportPreset(c)
+	if err != nil {
+		return err
+	}
+
+	updated, err := presets.Update(db, *id, p)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(updated)
+}
+
+func apiDeleteImportPresetById(c *fiber.Ctx, db *gorm.DB) error {
+	id := parseID(c)
+	if id == nil {
+		return errBadRequest("ID is invalid")
+	}
+
+	if err := presets.Delete(db, *id); err != nil {
+		return err
+	}
+
+	return renderJSONOKMessage(c)
+}
+
+func parseBodyAsImportPreset(c *fiber.Ctx) (*models.ImportPreset, error) {
+	type presetRequest struct {
+		Name           string            `json:"name"`
+		Delimiter      string            `json:"delimiter"`
+		Columns        map[string]string `json:"columns"`
+		DateFormat     string            `json:"date_format"`
+		StatusRules    map[string]string `json:"status_rules"`
+		StatusConflict string            `json:"status_conflict"`
+	}
+
+	r := presetRequest{}
+	if err := c.BodyParser(&r); err != nil {
+		return nil, errBadRequest(err.Error())
+	}
+	for field := range r.Columns {
+		if !slices.Contains(importFields, field) {
+			return nil, errValidation("columns", "Unknown field \""+field+"\"")
+		}
+	}
+
+	return &models.ImportPreset{
+		Name:           r.Name,
+		Delimiter:      r.Delimiter,
+		Columns:        r.Columns,
+		DateFormat:     r.DateFormat,
+		StatusRules:    r.StatusRules,
+		StatusConflict: r.StatusConflict,
+	}, nil
+}
@@ -0,0 +1,18 @@
+package route
+
+import (
+	_ "embed"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The OpenAPI document describing every route registered in Load. The route
+// tests fail when the two drift apart.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func apiOpenAPI(c *fiber.Ctx) error {
+	c.Type("json", "utf-8")
+	return c.Send(openAPISpec)
+}
@@ -22,13 +22,13 @@ func apiRenameSeries(c *fiber.Ctx, db *gorm.DB) error {
 
 	r := new(renameSeriesRequest)
 	if err := c.BodyParser(&r); err != nil {
-		return renderJSONError(c, err.Error())
+		return errBadRequest(err.Error())
 	}
 
 	oldName, _ := url.QueryUnescape(c.Params("name"))
 
 	if err := repo.Rename(db, "series", oldName, r.Name); err != nil {
-		return renderJSONError(c, err.Error())
+		return err
 	}
 
 	return renderJSONOKMessage(c)
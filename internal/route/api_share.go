@@ -0,0 +1,166 @@
+package route
+
+import (
+	"bytes"
+	"html/template"
+	"time"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/shares"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type publicBook struct {
+	Title      string     `json:"title"`
+	Author     string     `json:"author"`
+	Series     string     `json:"series"`
+	ISBN       string     `json:"isbn"`
+	Comments   string     `json:"comments,omitempty"`
+	Status     string     `json:"status"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+var sharePageTemplate = template.Must(template.New("share").Funcs(template.FuncMap{
+	"date": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	},
+}).Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="UTF-8" />
+<meta name="viewport" content="width=device-width, initial-scale=1.0" />
+<meta name="robots" content="noindex" />
+<title>{{ .Title }} - buku</title>
+<style>
+body { font-family: system-ui, sans-serif; font-weight: 300; max-width: 32rem; margin: 2.5rem auto; padding: 0 1rem; color: #111827; background: #f9fafb; }
+h1 { font-size: 1.25rem; font-weight: 500; }
+ul { list-style: none; padding: 0; }
+li { background: #fff; border-radius: .5rem; padding: .75rem; margin-bottom: .5rem; box-shadow: 0 1px 2px rgba(0,0,0,.05); }
+.title { font-weight: 500; }
+.meta, .comments { font-size: .875rem; color: #4b5563; }
+@media (prefers-color-scheme: dark) {
+body { color: #f3f4f6; background: #111827; }
+li { background: #1f2937; }
+.meta, .comments { color: #9ca3af; }
+}
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+<p class="meta">{{ len .Books }} books</p>
+<ul>
+{{- range .Books }}
+<li>
+<div class="title">{{ .Title }}</div>
+<div class="meta">{{ .Author }}{{ if .Series }} · {{ .Series }}{{ end }}{{ with date .FinishedAt }} · {{ . }}{{ end }}</div>
+{{- if .Comments }}
+<div class="comments">{{ .Comments }}</div>
+{{- end }}
+</li>
+{{- end }}
+</ul>
+</body>
+</html>
+`))
+
+func apiShares(c *fiber.Ctx, db *gorm.DB) error {
+	return c.JSON(shares.GetAll(db))
+}
+
+func apiCreateShare(c *fiber.Ctx, db *gorm.DB) error {
+	type createShareRequest struct {
+		Title        string `json:"title"`
+		Kind         string `json:"kind"`
+		Value        string `json:"value"`
+		ShowComments bool   `json:"show_comments"`
+	}
+
+	r := createShareRequest{}
+	if err := c.BodyParser(&r); err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	created, err := shares.Create(db, &models.Share{
+		Title:        r.Title,
+		Kind:         r.Kind,
+		Value:        r.Value,
+		ShowComments: r.ShowComments,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+func apiDeleteShareById(c *fiber.Ctx, db *gorm.DB) error {
+	id := parseID(c)
+	if id == nil {
+		return errBadRequest("ID is invalid")
+	}
+
+	if err := shares.Delete(db, *id); err != nil {
+		return err
+	}
+
+	return renderJSONOKMessage(c)
+}
+
+// public
+
+func publicShareJSON(c *fiber.Ctx, db *gorm.DB) error {
+	return withQueryShare(db, c, func(s *models.Share) error {
+		return c.JSON(fiber.Map{
+			"title": s.DisplayTitle(),
+			"kind":  s.Kind,
+			"value": s.Value,
+			"books": publicBooks(shares.Books(db, s)),
+		})
+	})
+}
+
+func publicSharePage(c *fiber.Ctx, db *gorm.DB) error {
+	return withQueryShare(db, c, func(s *models.Share) error {
+		b := new(bytes.Buffer)
+		err := sharePageTemplate.Execute(b, fiber.Map{
+			"Title": s.DisplayTitle(),
+			"Books": publicBooks(shares.Books(db, s)),
+		})
+		if err != nil {
+			return err
+		}
+
+		c.Type("html", "utf-8")
+		return c.Send(b.Bytes())
+	})
+}
+
+func publicBooks(list []models.Book) []publicBook {
+	ret := make([]publicBook, 0, len(list))
+	for _, b := range list {
+		ret = append(ret, publicBook{
+			Title:      b.Title,
+			Author:     b.Author,
+			Series:     b.Series,
+			ISBN:       b.ISBN,
+			Comments:   b.Comments,
+			Status:     b.Status,
+			StartedAt:  b.StartedAt,
+			FinishedAt: b.FinishedAt,
+		})
+	}
+	return ret
+}
+
+func withQueryShare(db *gorm.DB, c *fiber.Ctx, fn func(*models.Share) error) error {
+	share := shares.GetByToken(db, c.Params("token"))
+	if share == nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return fn(share)
+}
@@ -0,0 +1,100 @@
+package route
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"waynezhang/buku/internal/infra/config"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// apiTester calls the API like the SPA, with the session cookies and the
+// CSRF token.
+type apiTester struct {
+	t       *testing.T
+	app     *fiber.App
+	cookies []*http.Cookie
+	token   string
+}
+
+func newAPITester(t *testing.T) (*apiTester, *gorm.DB) {
+	db, _ := database.Load(":memory:")
+	a := &apiTester{t: t, app: Load(&config.Config{AuthDisabled: true}, db)}
+
+	status, body := a.request(http.MethodGet, "/api/csrf.json", "")
+	assert.Equal(t, status, http.StatusOK)
+	a.token, _ = body["csrf_token"].(string)
+	return a, db
+}
+
+// request sends body as JSON and returns the status and the JSON object
+// of the response, if any.
+func (a *apiTester) request(method string, path string, body string) (int, map[string]any) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(CSRF_HEADER_NAME, a.token)
+	for _, c := range a.cookies {
+		req.AddCookie(c)
+	}
+
+	resp, err := a.app.Test(req, -1)
+	assert.Nil(a.t, err)
+	a.cookies = append(a.cookies, resp.Cookies()...)
+
+	data, _ := io.ReadAll(resp.Body)
+	ret := map[string]any{}
+	_ = json.Unmarshal(data, &ret)
+	return resp.StatusCode, ret
+}
+
+func TestUpdateBookStatus(t *testing.T) {
+	a, db := newAPITester(t)
+
+	now := time.Now()
+	b, _ := books.Create(db, &models.Book{Title: "Test", StartedAt: &now, FinishedAt: &now})
+	assert.Equal(t, b.Status, models.STATUS_READ)
+	path := "/api/book/" + strconv.FormatUint(uint64(b.ID), 10) + ".json"
+
+	// Clearing the dates without a status makes the book to-read, as before
+	status, body := a.request(http.MethodPost, path, `{"title":"Test","started_at":"","finished_at":""}`)
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, body["status"], models.STATUS_TO_READ)
+
+	status, body = a.request(http.MethodPost, path, `{"title":"Test","status":"read"}`)
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, body["status"], models.STATUS_READ)
+	assert.Equal(t, books.GetByID(db, b.ID).Status, models.STATUS_READ)
+
+	// Dates win over the status
+	status, body = a.request(http.MethodPost, path, `{"title":"Test","status":"read","started_at":"2024-01-02"}`)
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, body["status"], models.STATUS_READING)
+
+	status, body = a.request(http.MethodPost, path, `{"title":"Test","status":"abandoned"}`)
+	assert.Equal(t, status, http.StatusUnprocessableEntity)
+	assert.Equal(t, body["errors"].([]any)[0].(map[string]any)["field"], "status")
+}
+
+func TestChangeStatusKeepsAuthor(t *testing.T) {
+	a, db := newAPITester(t)
+
+	for _, author := range []string{"Terry Pratchett and Neil Gaiman", "Brian W. Kernighan, Dennis M. Ritchie"} {
+		b, _ := books.Create(db, &models.Book{Title: "Test", Author: author})
+
+		status, _ := a.request(http.MethodPost, "/api/book/"+strconv.FormatUint(uint64(b.ID), 10)+"/status.json", `{"status":"reading"}`)
+		assert.Equal(t, status, http.StatusOK)
+		assert.Equal(t, books.GetByID(db, b.ID).Author, author)
+		assert.Equal(t, books.GetByID(db, b.ID).Status, models.STATUS_READING)
+	}
+}
@@ -0,0 +1,156 @@
+package route
+
+import (
+	"bytes"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+	"waynezhang/buku/internal/repo/shares"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	ICS_CONTENT_TYPE = "text/calendar"
+	ICS_DATE         = "20060102"
+	ICS_TIME         = "20060102T150405Z"
+	// Lines longer than this many octets are folded.
+	ICS_LINE_LENGTH = 75
+)
+
+// apiExportCalendar exports the reading periods as an iCalendar file,
+// optionally only those of a year or of the books with a status.
+func apiExportCalendar(c *fiber.Ctx, db *gorm.DB) error {
+	year := 0
+	if str := c.Query("year"); len(str) > 0 {
+		y, err := strconv.Atoi(str)
+		if err != nil {
+			return errBadRequest("Year is invalid")
+		}
+		year = y
+	}
+	status := c.Query("status")
+	if len(status) > 0 && !slices.Contains(models.Statuses, status) {
+		return errBadRequest("Status is invalid")
+	}
+
+	var list []models.Book
+	if len(status) > 0 {
+		list = books.GetByStatus(db, books.ReadStatus(status))
+	} else {
+		list = books.GetAll(db)
+	}
+	now := time.Now()
+	if year > 0 {
+		list = slices.DeleteFunc(list, func(b models.Book) bool {
+			return !readIn(&b, year, now)
+		})
+	}
+
+	title := "buku"
+	if year > 0 {
+		title += " " + strconv.Itoa(year)
+	}
+	c.Attachment("buku-" + now.Format("2006-01-02") + ".ics")
+	return renderCalendar(c, title, list, now)
+}
+
+// publicShareCalendar serves a share as an iCalendar subscription,
+// addressed by the share token for calendar apps which can't log in.
+func publicShareCalendar(c *fiber.Ctx, db *gorm.DB) error {
+	return withQueryShare(db, c, func(s *models.Share) error {
+		return renderCalendar(c, s.DisplayTitle(), shares.Books(db, s), time.Now())
+	})
+}
+
+func renderCalendar(c *fiber.Ctx, title string, list []models.Book, now time.Time) error {
+	b := new(bytes.Buffer)
+	writeCalendar(b, title, list, now)
+
+	c.Set(fiber.HeaderContentType, ICS_CONTENT_TYPE+";charset=utf-8")
+	return c.Send(b.Bytes())
+}
+
+// writeCalendar writes each book read or being read as an all-day event
+// from the day it was started to the day it was finished, or to today for
+// books being read. Books never started have no event.
+func writeCalendar(b *bytes.Buffer, title string, list []models.Book, now time.Time) {
+	writeICSLine(b, "BEGIN:VCALENDAR")
+	writeICSLine(b, "VERSION:2.0")
+	writeICSLine(b, "PRODID:-//buku//Reading Calendar//EN")
+	writeICSLine(b, "CALSCALE:GREGORIAN")
+	writeICSLine(b, "METHOD:PUBLISH")
+	writeICSLine(b, "X-WR-CALNAME:"+escapeICS(title))
+
+	for _, book := range list {
+		if book.StartedAt == nil {
+			continue
+		}
+		end := now
+		summary := book.Title
+		if book.FinishedAt != nil {
+			end = *book.FinishedAt
+		} else {
+			summary = "Reading: " + summary
+		}
+		if len(book.Author) > 0 {
+			summary += " by " + book.Author
+		}
+		description := bookSummary(&book)
+		if len(book.Comments) > 0 {
+			description += "\n\n" + book.Comments
+		}
+
+		writeICSLine(b, "BEGIN:VEVENT")
+		writeICSLine(b, "UID:book-"+strconv.FormatUint(uint64(book.ID), 10)+"@buku")
+		writeICSLine(b, "DTSTAMP:"+book.UpdatedAt.UTC().Format(ICS_TIME))
+		writeICSLine(b, "DTSTART;VALUE=DATE:"+book.StartedAt.Format(ICS_DATE))
+		// The end of all-day events is exclusive
+		writeICSLine(b, "DTEND;VALUE=DATE:"+end.AddDate(0, 0, 1).Format(ICS_DATE))
+		writeICSLine(b, "SUMMARY:"+escapeICS(summary))
+		writeICSLine(b, "DESCRIPTION:"+escapeICS(description))
+		writeICSLine(b, "CATEGORIES:"+escapeICS(statusLabel(book.Status)))
+		writeICSLine(b, "TRANSP:TRANSPARENT")
+		writeICSLine(b, "END:VEVENT")
+	}
+
+	writeICSLine(b, "END:VCALENDAR")
+}
+
+// readIn reports whether the reading period of b overlaps the year.
+func readIn(b *models.Book, year int, now time.Time) bool {
+	if b.StartedAt == nil {
+		return false
+	}
+	end := now
+	if b.FinishedAt != nil {
+		end = *b.FinishedAt
+	}
+	return b.StartedAt.Year() <= year && end.Year() >= year
+}
+
+// writeICSLine ends the line with CRLF, folding it into continuation lines
+// starting with a space when it is too long, without splitting characters.
+func writeICSLine(b *bytes.Buffer, line string) {
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > ICS_LINE_LENGTH {
+			b.WriteString("\r\n ")
+			length = 1
+		}
+		b.WriteRune(r)
+		length += size
+	}
+	b.WriteString("\r\n")
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICS(str string) string {
+	return icsEscaper.Replace(str)
+}
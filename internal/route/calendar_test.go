@@ -0,0 +1,81 @@
+package route
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"waynezhang/buku/internal/infra/config"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+	"waynezhang/buku/internal/repo/shares"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCalendar(t *testing.T) {
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	started := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	finished := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+	list := []models.Book{
+		{ID: 1, Title: "Dune, Part 1", Author: "Frank Herbert", Status: models.STATUS_READ, StartedAt: &started, FinishedAt: &finished, UpdatedAt: now},
+		{ID: 2, Title: "Walden", Status: models.STATUS_READING, StartedAt: &finished, Comments: strings.Repeat("long; ", 20), UpdatedAt: now},
+		{ID: 3, Title: "Emma", Status: models.STATUS_TO_READ},
+	}
+
+	b := new(bytes.Buffer)
+	writeCalendar(b, "buku", list, now)
+	ics := b.String()
+
+	assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+	assert.Equal(t, strings.Count(ics, "BEGIN:VEVENT"), 2)
+	assert.Contains(t, ics, "UID:book-1@buku\r\n")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:20240110\r\nDTEND;VALUE=DATE:20240204\r\n")
+	assert.Contains(t, ics, `SUMMARY:Dune\, Part 1 by Frank Herbert`)
+	assert.Contains(t, ics, "SUMMARY:Reading: Walden\r\n")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:20240203\r\nDTEND;VALUE=DATE:20240306\r\n")
+	for _, line := range strings.Split(ics, "\r\n") {
+		assert.LessOrEqual(t, len(line), ICS_LINE_LENGTH)
+	}
+	assert.Contains(t, ics, "\r\n long")
+}
+
+func TestCalendar(t *testing.T) {
+	db, _ := database.Load(":memory:")
+	app := Load(&config.Config{AuthDisabled: true}, db)
+
+	d1 := time.Date(2023, 12, 20, 0, 0, 0, 0, time.UTC)
+	d2 := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	_, _ = books.Create(db, &models.Book{Title: "Dune", StartedAt: &d1, FinishedAt: &d2})
+	_, _ = books.Create(db, &models.Book{Title: "Walden", StartedAt: &d1, FinishedAt: &d1})
+	_, _ = books.Create(db, &models.Book{Title: "Emma", StartedAt: &d2})
+
+	get := func(path string) (int, string) {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Nil(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	status, ics := get("/api/export/ics?year=2024")
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, strings.Count(ics, "BEGIN:VEVENT"), 2)
+	assert.NotContains(t, ics, "Walden")
+
+	_, ics = get("/api/export/ics?status=reading")
+	assert.Equal(t, strings.Count(ics, "BEGIN:VEVENT"), 1)
+	assert.Contains(t, ics, "Emma")
+
+	status, _ = get("/api/export/ics?status=unknown")
+	assert.Equal(t, status, http.StatusBadRequest)
+
+	s, _ := shares.Create(db, &models.Share{Kind: models.SHARE_KIND_YEAR, Value: "2023"})
+	_, ics = get("/share/" + s.Token + ".ics")
+	assert.Equal(t, strings.Count(ics, "BEGIN:VEVENT"), 1)
+	assert.Contains(t, ics, "X-WR-CALNAME:Read in 2023")
+}
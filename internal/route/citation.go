@@ -0,0 +1,58 @@
+package route
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"time"
+	"waynezhang/buku/internal/exporter"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type citationFormat struct {
+	ext         string
+	contentType string
+	write       func(io.Writer, []models.Book, map[uint]string) error
+}
+
+var (
+	bibtexFormat = citationFormat{"bib", exporter.BIBTEX_CONTENT_TYPE, exporter.WriteBibTeX}
+	risFormat    = citationFormat{"ris", exporter.RIS_CONTENT_TYPE, exporter.WriteRIS}
+)
+
+// apiExportCitations exports the books matching the filters of the book
+// list, or the whole library, as citations.
+func apiExportCitations(c *fiber.Ctx, db *gorm.DB, format citationFormat) error {
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Format("2006-01-02")
+	c.Attachment("buku-" + now + "." + format.ext)
+	return renderCitations(c, db, books.GetByFilter(db, filter), format)
+}
+
+func apiBookCitation(c *fiber.Ctx, db *gorm.DB, format citationFormat) error {
+	return withQueryBook(db, c, func(b *models.Book) error {
+		c.Attachment("book-" + strconv.FormatUint(uint64(b.ID), 10) + "." + format.ext)
+		return renderCitations(c, db, []models.Book{*b}, format)
+	})
+}
+
+// renderCitations keys the citations against the books they may share keys
+// with, so that a book has the same key whether it is exported alone or with
+// others.
+func renderCitations(c *fiber.Ctx, db *gorm.DB, list []models.Book, format citationFormat) error {
+	b := new(bytes.Buffer)
+	if err := format.write(b, list, exporter.CitationKeys(books.GetCitationPeers(db, list))); err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, format.contentType+";charset=utf-8")
+	return c.Send(b.Bytes())
+}
@@ -0,0 +1,116 @@
+package route
+
+import (
+	"errors"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+)
+
+const (
+	ERROR_BAD_REQUEST         = "bad_request"
+	ERROR_UNAUTHORIZED        = "unauthorized"
+	ERROR_INVALID_CREDENTIALS = "invalid_credentials"
+	ERROR_FORBIDDEN           = "forbidden"
+	ERROR_INVALID_CSRF_TOKEN  = "invalid_csrf_token"
+	ERROR_NOT_FOUND           = "not_found"
+	ERROR_METHOD_NOT_ALLOWED  = "method_not_allowed"
+	ERROR_CONFLICT            = "conflict"
+	ERROR_VALIDATION_FAILED   = "validation_failed"
+	ERROR_INTERNAL            = "internal_error"
+)
+
+// apiError is the error returned by handlers when the response status and
+// code are known up front. Everything else is mapped in toAPIError.
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  []models.FieldError
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func newAPIError(status int, code string, message string) *apiError {
+	return &apiError{Status: status, Code: code, Message: message}
+}
+
+func errBadRequest(message string) error {
+	return newAPIError(fiber.StatusBadRequest, ERROR_BAD_REQUEST, message)
+}
+
+func errNotFound(message string) error {
+	return newAPIError(fiber.StatusNotFound, ERROR_NOT_FOUND, message)
+}
+
+func errValidation(field string, message string) error {
+	return models.ValidationError{{Field: field, Message: message}}
+}
+
+func toAPIError(err error) *apiError {
+	var apiErr *apiError
+	var validationErr models.ValidationError
+	var fiberErr *fiber.Error
+
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.As(err, &validationErr):
+		e := newAPIError(fiber.StatusUnprocessableEntity, ERROR_VALIDATION_FAILED, validationErr.Error())
+		e.Fields = validationErr
+		return e
+	case errors.Is(err, repo.ErrNotFound):
+		return newAPIError(fiber.StatusNotFound, ERROR_NOT_FOUND, err.Error())
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return newAPIError(fiber.StatusConflict, ERROR_CONFLICT, "Record already exists")
+	case errors.As(err, &fiberErr):
+		return newAPIError(fiberErr.Code, errorCodeForStatus(fiberErr.Code), fiberErr.Message)
+	}
+
+	log.Errorf("Unhandled error: %s", err.Error())
+	return newAPIError(fiber.StatusInternalServerError, ERROR_INTERNAL, "Internal server error")
+}
+
+func errorCodeForStatus(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return ERROR_BAD_REQUEST
+	case fiber.StatusUnauthorized:
+		return ERROR_UNAUTHORIZED
+	case fiber.StatusForbidden:
+		return ERROR_FORBIDDEN
+	case fiber.StatusNotFound:
+		return ERROR_NOT_FOUND
+	case fiber.StatusMethodNotAllowed:
+		return ERROR_METHOD_NOT_ALLOWED
+	case fiber.StatusConflict:
+		return ERROR_CONFLICT
+	case fiber.StatusUnprocessableEntity:
+		return ERROR_VALIDATION_FAILED
+	}
+	if status < fiber.StatusInternalServerError {
+		return ERROR_BAD_REQUEST
+	}
+	return ERROR_INTERNAL
+}
+
+// Central error handler, renders every error returned by a handler or
+// middleware as {"ok": false, "code": ..., "message": ..., "errors": [...]}.
+func errorHandler(c *fiber.Ctx, err error) error {
+	e := toAPIError(err)
+
+	body := fiber.Map{
+		"ok":      false,
+		"code":    e.Code,
+		"message": e.Message,
+	}
+	if len(e.Fields) > 0 {
+		body["errors"] = e.Fields
+	}
+	return c.Status(e.Status).JSON(body)
+}
@@ -0,0 +1,62 @@
+package route
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponses(t *testing.T) {
+	a, db := newAPITester(t)
+
+	status, body := a.request(http.MethodPost, "/api/book.json", `{"title":" "}`)
+	assert.Equal(t, status, http.StatusUnprocessableEntity)
+	assert.Equal(t, body["ok"], false)
+	assert.Equal(t, body["code"], ERROR_VALIDATION_FAILED)
+	assert.Equal(t, body["errors"], []any{map[string]any{"field": "title", "message": "Title is required"}})
+
+	status, body = a.request(http.MethodDelete, "/api/book/999.json", "")
+	assert.Equal(t, status, http.StatusNotFound)
+	assert.Equal(t, body["code"], ERROR_NOT_FOUND)
+	assert.Equal(t, body["message"], "ID not found")
+	assert.Nil(t, body["errors"])
+
+	status, body = a.request(http.MethodGet, "/api/book/999.json", "")
+	assert.Equal(t, status, http.StatusNotFound)
+	assert.Equal(t, body["code"], ERROR_NOT_FOUND)
+
+	preset := `{"name":"Sheet","columns":{"Title":"Titel"}}`
+	status, _ = a.request(http.MethodPost, "/api/import/preset.json", preset)
+	assert.Equal(t, status, http.StatusCreated)
+	status, body = a.request(http.MethodPost, "/api/import/preset.json", preset)
+	assert.Equal(t, status, http.StatusConflict)
+	assert.Equal(t, body["code"], ERROR_CONFLICT)
+	assert.Equal(t, body["message"], "Record already exists")
+
+	status, body = a.request(http.MethodPost, "/api/book.json", `not json`)
+	assert.Equal(t, status, http.StatusBadRequest)
+	assert.Equal(t, body["code"], ERROR_BAD_REQUEST)
+	assert.Equal(t, body["message"], "Invalid request body")
+
+	status, body = a.request(http.MethodGet, "/api/books.json?status=abandoned", "")
+	assert.Equal(t, status, http.StatusBadRequest)
+	assert.Equal(t, body["code"], ERROR_BAD_REQUEST)
+
+	status, _ = a.request(http.MethodPost, "/api/book.json", "")
+	assert.Equal(t, status, http.StatusBadRequest)
+
+	status, body = a.request(http.MethodGet, "/api/unknown.json", "")
+	assert.Equal(t, status, http.StatusNotFound)
+	assert.Equal(t, body["code"], ERROR_NOT_FOUND)
+
+	status, _ = a.request(http.MethodPost, "/api/book.json", `{"title":"Test"}`)
+	assert.Equal(t, status, http.StatusCreated)
+
+	// Unexpected errors don't leak their message
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	status, body = a.request(http.MethodPost, "/api/book.json", `{"title":"Test"}`)
+	assert.Equal(t, status, http.StatusInternalServerError)
+	assert.Equal(t, body, map[string]any{"ok": false, "code": ERROR_INTERNAL, "message": "Internal server error"})
+}
@@ -0,0 +1,71 @@
+package route
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"waynezhang/buku/internal/infra/config"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamCSVExport(t *testing.T) {
+	db, _ := database.Load(":memory:")
+	app := Load(&config.Config{AuthDisabled: true}, db)
+
+	const count = 30000
+	list := make([]models.Book, 0, count)
+	for i := 0; i < count; i++ {
+		list = append(list, models.Book{Title: fmt.Sprintf("Book %05d", i), Author: "Author " + strconv.Itoa(i%7), Status: models.STATUS_TO_READ})
+	}
+	assert.Nil(t, db.CreateInBatches(list, 1000).Error)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/export?columns=title,author&sort=title", nil), -1)
+	assert.Nil(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, resp.TransferEncoding, []string{"chunked"})
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), ".csv")
+
+	scanner := bufio.NewScanner(resp.Body)
+	assert.True(t, scanner.Scan())
+	assert.Equal(t, scanner.Text(), "Title,Author")
+	rows := 0
+	for scanner.Scan() {
+		assert.Equal(t, scanner.Text(), fmt.Sprintf("Book %05d,Author %d", rows, rows%7))
+		rows += 1
+	}
+	assert.Nil(t, scanner.Err())
+	assert.Equal(t, rows, count)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/export?columns=title,rating", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+
+}
+
+func TestStreamCSVExportError(t *testing.T) {
+	db, _ := database.Load(":memory:")
+	app := Load(&config.Config{AuthDisabled: true}, db)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	// Errors once the export started break the download, instead of ending
+	// it like a complete file
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	resp, err := http.Get("http://" + ln.Addr().String() + "/api/export")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	_, err = io.ReadAll(resp.Body)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,278 @@
+package route
+
+import (
+	"encoding/xml"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/shares"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published,omitempty"`
+	Authors   []atomAuthor `xml:"author"`
+	Summary   *atomText    `xml:"summary"`
+	Content   *atomText    `xml:"content"`
+	Links     []atomLink   `xml:"link"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel   string `xml:"rel,attr"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Scheme string `xml:"scheme,attr"`
+	Term   string `xml:"term,attr"`
+	Label  string `xml:"label,attr"`
+}
+
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	XmlnsAtom string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Category    string  `xml:"category"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// feedItem is a book of a shared list together with the event which put it
+// there, such as being finished, so that feed readers show it again when a
+// book is read again.
+type feedItem struct {
+	GUID  string
+	Book  models.Book
+	Event string
+	Date  time.Time
+}
+
+// publicShareAtom serves a share as an Atom feed. Like the share page it is
+// addressed by the share token, for feed readers which can't log in.
+func publicShareAtom(c *fiber.Ctx, db *gorm.DB) error {
+	return withQueryShare(db, c, func(s *models.Share) error {
+		page := c.BaseURL() + "/share/" + s.Token
+		items := shareFeedItems(s, shares.Books(db, s))
+
+		feed := atomFeed{
+			Xmlns:   "http://www.w3.org/2005/Atom",
+			ID:      "urn:buku:share:" + strconv.FormatUint(uint64(s.ID), 10),
+			Title:   s.DisplayTitle(),
+			Updated: atomTime(feedUpdated(s, items)),
+			Author:  atomAuthor{Name: "buku"},
+			Links: []atomLink{
+				{Rel: "self", Href: page + ".atom", Type: "application/atom+xml"},
+				{Rel: "alternate", Href: page, Type: "text/html"},
+			},
+			Entries: []atomEntry{},
+		}
+		for _, it := range items {
+			e := atomEntry{
+				ID:        it.GUID,
+				Title:     it.Book.Title,
+				Updated:   atomTime(it.Book.UpdatedAt),
+				Published: atomTime(it.Date),
+				Summary:   &atomText{Type: "text", Text: bookSummary(&it.Book)},
+				Links:     []atomLink{{Rel: "alternate", Href: page, Type: "text/html"}},
+			}
+			if len(it.Book.Author) > 0 {
+				e.Authors = []atomAuthor{{Name: it.Book.Author}}
+			}
+			if len(it.Book.Comments) > 0 {
+				e.Content = &atomText{Type: "text", Text: it.Book.Comments}
+			}
+			feed.Entries = append(feed.Entries, e)
+		}
+
+		return renderXML(c, "application/atom+xml", feed)
+	})
+}
+
+// publicShareRSS serves a share as an RSS 2.0 feed.
+func publicShareRSS(c *fiber.Ctx, db *gorm.DB) error {
+	return withQueryShare(db, c, func(s *models.Share) error {
+		page := c.BaseURL() + "/share/" + s.Token
+		items := shareFeedItems(s, shares.Books(db, s))
+
+		feed := rssFeed{
+			Version:   "2.0",
+			XmlnsAtom: "http://www.w3.org/2005/Atom",
+			Channel: rssChannel{
+				Title:         s.DisplayTitle(),
+				Link:          page,
+				Description:   s.DisplayTitle() + " on buku",
+				LastBuildDate: feedUpdated(s, items).Format(time.RFC1123Z),
+				AtomLink:      atomLink{Rel: "self", Href: page + ".rss", Type: "application/rss+xml"},
+				Items:         []rssItem{},
+			},
+		}
+		for _, it := range items {
+			title := it.Book.Title
+			if len(it.Book.Author) > 0 {
+				title += " by " + it.Book.Author
+			}
+			description := bookSummary(&it.Book)
+			if len(it.Book.Comments) > 0 {
+				description += "\n\n" + it.Book.Comments
+			}
+			feed.Channel.Items = append(feed.Channel.Items, rssItem{
+				Title:       title,
+				Link:        page,
+				GUID:        rssGUID{Value: it.GUID},
+				PubDate:     it.Date.Format(time.RFC1123Z),
+				Category:    it.Book.Status,
+				Description: description,
+			})
+		}
+
+		return renderXML(c, "application/rss+xml", feed)
+	})
+}
+
+// shareFeedItems dates the books of a share by the event the share is about,
+// the latest first. Lists not about an event, such as the books of an
+// author, are dated by the last change of each book.
+func shareFeedItems(s *models.Share, list []models.Book) []feedItem {
+	items := make([]feedItem, 0, len(list))
+	for _, b := range list {
+		event, date := shareEvent(s, &b)
+		it := feedItem{
+			GUID: "urn:buku:book:" + strconv.FormatUint(uint64(b.ID), 10),
+			Book: b,
+			Date: b.UpdatedAt,
+		}
+		if date != nil {
+			it.GUID += ":" + event + ":" + date.Format("2006-01-02")
+			it.Event = event
+			it.Date = *date
+		}
+		items = append(items, it)
+	}
+
+	slices.SortStableFunc(items, func(a, b feedItem) int {
+		return b.Date.Compare(a.Date)
+	})
+	return items
+}
+
+func shareEvent(s *models.Share, b *models.Book) (string, *time.Time) {
+	switch {
+	case s.Kind == models.SHARE_KIND_RECENT && s.Value == models.SHARE_RECENT_ADDED:
+		return models.SHARE_RECENT_ADDED, &b.CreatedAt
+	case s.Kind == models.SHARE_KIND_RECENT && s.Value == models.SHARE_RECENT_STARTED,
+		s.Kind == models.SHARE_KIND_STATUS && s.Value == models.STATUS_READING:
+		return models.SHARE_RECENT_STARTED, b.StartedAt
+	case s.Kind == models.SHARE_KIND_RECENT && s.Value == models.SHARE_RECENT_FINISHED,
+		s.Kind == models.SHARE_KIND_STATUS && s.Value == models.STATUS_READ,
+		s.Kind == models.SHARE_KIND_YEAR:
+		return models.SHARE_RECENT_FINISHED, b.FinishedAt
+	}
+	return "", nil
+}
+
+// feedUpdated is the time the feed last changed, that of its latest book,
+// or the share creation for empty feeds.
+func feedUpdated(s *models.Share, items []feedItem) time.Time {
+	updated := s.CreatedAt
+	for _, it := range items {
+		for _, t := range []time.Time{it.Date, it.Book.UpdatedAt} {
+			if t.After(updated) {
+				updated = t
+			}
+		}
+	}
+	return updated
+}
+
+// bookSummary is the line feed readers show under the title, such as
+// "Read · Dune Chronicles · 2024-01-02 - 2024-02-03".
+func bookSummary(b *models.Book) string {
+	parts := []string{statusLabel(b.Status)}
+	if len(b.Series) > 0 {
+		parts = append(parts, b.Series)
+	}
+	dates := []string{}
+	for _, d := range []*time.Time{b.StartedAt, b.FinishedAt} {
+		if d != nil {
+			dates = append(dates, d.Format("2006-01-02"))
+		}
+	}
+	if len(dates) > 0 {
+		parts = append(parts, strings.Join(dates, " - "))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func statusLabel(status string) string {
+	switch status {
+	case models.STATUS_READING:
+		return "Reading"
+	case models.STATUS_READ:
+		return "Read"
+	}
+	return "To Read"
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func renderXML(c *fiber.Ctx, contentType string, v any) error {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, contentType+";charset=utf-8")
+	return c.Send(append([]byte(xml.Header), data...))
+}
@@ -0,0 +1,68 @@
+package route
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"waynezhang/buku/internal/infra/config"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+	"waynezhang/buku/internal/repo/shares"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareFeeds(t *testing.T) {
+	db, _ := database.Load(":memory:")
+	app := Load(&config.Config{Username: "user", Password: "pass"}, db)
+
+	d1 := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	d2 := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+	b1, _ := books.Create(db, &models.Book{Title: "Dune", Author: "Frank Herbert", FinishedAt: &d1, Comments: "private"})
+	_, _ = books.Create(db, &models.Book{Title: "Walden", StartedAt: &d1, FinishedAt: &d2})
+	_, _ = books.Create(db, &models.Book{Title: "Emma"})
+	s, _ := shares.Create(db, &models.Share{Kind: models.SHARE_KIND_RECENT, Value: models.SHARE_RECENT_FINISHED})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/share/"+s.Token+".atom", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/atom+xml")
+	data, _ := io.ReadAll(resp.Body)
+	atom := atomFeed{}
+	assert.Nil(t, xml.Unmarshal(data, &atom), string(data))
+	assert.Equal(t, atom.Title, "Recently finished")
+	assert.Len(t, atom.Entries, 2)
+	assert.Equal(t, atom.Entries[0].Title, "Walden")
+	assert.Equal(t, atom.Entries[0].Published, "2024-02-03T00:00:00Z")
+	assert.Equal(t, atom.Entries[1].ID, "urn:buku:book:1:finished:2024-01-10")
+	assert.Nil(t, atom.Entries[1].Content)
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, "/share/"+s.Token+".rss", nil))
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/rss+xml")
+	data, _ = io.ReadAll(resp.Body)
+	rss := rssFeed{}
+	assert.Nil(t, xml.Unmarshal(data, &rss), string(data))
+	assert.Len(t, rss.Channel.Items, 2)
+	assert.Equal(t, rss.Channel.Items[1].Title, "Dune by Frank Herbert")
+	assert.Equal(t, rss.Channel.Items[1].PubDate, "Wed, 10 Jan 2024 00:00:00 +0000")
+
+	// Reading the book again makes a new item
+	again := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	b1.StartedAt, b1.FinishedAt = &again, &again
+	_, _ = books.Update(db, b1.ID, b1)
+	items := shareFeedItems(s, shares.Books(db, s))
+	assert.Equal(t, items[0].GUID, "urn:buku:book:1:finished:2024-03-01")
+
+	// Lists not about an event use the last change
+	author := &models.Share{Kind: models.SHARE_KIND_AUTHOR, Value: "Frank Herbert"}
+	items = shareFeedItems(author, shares.Books(db, author))
+	assert.Equal(t, items[0].GUID, "urn:buku:book:1")
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, "/share/unknown.rss", nil))
+	assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+}
@@ -0,0 +1,136 @@
+package route
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"waynezhang/buku/internal/infra/config"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/progress"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Error codes of the KOReader sync protocol. KOReader shows the message.
+const (
+	KOSYNC_ERROR_INTERNAL            = 2000
+	KOSYNC_ERROR_UNAUTHORIZED        = 2001
+	KOSYNC_ERROR_USER_EXISTS         = 2002
+	KOSYNC_ERROR_INVALID_REQUEST     = 2003
+	KOSYNC_ERROR_DOCUMENT_MISSING    = 2004
+	KOSYNC_ERROR_REGISTRATION_CLOSED = 2005
+)
+
+const (
+	KOSYNC_HEADER_USER = "x-auth-user"
+	KOSYNC_HEADER_KEY  = "x-auth-key"
+)
+
+type kosyncProgress struct {
+	Document   string  `json:"document"`
+	Progress   string  `json:"progress"`
+	Percentage float64 `json:"percentage"`
+	Device     string  `json:"device"`
+	DeviceID   string  `json:"device_id"`
+	Timestamp  int64   `json:"timestamp,omitempty"`
+}
+
+// kosyncAuth checks the credentials KOReader sends with every request: the
+// buku username, and the MD5 hex digest of the password.
+func kosyncAuth(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if cfg.AuthDisabled || kosyncAuthorized(c, cfg) {
+			return c.Next()
+		}
+		return kosyncError(c, fiber.StatusUnauthorized, KOSYNC_ERROR_UNAUTHORIZED, "Unauthorized")
+	}
+}
+
+func kosyncAuthorized(c *fiber.Ctx, cfg *config.Config) bool {
+	sum := md5.Sum([]byte(cfg.Password))
+	key := hex.EncodeToString(sum[:])
+
+	userOK := subtle.ConstantTimeCompare([]byte(c.Get(KOSYNC_HEADER_USER)), []byte(cfg.Username)) == 1
+	keyOK := subtle.ConstantTimeCompare([]byte(c.Get(KOSYNC_HEADER_KEY)), []byte(key)) == 1
+	return userOK && keyOK
+}
+
+// kosyncCreateUser answers KOReader's "Register" button. buku has a single
+// user, configured in the environment, so there is nothing to register.
+func kosyncCreateUser(c *fiber.Ctx, cfg *config.Config) error {
+	r := struct {
+		Username string `json:"username"`
+	}{}
+	_ = json.Unmarshal(c.Body(), &r)
+
+	if r.Username == cfg.Username {
+		return kosyncError(c, fiber.StatusPaymentRequired, KOSYNC_ERROR_USER_EXISTS, "Username is already registered, use Login.")
+	}
+	return kosyncError(c, fiber.StatusForbidden, KOSYNC_ERROR_REGISTRATION_CLOSED, "Registration is disabled, log in with the buku username and password.")
+}
+
+func kosyncAuthUser(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"authorized": "OK"})
+}
+
+func kosyncUpdateProgress(c *fiber.Ctx, db *gorm.DB) error {
+	r := kosyncProgress{}
+	if err := json.Unmarshal(c.Body(), &r); err != nil {
+		return kosyncError(c, fiber.StatusBadRequest, KOSYNC_ERROR_INVALID_REQUEST, "Invalid request")
+	}
+	if len(r.Document) == 0 {
+		return kosyncError(c, fiber.StatusForbidden, KOSYNC_ERROR_DOCUMENT_MISSING, "Field 'document' not provided.")
+	}
+
+	p, err := progress.Save(db, &models.DocumentProgress{
+		Document:   r.Document,
+		Progress:   r.Progress,
+		Percentage: r.Percentage,
+		Device:     r.Device,
+		DeviceID:   r.DeviceID,
+	})
+	if err != nil {
+		var errs models.ValidationError
+		if errors.As(err, &errs) {
+			return kosyncError(c, fiber.StatusBadRequest, KOSYNC_ERROR_INVALID_REQUEST, errs.Error())
+		}
+		return kosyncError(c, fiber.StatusInternalServerError, KOSYNC_ERROR_INTERNAL, "Unknown server error")
+	}
+
+	return c.JSON(fiber.Map{
+		"document":  p.Document,
+		"timestamp": p.SyncedAt.Unix(),
+	})
+}
+
+// kosyncGetProgress returns an empty object for documents never synced,
+// which KOReader takes as "no progress on the server".
+func kosyncGetProgress(c *fiber.Ctx, db *gorm.DB) error {
+	p := progress.GetByDocument(db, c.Params("document"))
+	if p == nil || p.SyncedAt.IsZero() {
+		return c.JSON(fiber.Map{})
+	}
+
+	return c.JSON(kosyncProgress{
+		Document:   p.Document,
+		Progress:   p.Progress,
+		Percentage: p.Percentage,
+		Device:     p.Device,
+		DeviceID:   p.DeviceID,
+		Timestamp:  p.SyncedAt.Unix(),
+	})
+}
+
+func kosyncHealthcheck(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"state": "OK"})
+}
+
+func kosyncError(c *fiber.Ctx, status int, code int, message string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"code":    code,
+		"message": message,
+	})
+}
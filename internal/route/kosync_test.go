@@ -0,0 +1,83 @@
+package route
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"waynezhang/buku/internal/infra/config"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+	"waynezhang/buku/internal/repo/progress"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// MD5 of "pass"
+const testKOSyncKey = "1a1dc91c907325c69271ddf0c944bc72"
+
+func kosyncRequest(t *testing.T, app *fiber.App, method string, path string, key string, body string) (int, map[string]any) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Accept", "application/vnd.koreader.v1+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(KOSYNC_HEADER_USER, "user")
+	req.Header.Set(KOSYNC_HEADER_KEY, key)
+
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	ret := map[string]any{}
+	assert.Nil(t, json.Unmarshal(data, &ret), string(data))
+	return resp.StatusCode, ret
+}
+
+func TestKOSync(t *testing.T) {
+	db, _ := database.Load(":memory:")
+	app := Load(&config.Config{Username: "user", Password: "pass"}, db)
+
+	status, _ := kosyncRequest(t, app, http.MethodGet, "/kosync/healthcheck", "", "")
+	assert.Equal(t, status, http.StatusOK)
+
+	status, body := kosyncRequest(t, app, http.MethodGet, "/kosync/users/auth", "wrong", "")
+	assert.Equal(t, status, http.StatusUnauthorized)
+	assert.Equal(t, body["code"], float64(KOSYNC_ERROR_UNAUTHORIZED))
+
+	status, body = kosyncRequest(t, app, http.MethodGet, "/kosync/users/auth", testKOSyncKey, "")
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, body["authorized"], "OK")
+
+	status, body = kosyncRequest(t, app, http.MethodPost, "/kosync/users/create", "", `{"username":"user","password":"x"}`)
+	assert.Equal(t, status, http.StatusPaymentRequired)
+	assert.Equal(t, body["code"], float64(KOSYNC_ERROR_USER_EXISTS))
+
+	status, body = kosyncRequest(t, app, http.MethodGet, "/kosync/syncs/progress/abc", testKOSyncKey, "")
+	assert.Equal(t, status, http.StatusOK)
+	assert.Len(t, body, 0)
+
+	b, _ := books.Create(db, &models.Book{Title: "Book 1"})
+	_, _ = progress.Link(db, "abc", &b.ID)
+
+	status, body = kosyncRequest(t, app, http.MethodPut, "/kosync/syncs/progress", testKOSyncKey, `{}`)
+	assert.Equal(t, status, http.StatusForbidden)
+	assert.Equal(t, body["code"], float64(KOSYNC_ERROR_DOCUMENT_MISSING))
+
+	status, body = kosyncRequest(t, app, http.MethodPut, "/kosync/syncs/progress", testKOSyncKey,
+		`{"document":"abc","progress":"/body/DocFragment[3]","percentage":0.25,"device":"Kobo","device_id":"K1"}`)
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, body["document"], "abc")
+	assert.NotZero(t, body["timestamp"])
+	assert.Equal(t, books.GetByID(db, b.ID).Status, models.STATUS_READING)
+
+	status, body = kosyncRequest(t, app, http.MethodGet, "/kosync/syncs/progress/abc", testKOSyncKey, "")
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, body["progress"], "/body/DocFragment[3]")
+	assert.Equal(t, body["percentage"], 0.25)
+	assert.Equal(t, body["device_id"], "K1")
+
+	_, _ = kosyncRequest(t, app, http.MethodPut, "/kosync/syncs/progress", testKOSyncKey, `{"document":"abc","percentage":1}`)
+	assert.Equal(t, books.GetByID(db, b.ID).Status, models.STATUS_READ)
+}
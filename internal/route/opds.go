@@ -0,0 +1,227 @@
+package route
+
+import (
+	"crypto/subtle"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+	"waynezhang/buku/internal/infra/config"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo"
+	"waynezhang/buku/internal/repo/authors"
+	"waynezhang/buku/internal/repo/books"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/basicauth"
+	"gorm.io/gorm"
+)
+
+const (
+	OPDS_TYPE_NAVIGATION  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	OPDS_TYPE_ACQUISITION = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+
+	OPDS_REL_IMAGE     = "http://opds-spec.org/image"
+	OPDS_REL_THUMBNAIL = "http://opds-spec.org/image/thumbnail"
+
+	// buku doesn't keep covers, so they are linked from Open Library by ISBN.
+	OPDS_COVER_URL = "https://covers.openlibrary.org/b/isbn/%s-%s.jpg?default=false"
+)
+
+type opdsFeed struct {
+	XMLName   xml.Name    `xml:"feed"`
+	Xmlns     string      `xml:"xmlns,attr"`
+	XmlnsDC   string      `xml:"xmlns:dc,attr"`
+	XmlnsOPDS string      `xml:"xmlns:opds,attr"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Author    atomAuthor  `xml:"author"`
+	Links     []atomLink  `xml:"link"`
+	Entries   []opdsEntry `xml:"entry"`
+}
+
+type opdsEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomAuthor   `xml:"author"`
+	Identifier string         `xml:"dc:identifier,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary"`
+	Content    *atomText      `xml:"content"`
+	Links      []atomLink     `xml:"link"`
+}
+
+// requireBasicAuth authenticates clients which can't log in to the SPA, such
+// as e-reader apps, with HTTP basic auth against the buku credentials.
+func requireBasicAuth(cfg *config.Config) fiber.Handler {
+	return basicauth.New(basicauth.Config{
+		Next: func(c *fiber.Ctx) bool {
+			return cfg.AuthDisabled
+		},
+		Realm: "buku",
+		Authorizer: func(username string, password string) bool {
+			userOK := subtle.ConstantTimeCompare([]byte(username), []byte(cfg.Username)) == 1
+			passOK := subtle.ConstantTimeCompare([]byte(password), []byte(cfg.Password)) == 1
+			return userOK && passOK
+		},
+	})
+}
+
+// opdsRoot is the start of the catalog, navigating to the acquisition feeds
+// by status and to the lists of authors, series and years.
+func opdsRoot(c *fiber.Ctx) error {
+	feed := newOPDSFeed("/opds", "buku", OPDS_TYPE_NAVIGATION)
+	feed.Entries = []opdsEntry{
+		opdsNavigation("Reading", "Books being read", "/opds/status/"+models.STATUS_READING, OPDS_TYPE_ACQUISITION),
+		opdsNavigation("To Read", "Books to read", "/opds/status/"+models.STATUS_TO_READ, OPDS_TYPE_ACQUISITION),
+		opdsNavigation("Read", "Books read", "/opds/status/"+models.STATUS_READ, OPDS_TYPE_ACQUISITION),
+		opdsNavigation("Authors", "Books by author", "/opds/authors", OPDS_TYPE_NAVIGATION),
+		opdsNavigation("Series", "Books by series", "/opds/series", OPDS_TYPE_NAVIGATION),
+		opdsNavigation("Years", "Books read by year", "/opds/years", OPDS_TYPE_NAVIGATION),
+		opdsNavigation("All Books", "Every book by title", "/opds/books", OPDS_TYPE_ACQUISITION),
+	}
+	return renderOPDS(c, feed)
+}
+
+func opdsBooks(c *fiber.Ctx, db *gorm.DB) error {
+	list := books.GetByKeyword(db, "", "title", "asc", "")
+	return renderOPDS(c, opdsBookFeed("/opds/books", "All Books", list))
+}
+
+func opdsBooksByStatus(c *fiber.Ctx, db *gorm.DB) error {
+	status := c.Params("status")
+	if !slices.Contains(models.Statuses, status) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	list := books.GetByStatus(db, books.ReadStatus(status))
+	return renderOPDS(c, opdsBookFeed("/opds/status/"+status, statusLabel(status), list))
+}
+
+func opdsAuthors(c *fiber.Ctx, db *gorm.DB) error {
+	return renderOPDS(c, opdsNameFeed("/opds/authors", "Authors", "/opds/author/", authors.GetAll(db, "", "asc")))
+}
+
+func opdsBooksByAuthor(c *fiber.Ctx, db *gorm.DB) error {
+	name, _ := url.PathUnescape(c.Params("name"))
+	list := books.GetByAuthor(db, name)
+	return renderOPDS(c, opdsBookFeed("/opds/author/"+url.PathEscape(name), name, list))
+}
+
+func opdsSeries(c *fiber.Ctx, db *gorm.DB) error {
+	return renderOPDS(c, opdsNameFeed("/opds/series", "Series", "/opds/series/", repo.GetAll(db, "series", "", "asc")))
+}
+
+func opdsBooksBySeries(c *fiber.Ctx, db *gorm.DB) error {
+	name, _ := url.PathUnescape(c.Params("name"))
+	list := books.GetBySeries(db, name, "finished_at", "asc")
+	return renderOPDS(c, opdsBookFeed("/opds/series/"+url.PathEscape(name), name, list))
+}
+
+func opdsYears(c *fiber.Ctx, db *gorm.DB) error {
+	feed := newOPDSFeed("/opds/years", "Years", OPDS_TYPE_NAVIGATION)
+	for _, r := range books.CountStatInYears(db) {
+		year := strconv.Itoa(r.Year)
+		feed.Entries = append(feed.Entries, opdsNavigation(year, opdsCount(r.Count), "/opds/year/"+year, OPDS_TYPE_ACQUISITION))
+	}
+	return renderOPDS(c, feed)
+}
+
+func opdsBooksByYear(c *fiber.Ctx, db *gorm.DB) error {
+	year := parseYear(c)
+	list := books.GetByYear(db, year)
+	return renderOPDS(c, opdsBookFeed("/opds/year/"+strconv.Itoa(year), "Read in "+strconv.Itoa(year), list))
+}
+
+func newOPDSFeed(path string, title string, kind string) *opdsFeed {
+	return &opdsFeed{
+		Xmlns:     "http://www.w3.org/2005/Atom",
+		XmlnsDC:   "http://purl.org/dc/terms/",
+		XmlnsOPDS: "http://opds-spec.org/2010/catalog",
+		ID:        "urn:buku:opds:" + path,
+		Title:     title,
+		Updated:   atomTime(time.Now()),
+		Author:    atomAuthor{Name: "buku"},
+		Links: []atomLink{
+			{Rel: "self", Href: path, Type: kind},
+			{Rel: "start", Href: "/opds", Type: OPDS_TYPE_NAVIGATION},
+		},
+		Entries: []opdsEntry{},
+	}
+}
+
+func opdsNavigation(title string, summary string, href string, kind string) opdsEntry {
+	return opdsEntry{
+		Title:   title,
+		ID:      "urn:buku:opds:" + href,
+		Updated: atomTime(time.Now()),
+		Summary: &atomText{Type: "text", Text: summary},
+		Links:   []atomLink{{Rel: "subsection", Href: href, Type: kind}},
+	}
+}
+
+// opdsNameFeed navigates to the books of each author or series of list, as
+// returned by repo.GetAll.
+func opdsNameFeed(path string, title string, prefix string, list []map[string]any) *opdsFeed {
+	feed := newOPDSFeed(path, title, OPDS_TYPE_NAVIGATION)
+	for _, r := range list {
+		name, _ := r["name"].(string)
+		count, _ := r["count"].(int64)
+		feed.Entries = append(feed.Entries, opdsNavigation(name, opdsCount(int(count)), prefix+url.PathEscape(name), OPDS_TYPE_ACQUISITION))
+	}
+	return feed
+}
+
+// opdsBookFeed lists books with their metadata. buku keeps no book files, so
+// entries link to the book page instead of an acquisition.
+func opdsBookFeed(path string, title string, list []models.Book) *opdsFeed {
+	feed := newOPDSFeed(path, title, OPDS_TYPE_ACQUISITION)
+	for _, b := range list {
+		e := opdsEntry{
+			Title:   b.Title,
+			ID:      "urn:buku:book:" + strconv.FormatUint(uint64(b.ID), 10),
+			Updated: atomTime(b.UpdatedAt),
+			Categories: []atomCategory{
+				{Scheme: "urn:buku:status", Term: b.Status, Label: statusLabel(b.Status)},
+			},
+			Summary: &atomText{Type: "text", Text: bookSummary(&b)},
+			Links: []atomLink{
+				{Rel: "alternate", Href: "/page/book/" + strconv.FormatUint(uint64(b.ID), 10), Type: "text/html", Title: "buku"},
+			},
+		}
+		for _, credit := range models.ParseCredits(b.Author) {
+			e.Authors = append(e.Authors, atomAuthor{Name: credit.Name})
+		}
+		if isbn := models.NormalizeISBN(b.ISBN); len(isbn) > 0 {
+			e.Identifier = "urn:isbn:" + isbn
+			e.Links = append(e.Links,
+				atomLink{Rel: OPDS_REL_IMAGE, Href: opdsCoverURL(isbn, "L"), Type: "image/jpeg"},
+				atomLink{Rel: OPDS_REL_THUMBNAIL, Href: opdsCoverURL(isbn, "M"), Type: "image/jpeg"},
+			)
+		}
+		if len(b.Comments) > 0 {
+			e.Content = &atomText{Type: "text", Text: b.Comments}
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+	return feed
+}
+
+func opdsCount(count int) string {
+	if count == 1 {
+		return "1 book"
+	}
+	return strconv.Itoa(count) + " books"
+}
+
+func opdsCoverURL(isbn string, size string) string {
+	return fmt.Sprintf(OPDS_COVER_URL, isbn, size)
+}
+
+func renderOPDS(c *fiber.Ctx, feed *opdsFeed) error {
+	return renderXML(c, feed.Links[0].Type, feed)
+}
@@ -0,0 +1,87 @@
+package route
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"waynezhang/buku/internal/infra/config"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func opdsRequest(t *testing.T, app *fiber.App, path string, auth bool) (*http.Response, *opdsFeed) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if auth {
+		req.SetBasicAuth("user", "pass")
+	}
+
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	data, _ := io.ReadAll(resp.Body)
+	feed := &opdsFeed{}
+	assert.Nil(t, xml.Unmarshal(data, feed), string(data))
+	return resp, feed
+}
+
+func TestOPDS(t *testing.T) {
+	db, _ := database.Load(":memory:")
+	app := Load(&config.Config{Username: "user", Password: "pass"}, db)
+
+	finished := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+	_, _ = books.Create(db, &models.Book{Title: "Dune", Author: "Frank Herbert", Series: "Dune Chronicles", ISBN: "978-0441172719", FinishedAt: &finished})
+	_, _ = books.Create(db, &models.Book{Title: "Children of Dune", Author: "Frank Herbert", Series: "Dune Chronicles"})
+	_, _ = books.Create(db, &models.Book{Title: "Walden", Author: "Henry David Thoreau"})
+
+	resp, _ := opdsRequest(t, app, "/opds", false)
+	assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+	assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "realm=buku")
+
+	resp, feed := opdsRequest(t, app, "/opds", true)
+	assert.Contains(t, resp.Header.Get("Content-Type"), OPDS_TYPE_NAVIGATION)
+	assert.Len(t, feed.Entries, 7)
+	assert.Equal(t, feed.Entries[0].Links[0].Href, "/opds/status/reading")
+
+	_, feed = opdsRequest(t, app, "/opds/authors", true)
+	assert.Len(t, feed.Entries, 2)
+	assert.Equal(t, feed.Entries[0].Title, "Frank Herbert")
+	assert.Equal(t, feed.Entries[0].Summary.Text, "2 books")
+	assert.Equal(t, feed.Entries[0].Links[0].Href, "/opds/author/Frank%20Herbert")
+
+	resp, feed = opdsRequest(t, app, "/opds/author/Frank%20Herbert", true)
+	assert.Contains(t, resp.Header.Get("Content-Type"), OPDS_TYPE_ACQUISITION)
+	assert.Len(t, feed.Entries, 2)
+
+	_, feed = opdsRequest(t, app, "/opds/series/Dune%20Chronicles", true)
+	assert.Len(t, feed.Entries, 2)
+
+	_, feed = opdsRequest(t, app, "/opds/years", true)
+	assert.Len(t, feed.Entries, 1)
+	assert.Equal(t, feed.Entries[0].Title, "2024")
+
+	_, feed = opdsRequest(t, app, "/opds/year/2024", true)
+	assert.Len(t, feed.Entries, 1)
+	e := feed.Entries[0]
+	assert.Equal(t, e.Title, "Dune")
+	assert.Equal(t, e.Authors[0].Name, "Frank Herbert")
+	assert.Equal(t, e.Categories[0].Term, models.STATUS_READ)
+	assert.Equal(t, e.Summary.Text, "Read · Dune Chronicles · 2024-02-03 - 2024-02-03")
+	assert.Len(t, e.Links, 3)
+	assert.Equal(t, e.Links[1].Rel, OPDS_REL_IMAGE)
+	assert.Contains(t, e.Links[1].Href, "9780441172719")
+
+	_, feed = opdsRequest(t, app, "/opds/status/to-read", true)
+	assert.Len(t, feed.Entries, 2)
+
+	resp, _ = opdsRequest(t, app, "/opds/status/unknown", true)
+	assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+}
@@ -0,0 +1,92 @@
+package route
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"waynezhang/buku/internal/infra/config"
+	"waynezhang/buku/internal/infra/database"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func testApp() *fiber.App {
+	db, _ := database.Load(":memory:")
+	return Load(&config.Config{AuthDisabled: true}, db)
+}
+
+var routeParamPattern = regexp.MustCompile(`:(\w+)(<[^>]*>)?`)
+
+// "/api/book/:id<int>.json" -> "/api/book/{id}.json"
+func openAPIPath(path string) string {
+	return routeParamPattern.ReplaceAllString(path, "{$1}")
+}
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	spec := struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}{}
+	assert.Nil(t, json.Unmarshal(openAPISpec, &spec))
+
+	documented := map[string]bool{}
+	for path, ops := range spec.Paths {
+		for method := range ops {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	registered := map[string]bool{}
+	for _, r := range testApp().GetRoutes(true) {
+		if r.Method == fiber.MethodHead {
+			continue
+		}
+		if !strings.HasPrefix(r.Path, "/api/") && !strings.HasPrefix(r.Path, "/share/") {
+			continue
+		}
+		registered[r.Method+" "+openAPIPath(r.Path)] = true
+	}
+
+	assert.Equal(t, documented, registered)
+}
+
+func TestOpenAPIReferences(t *testing.T) {
+	spec := map[string]any{}
+	assert.Nil(t, json.Unmarshal(openAPISpec, &spec))
+	assert.Equal(t, spec["openapi"], "3.0.3")
+
+	components := spec["components"].(map[string]any)
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+				assert.Len(t, parts, 2, ref)
+				section, _ := components[parts[0]].(map[string]any)
+				assert.Contains(t, section, parts[1], ref)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(spec)
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	resp, err := testApp().Test(httptest.NewRequest("GET", API_OPENAPI, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, resp.StatusCode, 200)
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, body, openAPISpec)
+}
@@ -1,9 +1,11 @@
 package route
 
 import (
+	"slices"
 	"strconv"
 	"time"
 	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -21,7 +23,31 @@ func parseYear(f *fiber.Ctx) int {
 	year, _ := strconv.Atoi(f.Params("year"))
 	return year
 }
-func parseBodyAsBook(c *fiber.Ctx) (*models.Book, []string) {
+
+// parseBookFilter reads the filters of the book list from the query.
+func parseBookFilter(c *fiber.Ctx) (books.Filter, error) {
+	f := books.Filter{
+		Keyword: c.Query("name"),
+		Status:  c.Query("status"),
+		Author:  c.Query("author"),
+		Series:  c.Query("series"),
+		Sort:    c.Query("sort"),
+		Order:   c.Query("order"),
+	}
+	if len(f.Status) > 0 && !slices.Contains(models.Statuses, f.Status) {
+		return f, errBadRequest("Status is invalid")
+	}
+	if str := c.Query("year"); len(str) > 0 {
+		year, err := strconv.Atoi(str)
+		if err != nil {
+			return f, errBadRequest("Year is invalid")
+		}
+		f.Year = year
+	}
+	return f, nil
+}
+
+func parseBodyAsBook(c *fiber.Ctx) (*models.Book, error) {
 	type request struct {
 		Title      string `json:"title"`
 		Author     string `json:"author"`
@@ -30,9 +56,16 @@ func parseBodyAsBook(c *fiber.Ctx) (*models.Book, []string) {
 		Comments   string `json:"comments"`
 		StartedAt  string `json:"started_at"`
 		FinishedAt string `json:"finished_at"`
+		// Status is only kept for undated books, and only when given, so
+		// that clients without it get the status of the dates.
+		Status *string `json:"status"`
+		// Credits replace the author when given.
+		Credits []models.Credit `json:"credits"`
 	}
 	r := request{}
-	_ = c.BodyParser(&r)
+	if err := c.BodyParser(&r); err != nil {
+		return nil, errBadRequest("Invalid request body")
+	}
 
 	book := models.Book{
 		Title:    r.Title,
@@ -40,12 +73,20 @@ func parseBodyAsBook(c *fiber.Ctx) (*models.Book, []string) {
 		Series:   r.Series,
 		ISBN:     r.ISBN,
 		Comments: r.Comments,
+		Credits:  r.Credits,
 	}
 
-	errors := []string{}
+	errors := models.ValidationError{}
+	if r.Status != nil && len(*r.Status) > 0 {
+		if slices.Contains(models.Statuses, *r.Status) {
+			book.Status = *r.Status
+		} else {
+			errors = append(errors, models.FieldError{Field: "status", Message: "Invalid status"})
+		}
+	}
 	if len(r.StartedAt) > 0 {
 		if date, err := time.Parse("2006-01-02", r.StartedAt); err != nil {
-			errors = append(errors, "Invalid start date")
+			errors = append(errors, models.FieldError{Field: "started_at", Message: "Invalid start date"})
 		} else {
 			book.StartedAt = &date
 		}
@@ -54,7 +95,7 @@ func parseBodyAsBook(c *fiber.Ctx) (*models.Book, []string) {
 	}
 	if len(r.FinishedAt) > 0 {
 		if date, err := time.Parse("2006-01-02", r.FinishedAt); err != nil {
-			errors = append(errors, "Invalid finish date")
+			errors = append(errors, models.FieldError{Field: "finished_at", Message: "Invalid finish date"})
 		} else {
 			book.FinishedAt = &date
 		}
@@ -62,7 +103,10 @@ func parseBodyAsBook(c *fiber.Ctx) (*models.Book, []string) {
 		book.FinishedAt = nil
 	}
 
-	errors = append(book.Validate(), errors...)
+	errors = append(book.ValidateFields(), errors...)
+	if len(errors) > 0 {
+		return nil, errors
+	}
 
-	return &book, errors
+	return &book, nil
 }
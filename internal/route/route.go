@@ -1,6 +1,7 @@
 package route
 
 import (
+	"waynezhang/buku/internal/importer"
 	"waynezhang/buku/internal/infra/config"
 
 	"github.com/gofiber/fiber/v2"
@@ -10,6 +11,7 @@ import (
 )
 
 var store *session.Store
+var importJobs *importer.Jobs
 
 // Authentication middleware
 func requireAuth(cfg *config.Config) fiber.Handler {
@@ -21,12 +23,12 @@ func requireAuth(cfg *config.Config) fiber.Handler {
 
 		sess, err := store.Get(c)
 		if err != nil {
-			return c.Status(401).JSON(fiber.Map{"ok": false, "message": "Authentication required"})
+			return newAPIError(fiber.StatusUnauthorized, ERROR_UNAUTHORIZED, "Authentication required")
 		}
 
 		authenticated := sess.Get("authenticated")
 		if authenticated != true {
-			return c.Status(401).JSON(fiber.Map{"ok": false, "message": "Authentication required"})
+			return newAPIError(fiber.StatusUnauthorized, ERROR_UNAUTHORIZED, "Authentication required")
 		}
 
 		return c.Next()
@@ -34,17 +36,34 @@ func requireAuth(cfg *config.Config) fiber.Handler {
 }
 
 func Load(cfg *config.Config, db *gorm.DB) *fiber.App {
-	f := fiber.New()
+	f := fiber.New(fiber.Config{
+		ErrorHandler: errorHandler,
+	})
 	f.Use(logger.New())
+	f.Use(securityHeaders(cfg))
 
 	// Initialize session store
-	store = session.New()
+	store = session.New(session.Config{
+		CookieHTTPOnly: true,
+		CookieSameSite: "Lax",
+		CookieSecure:   cfg.CookieSecure,
+	})
+	importJobs = importer.NewJobs()
 
 	f.Get("/", func(c *fiber.Ctx) error { return c.Redirect("/page/login") })
 
 	f.Static("/", "./static")
 	f.Get("/health", func(c *fiber.Ctx) error { return c.SendString("OK") })
 
+	// CSRF protection for every API route, including login
+	f.Use("/api", requireCSRFToken(cfg))
+	f.Get("/api/csrf.json", func(c *fiber.Ctx) error {
+		return apiCSRFToken(c)
+	})
+	f.Get("/api/openapi.json", func(c *fiber.Ctx) error {
+		return apiOpenAPI(c)
+	})
+
 	// Authentication routes (unprotected)
 	f.Post("/api/login", func(c *fiber.Ctx) error {
 		return apiLogin(c, cfg)
@@ -56,6 +75,71 @@ func Load(cfg *config.Config, db *gorm.DB) *fiber.App {
 		return apiCheckAuth(c, cfg)
 	})
 
+	// Public shares (unprotected, addressed by secret token)
+	f.Get("/share/:token.json", func(c *fiber.Ctx) error {
+		return publicShareJSON(c, db)
+	})
+	f.Get("/share/:token.atom", func(c *fiber.Ctx) error {
+		return publicShareAtom(c, db)
+	})
+	f.Get("/share/:token.rss", func(c *fiber.Ctx) error {
+		return publicShareRSS(c, db)
+	})
+	f.Get("/share/:token.ics", func(c *fiber.Ctx) error {
+		return publicShareCalendar(c, db)
+	})
+	f.Get("/share/:token", func(c *fiber.Ctx) error {
+		return publicSharePage(c, db)
+	})
+
+	// KOReader progress sync, authenticated by the headers KOReader sends
+	f.Get("/kosync/healthcheck", func(c *fiber.Ctx) error {
+		return kosyncHealthcheck(c)
+	})
+	f.Post("/kosync/users/create", func(c *fiber.Ctx) error {
+		return kosyncCreateUser(c, cfg)
+	})
+	kosync := f.Group("/kosync", kosyncAuth(cfg))
+	kosync.Get("/users/auth", func(c *fiber.Ctx) error {
+		return kosyncAuthUser(c)
+	})
+	kosync.Put("/syncs/progress", func(c *fiber.Ctx) error {
+		return kosyncUpdateProgress(c, db)
+	})
+	kosync.Get("/syncs/progress/:document", func(c *fiber.Ctx) error {
+		return kosyncGetProgress(c, db)
+	})
+
+	// OPDS catalog for e-reader apps, authenticated with HTTP basic auth
+	opds := f.Group("/opds", requireBasicAuth(cfg))
+	opds.Get("/", func(c *fiber.Ctx) error {
+		return opdsRoot(c)
+	})
+	opds.Get("/books", func(c *fiber.Ctx) error {
+		return opdsBooks(c, db)
+	})
+	opds.Get("/status/:status", func(c *fiber.Ctx) error {
+		return opdsBooksByStatus(c, db)
+	})
+	opds.Get("/authors", func(c *fiber.Ctx) error {
+		return opdsAuthors(c, db)
+	})
+	opds.Get("/author/:name", func(c *fiber.Ctx) error {
+		return opdsBooksByAuthor(c, db)
+	})
+	opds.Get("/series", func(c *fiber.Ctx) error {
+		return opdsSeries(c, db)
+	})
+	opds.Get("/series/:name", func(c *fiber.Ctx) error {
+		return opdsBooksBySeries(c, db)
+	})
+	opds.Get("/years", func(c *fiber.Ctx) error {
+		return opdsYears(c, db)
+	})
+	opds.Get("/year/:year<int>", func(c *fiber.Ctx) error {
+		return opdsBooksByYear(c, db)
+	})
+
 	// Protected API routes
 	api := f.Group("/api", requireAuth(cfg))
 
@@ -96,6 +180,20 @@ func Load(cfg *config.Config, db *gorm.DB) *fiber.App {
 	api.Post("/book/:id<int>/status.json", func(c *fiber.Ctx) error {
 		return apiBookChangeStatus(c, db)
 	})
+	api.Get("/book/:id<int>/highlights.json", func(c *fiber.Ctx) error {
+		return apiBookHighlights(c, db)
+	})
+	api.Get("/book/:id<int>/citation.bib", func(c *fiber.Ctx) error {
+		return apiBookCitation(c, db, bibtexFormat)
+	})
+	api.Get("/book/:id<int>/citation.ris", func(c *fiber.Ctx) error {
+		return apiBookCitation(c, db, risFormat)
+	})
+
+	// highlights
+	api.Delete("/highlight/:id<int>.json", func(c *fiber.Ctx) error {
+		return apiDeleteHighlightById(c, db)
+	})
 
 	// google book
 	api.Get("/google_book_search.json", func(c *fiber.Ctx) error {
@@ -118,6 +216,28 @@ func Load(cfg *config.Config, db *gorm.DB) *fiber.App {
 		return apiRenameSeries(c, db)
 	})
 
+	// shares
+	api.Get("/shares.json", func(c *fiber.Ctx) error {
+		return apiShares(c, db)
+	})
+	api.Post("/share.json", func(c *fiber.Ctx) error {
+		return apiCreateShare(c, db)
+	})
+	api.Delete("/share/:id<int>.json", func(c *fiber.Ctx) error {
+		return apiDeleteShareById(c, db)
+	})
+
+	// synced documents
+	api.Get("/documents.json", func(c *fiber.Ctx) error {
+		return apiDocuments(c, db)
+	})
+	api.Post("/document/:document.json", func(c *fiber.Ctx) error {
+		return apiLinkDocument(c, db)
+	})
+	api.Delete("/document/:document.json", func(c *fiber.Ctx) error {
+		return apiDeleteDocument(c, db)
+	})
+
 	// admin
 	api.Post("/delete_all.json", func(c *fiber.Ctx) error {
 		return apiDeleteAll(c, db)
@@ -125,16 +245,70 @@ func Load(cfg *config.Config, db *gorm.DB) *fiber.App {
 
 	// import
 	api.Post("/import/read_columns", func(c *fiber.Ctx) error {
-		return apiImportReadColumns(c)
+		return apiImportReadColumns(c, db)
+	})
+	api.Post("/import/preview", func(c *fiber.Ctx) error {
+		return apiImportPreview(c, db)
 	})
 	api.Post("/import", func(c *fiber.Ctx) error {
 		return apiImport(c, db)
 	})
+	api.Post("/import/jobs", func(c *fiber.Ctx) error {
+		return apiStartImportJob(c, db)
+	})
+	api.Get("/import/jobs/:id.json", func(c *fiber.Ctx) error {
+		return apiImportJob(c)
+	})
+	api.Delete("/import/jobs/:id.json", func(c *fiber.Ctx) error {
+		return apiCancelImportJob(c)
+	})
+	api.Get("/import/presets.json", func(c *fiber.Ctx) error {
+		return apiImportPresets(c, db)
+	})
+	api.Post("/import/preset.json", func(c *fiber.Ctx) error {
+		return apiCreateImportPreset(c, db)
+	})
+	api.Post("/import/preset/:id<int>.json", func(c *fiber.Ctx) error {
+		return apiUpdateImportPreset(c, db)
+	})
+	api.Delete("/import/preset/:id<int>.json", func(c *fiber.Ctx) error {
+		return apiDeleteImportPresetById(c, db)
+	})
+	api.Post("/import/clippings", func(c *fiber.Ctx) error {
+		return apiImportClippings(c, db)
+	})
+	api.Post("/import/kobo", func(c *fiber.Ctx) error {
+		return apiImportKobo(c, db)
+	})
+	api.Post("/import/json", func(c *fiber.Ctx) error {
+		return apiImportJSON(c, db)
+	})
+	api.Post("/import/markdown", func(c *fiber.Ctx) error {
+		return apiImportMarkdown(c, db)
+	})
 
 	// export
 	api.Get("/export", func(c *fiber.Ctx) error {
 		return handleCSVExportRequest(c, db)
 	})
+	api.Get("/export/goodreads", func(c *fiber.Ctx) error {
+		return handleGoodreadsExportRequest(c, db)
+	})
+	api.Get("/export/json", func(c *fiber.Ctx) error {
+		return handleJSONExportRequest(c, db)
+	})
+	api.Get("/export/ics", func(c *fiber.Ctx) error {
+		return apiExportCalendar(c, db)
+	})
+	api.Get("/export/bibtex", func(c *fiber.Ctx) error {
+		return apiExportCitations(c, db, bibtexFormat)
+	})
+	api.Get("/export/ris", func(c *fiber.Ctx) error {
+		return apiExportCitations(c, db, risFormat)
+	})
+	api.Get("/export/markdown", func(c *fiber.Ctx) error {
+		return apiExportMarkdown(c, db)
+	})
 
 	// SPA fallback - serve index.html for all /page routes
 	f.Get("/page/*", func(c *fiber.Ctx) error {
@@ -0,0 +1,38 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"waynezhang/buku/internal/infra/config"
+	"waynezhang/buku/internal/infra/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceWorkerCSP(t *testing.T) {
+	db, _ := database.Load(":memory:")
+	app := Load(config.Load(), db)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/sw.js", nil), -1)
+	assert.Nil(t, err)
+
+	connectSrc := ""
+	for _, directive := range strings.Split(resp.Header.Get("Content-Security-Policy"), ";") {
+		if fields := strings.Fields(directive); len(fields) > 0 && fields[0] == "connect-src" {
+			connectSrc = directive
+		}
+	}
+
+	// The worker caches these on install, under the policy of its script
+	sw, err := os.ReadFile("../../static/sw.js")
+	assert.Nil(t, err)
+	for _, u := range regexp.MustCompile(`'(https://[^']+)'`).FindAllStringSubmatch(string(sw), -1) {
+		parsed, _ := url.Parse(u[1])
+		assert.Contains(t, connectSrc, parsed.Scheme+"://"+parsed.Host)
+	}
+}
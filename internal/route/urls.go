@@ -1,12 +1,18 @@
 package route
 
 const (
+	API_CSRF_TOKEN                = "/api/csrf.json"
+	API_OPENAPI                   = "/api/openapi.json"
 	API_HOME                      = "/api/home.json"
 	API_BOOKS                     = "/api/books.json"
 	API_BOOK_BY_ID                = "/api/book/:id<int>.json"
 	API_CREATE_BOOK               = "/api/book.json"
 	API_UPDATE_BOOK               = "/api/book/:id<int>.json"
 	API_BOOK_CHANGE_STATUS        = "/api/book/:id<int>/status.json"
+	API_BOOK_HIGHLIGHTS           = "/api/book/:id<int>/highlights.json"
+	API_BOOK_CITATION_BIBTEX      = "/api/book/:id<int>/citation.bib"
+	API_BOOK_CITATION_RIS         = "/api/book/:id<int>/citation.ris"
+	API_DELETE_HIGHLIGHT          = "/api/highlight/:id<int>.json"
 	API_BOOKS_BY_STATUS           = "/api/books/:status.json"
 	API_BOOKS_BY_YEAR             = "/api/books/year/:year<int>.json"
 	API_BOOKS_BY_AUTHOR           = "/api/books/author/:name.json"
@@ -17,7 +23,49 @@ const (
 	API_SERIES                    = "/api/series.json"
 	API_RENAME_SERIES             = "/api/series/:name.json"
 	API_ADMIN_IMPORT_READ_COLUMNS = "/api/import/read_columns"
+	API_ADMIN_IMPORT_PREVIEW      = "/api/import/preview"
 	API_ADMIN_IMPORT              = "/api/import"
+	API_ADMIN_IMPORT_JOBS         = "/api/import/jobs"
+	API_ADMIN_IMPORT_JOB          = "/api/import/jobs/:id.json"
+	API_ADMIN_IMPORT_PRESETS      = "/api/import/presets.json"
+	API_ADMIN_CREATE_PRESET       = "/api/import/preset.json"
+	API_ADMIN_UPDATE_PRESET       = "/api/import/preset/:id<int>.json"
+	API_ADMIN_DELETE_PRESET       = "/api/import/preset/:id<int>.json"
+	API_ADMIN_IMPORT_CLIPPINGS    = "/api/import/clippings"
+	API_ADMIN_IMPORT_KOBO         = "/api/import/kobo"
+	API_ADMIN_IMPORT_JSON         = "/api/import/json"
+	API_ADMIN_IMPORT_MARKDOWN     = "/api/import/markdown"
 	API_ADMIN_EXPORT              = "/api/export"
+	API_ADMIN_EXPORT_GOODREADS    = "/api/export/goodreads"
+	API_ADMIN_EXPORT_JSON         = "/api/export/json"
+	API_ADMIN_EXPORT_ICS          = "/api/export/ics"
+	API_ADMIN_EXPORT_BIBTEX       = "/api/export/bibtex"
+	API_ADMIN_EXPORT_RIS          = "/api/export/ris"
+	API_ADMIN_EXPORT_MARKDOWN     = "/api/export/markdown"
+	API_SHARES                    = "/api/shares.json"
+	API_CREATE_SHARE              = "/api/share.json"
+	API_DELETE_SHARE              = "/api/share/:id<int>.json"
+	API_DOCUMENTS                 = "/api/documents.json"
+	API_LINK_DOCUMENT             = "/api/document/:document.json"
+	API_DELETE_DOCUMENT           = "/api/document/:document.json"
 	API_DELETE_ALL                = "/api/delete_all.json"
+	PUBLIC_SHARE_JSON             = "/share/:token.json"
+	PUBLIC_SHARE_ATOM             = "/share/:token.atom"
+	PUBLIC_SHARE_RSS              = "/share/:token.rss"
+	PUBLIC_SHARE_ICS              = "/share/:token.ics"
+	PUBLIC_SHARE_PAGE             = "/share/:token"
+	KOSYNC_HEALTHCHECK            = "/kosync/healthcheck"
+	KOSYNC_CREATE_USER            = "/kosync/users/create"
+	KOSYNC_AUTH_USER              = "/kosync/users/auth"
+	KOSYNC_UPDATE_PROGRESS        = "/kosync/syncs/progress"
+	KOSYNC_GET_PROGRESS           = "/kosync/syncs/progress/:document"
+	OPDS_ROOT                     = "/opds"
+	OPDS_BOOKS                    = "/opds/books"
+	OPDS_BOOKS_BY_STATUS          = "/opds/status/:status"
+	OPDS_AUTHORS                  = "/opds/authors"
+	OPDS_BOOKS_BY_AUTHOR          = "/opds/author/:name"
+	OPDS_SERIES                   = "/opds/series"
+	OPDS_BOOKS_BY_SERIES          = "/opds/series/:name"
+	OPDS_YEARS                    = "/opds/years"
+	OPDS_BOOKS_BY_YEAR            = "/opds/year/:year<int>"
 )
@@ -10,10 +10,3 @@ func renderJSONOKMessage(c *fiber.Ctx) error {
 		"message": "OK",
 	})
 }
-
-func renderJSONError(c *fiber.Ctx, message string) error {
-	return c.JSON(fiber.Map{
-		"ok":      false,
-		"message": message,
-	})
-}
\ No newline at end of file
@@ -0,0 +1,440 @@
+// Package vault exports the library as a zip of Markdown notes, an Obsidian
+// vault, and applies the edits made there back to the library.
+package vault
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+const (
+	BOOKS_DIR   = "Books"
+	AUTHORS_DIR = "Authors"
+	SERIES_DIR  = "Series"
+
+	// The highlights are generated from buku and dropped when reading notes
+	// back, so they are only edited in buku.
+	HIGHLIGHTS_HEADING = "## Highlights"
+
+	FRONT_MATTER_DELIMITER = "---"
+
+	// File names are cut to this many characters, before the extension.
+	MAX_NAME_LENGTH = 100
+
+	// Limits of the vaults read back, so that a small zip can't decompress
+	// into more than the server can hold.
+	MAX_ENTRIES    = 10000
+	MAX_NOTE_SIZE  = 1 << 20
+	MAX_VAULT_SIZE = 64 << 20
+)
+
+var (
+	errNotVault      = errors.New("Not a zip of Markdown notes")
+	errTooManyFiles  = errors.New("Too many files in the vault")
+	errNoteTooLarge  = errors.New("Note is too large")
+	errVaultTooLarge = errors.New("Vault is too large")
+)
+
+// FrontMatter is the YAML header of a book note. ID links the note to its
+// book; notes without it are new books.
+type FrontMatter struct {
+	ID       uint   `yaml:"buku_id,omitempty"`
+	Title    string `yaml:"title"`
+	Author   string `yaml:"author,omitempty"`
+	Series   string `yaml:"series,omitempty"`
+	ISBN     string `yaml:"isbn,omitempty"`
+	Status   string `yaml:"status,omitempty"`
+	Started  string `yaml:"started,omitempty"`
+	Finished string `yaml:"finished,omitempty"`
+}
+
+// Note is a book note read back from a vault.
+type Note struct {
+	Path        string
+	FrontMatter FrontMatter
+	Comments    string
+}
+
+type NoteError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+type Result struct {
+	Total     int         `json:"total"`
+	Created   int         `json:"created"`
+	Updated   int         `json:"updated"`
+	Unchanged int         `json:"unchanged"`
+	Errors    []NoteError `json:"errors"`
+}
+
+// Write writes the vault as a zip: a note per book in BOOKS_DIR, with the
+// comments and highlights as the body, and index notes linking to the books
+// of each author and series.
+func Write(w io.Writer, list []models.Book, highlights []models.Highlight) error {
+	byBook := map[uint][]models.Highlight{}
+	for _, h := range highlights {
+		byBook[h.BookID] = append(byBook[h.BookID], h)
+	}
+
+	z := zip.NewWriter(w)
+	names := map[uint]string{}
+	taken := map[string]bool{}
+	authors := map[string][]models.Book{}
+	series := map[string][]models.Book{}
+
+	for _, b := range list {
+		name := noteName(b.Title)
+		if taken[strings.ToLower(name)] {
+			name += " (" + utoa(b.ID) + ")"
+		}
+		taken[strings.ToLower(name)] = true
+		names[b.ID] = name
+
+		data, err := bookNote(&b, byBook[b.ID])
+		if err != nil {
+			return err
+		}
+		if err := writeFile(z, path.Join(BOOKS_DIR, name+".md"), data, b.UpdatedAt); err != nil {
+			return err
+		}
+
+		for _, c := range models.ParseCredits(b.Author) {
+			authors[c.Name] = append(authors[c.Name], b)
+		}
+		if len(b.Series) > 0 {
+			series[b.Series] = append(series[b.Series], b)
+		}
+	}
+
+	for _, index := range []struct {
+		dir   string
+		books map[string][]models.Book
+	}{{AUTHORS_DIR, authors}, {SERIES_DIR, series}} {
+		for _, name := range slices.Sorted(maps.Keys(index.books)) {
+			data := indexNote(name, index.books[name], names)
+			if err := writeFile(z, path.Join(index.dir, noteName(name)+".md"), data, time.Now()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return z.Close()
+}
+
+// Read reads the book notes of a vault zip, those with a title in their
+// front matter. Other files, such as the index notes, are skipped. Vaults
+// over MAX_ENTRIES files, MAX_NOTE_SIZE a note or MAX_VAULT_SIZE in all are
+// rejected.
+func Read(r io.ReaderAt, size int64) ([]Note, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errNotVault
+	}
+	if len(z.File) > MAX_ENTRIES {
+		return nil, errTooManyFiles
+	}
+
+	notes := []Note{}
+	total := 0
+	for _, f := range z.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(path.Ext(f.Name), ".md") {
+			continue
+		}
+		if f.UncompressedSize64 > MAX_NOTE_SIZE {
+			return nil, errNoteTooLarge
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		// The size in the header isn't trusted
+		data, err := io.ReadAll(io.LimitReader(rc, MAX_NOTE_SIZE+1))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > MAX_NOTE_SIZE {
+			return nil, errNoteTooLarge
+		}
+		if total += len(data); total > MAX_VAULT_SIZE {
+			return nil, errVaultTooLarge
+		}
+
+		note, ok := parseNote(f.Name, data)
+		if ok {
+			notes = append(notes, *note)
+		}
+	}
+	return notes, nil
+}
+
+// Apply saves the notes: notes of existing books update them, others create
+// books. Notes failing validation are reported and the others still saved.
+func Apply(db *gorm.DB, notes []Note) (*Result, error) {
+	r := Result{Total: len(notes), Errors: []NoteError{}}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, n := range notes {
+			b, err := n.book()
+			if err != nil {
+				r.Errors = append(r.Errors, NoteError{n.Path, err.Error()})
+				continue
+			}
+
+			var existing *models.Book
+			if n.FrontMatter.ID > 0 {
+				existing = books.GetByID(tx, n.FrontMatter.ID)
+			}
+
+			if existing == nil {
+				if _, err := books.Create(tx, b); err != nil {
+					if !noteError(&r, n.Path, err) {
+						return err
+					}
+					continue
+				}
+				r.Created += 1
+				continue
+			}
+
+			b.FixStatus()
+			if sameBook(existing, b) {
+				r.Unchanged += 1
+				continue
+			}
+			if _, err := books.Update(tx, existing.ID, b); err != nil {
+				if !noteError(&r, n.Path, err) {
+					return err
+				}
+				continue
+			}
+			r.Updated += 1
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func bookNote(b *models.Book, highlights []models.Highlight) ([]byte, error) {
+	fm := FrontMatter{
+		ID:     b.ID,
+		Title:  b.Title,
+		Author: b.Author,
+		Series: b.Series,
+		ISBN:   b.ISBN,
+		Status: b.Status,
+	}
+	if b.StartedAt != nil {
+		fm.Started = b.StartedAt.Format(time.DateOnly)
+	}
+	if b.FinishedAt != nil {
+		fm.Finished = b.FinishedAt.Format(time.DateOnly)
+	}
+	header, err := yaml.Marshal(&fm)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(FRONT_MATTER_DELIMITER + "\n")
+	buf.Write(header)
+	buf.WriteString(FRONT_MATTER_DELIMITER + "\n\n")
+	buf.WriteString("# " + b.Title + "\n")
+	if c := strings.TrimSpace(b.Comments); len(c) > 0 {
+		buf.WriteString("\n" + c + "\n")
+	}
+
+	if len(highlights) > 0 {
+		buf.WriteString("\n" + HIGHLIGHTS_HEADING + "\n")
+		for _, h := range highlights {
+			buf.WriteString("\n")
+			if h.Kind == models.HIGHLIGHT_KIND_NOTE {
+				buf.WriteString("- Note: " + strings.ReplaceAll(h.Text, "\n", "\n  ") + "\n")
+			} else {
+				buf.WriteString("> " + strings.ReplaceAll(h.Text, "\n", "\n> ") + "\n")
+			}
+			if where := highlightPlace(&h); len(where) > 0 {
+				buf.WriteString("\n" + where + "\n")
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// highlightPlace is the line under a highlight, such as "Location 40-42 ·
+// page 3", in italics.
+func highlightPlace(h *models.Highlight) string {
+	parts := []string{}
+	if len(h.Location) > 0 {
+		parts = append(parts, "Location "+h.Location)
+	}
+	if len(h.Page) > 0 {
+		parts = append(parts, "page "+h.Page)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "*" + strings.Join(parts, " · ") + "*"
+}
+
+func indexNote(name string, list []models.Book, names map[uint]string) []byte {
+	slices.SortStableFunc(list, func(a, b models.Book) int {
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	})
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("# " + name + "\n\n")
+	for _, b := range list {
+		buf.WriteString("- [[" + BOOKS_DIR + "/" + names[b.ID] + "|" + b.Title + "]]")
+		if b.FinishedAt != nil {
+			buf.WriteString(" (" + b.FinishedAt.Format(time.DateOnly) + ")")
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// parseNote splits a note into its front matter and body. The title heading
+// and the highlights are dropped from the body, which leaves the comments.
+func parseNote(name string, data []byte) (*Note, bool) {
+	str := strings.ReplaceAll(strings.TrimPrefix(string(data), "\uFEFF"), "\r\n", "\n")
+	if !strings.HasPrefix(str, FRONT_MATTER_DELIMITER+"\n") {
+		return nil, false
+	}
+	header, body, ok := strings.Cut(str[len(FRONT_MATTER_DELIMITER)+1:], "\n"+FRONT_MATTER_DELIMITER)
+	if !ok {
+		return nil, false
+	}
+	body = strings.TrimPrefix(strings.TrimPrefix(body, "-"), "\n")
+
+	n := Note{Path: name}
+	if err := yaml.Unmarshal([]byte(header), &n.FrontMatter); err != nil || len(strings.TrimSpace(n.FrontMatter.Title)) == 0 {
+		return nil, false
+	}
+
+	lines := strings.Split(body, "\n")
+	for len(lines) > 0 && len(strings.TrimSpace(lines[0])) == 0 {
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "# ") {
+		lines = lines[1:]
+	}
+	if i := slices.Index(lines, HIGHLIGHTS_HEADING); i >= 0 {
+		lines = lines[:i]
+	}
+	n.Comments = strings.TrimSpace(strings.Join(lines, "\n"))
+	return &n, true
+}
+
+func (n *Note) book() (*models.Book, error) {
+	fm := &n.FrontMatter
+	b := &models.Book{
+		Title:    strings.TrimSpace(fm.Title),
+		Author:   strings.TrimSpace(fm.Author),
+		Series:   strings.TrimSpace(fm.Series),
+		ISBN:     strings.TrimSpace(fm.ISBN),
+		Status:   strings.TrimSpace(fm.Status),
+		Comments: n.Comments,
+	}
+	if len(b.Status) > 0 && !slices.Contains(models.Statuses, b.Status) {
+		return nil, errors.New("Status is invalid")
+	}
+
+	for _, d := range []struct {
+		str string
+		to  **time.Time
+	}{{fm.Started, &b.StartedAt}, {fm.Finished, &b.FinishedAt}} {
+		if len(strings.TrimSpace(d.str)) == 0 {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, strings.TrimSpace(d.str))
+		if err != nil {
+			return nil, errors.New("Date format is invalid")
+		}
+		*d.to = &t
+	}
+	return b, nil
+}
+
+func sameBook(a *models.Book, b *models.Book) bool {
+	return a.Title == b.Title &&
+		a.Author == b.Author &&
+		a.Series == b.Series &&
+		a.ISBN == b.ISBN &&
+		a.Status == b.Status &&
+		a.Comments == b.Comments &&
+		sameDate(a.StartedAt, b.StartedAt) &&
+		sameDate(a.FinishedAt, b.FinishedAt)
+}
+
+func sameDate(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Format(time.DateOnly) == b.Format(time.DateOnly)
+}
+
+// noteError records validation errors against the note. Other errors abort.
+func noteError(r *Result, path string, err error) bool {
+	var errs models.ValidationError
+	if !errors.As(err, &errs) {
+		return false
+	}
+	r.Errors = append(r.Errors, NoteError{path, errs.Error()})
+	return true
+}
+
+// noteName makes a file name of str, without the characters Obsidian and
+// file systems reject in names.
+func noteName(str string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|#^[]`, r) || r < ' ' {
+			return -1
+		}
+		return r
+	}, str)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if utf8.RuneCountInString(name) > MAX_NAME_LENGTH {
+		name = strings.TrimSpace(string([]rune(name)[:MAX_NAME_LENGTH]))
+	}
+	if len(name) == 0 {
+		return "Untitled"
+	}
+	return name
+}
+
+func writeFile(z *zip.Writer, name string, data []byte, modified time.Time) error {
+	f, err := z.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func utoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/models"
+	"waynezhang/buku/internal/repo/books"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readFiles(t *testing.T, data []byte) map[string]string {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+
+	files := map[string]string{}
+	for _, f := range z.File {
+		rc, _ := f.Open()
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestWriteAndRead(t *testing.T) {
+	started := time.Date(2023, 3, 30, 0, 0, 0, 0, time.UTC)
+	finished := time.Date(2023, 4, 20, 0, 0, 0, 0, time.UTC)
+	list := []models.Book{
+		{ID: 1, Title: "1984", Author: "George Orwell", ISBN: "9780452284234", Status: models.STATUS_READ, StartedAt: &started, FinishedAt: &finished, Comments: "Bleak."},
+		{ID: 2, Title: "Dune: Part 1", Author: "Frank Herbert", Series: "Dune", Status: models.STATUS_TO_READ},
+		{ID: 3, Title: "1984", Author: "George Orwell", Status: models.STATUS_TO_READ},
+	}
+	hs := []models.Highlight{
+		{BookID: 1, Kind: models.HIGHLIGHT_KIND_HIGHLIGHT, Text: "Big Brother is watching you.", Location: "40-42"},
+		{BookID: 1, Kind: models.HIGHLIGHT_KIND_NOTE, Text: "Thirteen!"},
+	}
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, Write(buf, list, hs))
+
+	files := readFiles(t, buf.Bytes())
+	assert.Len(t, files, 6)
+	note := files["Books/1984.md"]
+	assert.True(t, strings.HasPrefix(note, "---\nbuku_id: 1\ntitle: \"1984\"\nauthor: George Orwell\n"), note)
+	assert.Contains(t, note, "finished: \"2023-04-20\"\n---\n\n# 1984\n\nBleak.\n\n## Highlights\n")
+	assert.Contains(t, note, "> Big Brother is watching you.\n\n*Location 40-42*\n")
+	assert.Contains(t, note, "- Note: Thirteen!\n")
+	assert.Contains(t, files, "Books/1984 (3).md")
+	assert.Contains(t, files, "Books/Dune Part 1.md")
+	assert.Equal(t, files["Authors/George Orwell.md"], "# George Orwell\n\n- [[Books/1984|1984]] (2023-04-20)\n- [[Books/1984 (3)|1984]]\n")
+	assert.Equal(t, files["Series/Dune.md"], "# Dune\n\n- [[Books/Dune Part 1|Dune: Part 1]]\n")
+
+	notes, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+	assert.Len(t, notes, 3)
+	assert.Equal(t, notes[0].FrontMatter.ID, uint(1))
+	assert.Equal(t, notes[0].FrontMatter.Title, "1984")
+	assert.Equal(t, notes[0].FrontMatter.Started, "2023-03-30")
+	assert.Equal(t, notes[0].Comments, "Bleak.")
+
+	_, err = Read(strings.NewReader("not a zip"), 9)
+	assert.NotNil(t, err)
+}
+
+func TestReadLimits(t *testing.T) {
+	read := func(write func(z *zip.Writer)) error {
+		buf := new(bytes.Buffer)
+		z := zip.NewWriter(buf)
+		write(z)
+		assert.Nil(t, z.Close())
+		_, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		return err
+	}
+
+	err := read(func(z *zip.Writer) {
+		w, _ := z.Create("Books/Big.md")
+		_, _ = w.Write(bytes.Repeat([]byte("a"), MAX_NOTE_SIZE+1))
+	})
+	assert.EqualError(t, err, "Note is too large")
+
+	err = read(func(z *zip.Writer) {
+		for i := 0; i <= MAX_ENTRIES; i++ {
+			_, _ = z.Create("Books/" + strconv.Itoa(i) + ".md")
+		}
+	})
+	assert.EqualError(t, err, "Too many files in the vault")
+}
+
+func TestApply(t *testing.T) {
+	db, _ := database.Load(":memory:")
+	b1, _ := books.Create(db, &models.Book{Title: "1984", Author: "Orwell", Comments: "Bleak."})
+	b2, _ := books.Create(db, &models.Book{Title: "Walden", Author: "Thoreau"})
+
+	notes := []Note{
+		{Path: "Books/1984.md", FrontMatter: FrontMatter{ID: b1.ID, Title: "1984", Author: "Orwell", Status: models.STATUS_TO_READ}, Comments: "Bleak."},
+		{Path: "Books/Walden.md", FrontMatter: FrontMatter{ID: b2.ID, Title: "Walden", Author: "Henry David Thoreau", Started: "2023-05-01"}, Comments: "Edited in Obsidian."},
+		{Path: "Books/Dune.md", FrontMatter: FrontMatter{Title: "Dune", Finished: "2022-01-02"}},
+		{Path: "Books/Bad.md", FrontMatter: FrontMatter{Title: "Bad", Status: "lost"}},
+		{Path: "Books/Late.md", FrontMatter: FrontMatter{Title: "Late", Started: "2023-05-01", Finished: "2023-04-01"}},
+	}
+	r, err := Apply(db, notes)
+	assert.Nil(t, err)
+	assert.Equal(t, r.Total, 5)
+	assert.Equal(t, r.Unchanged, 1)
+	assert.Equal(t, r.Updated, 1)
+	assert.Equal(t, r.Created, 1)
+	assert.Len(t, r.Errors, 2)
+	assert.Equal(t, r.Errors[0], NoteError{"Books/Bad.md", "Status is invalid"})
+	assert.Equal(t, r.Errors[1].Path, "Books/Late.md")
+
+	walden := books.GetByID(db, b2.ID)
+	assert.Equal(t, walden.Author, "Henry David Thoreau")
+	assert.Equal(t, walden.Comments, "Edited in Obsidian.")
+	assert.Equal(t, walden.Status, models.STATUS_READING)
+
+	dune := books.GetByTitleAndAuthor(db, "Dune", "")
+	assert.Len(t, dune, 1)
+	assert.Equal(t, dune[0].Status, models.STATUS_READ)
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+func (c *Client) Login(ctx context.Context, username string, password string) error {
+	in := map[string]string{"username": username, "password": password}
+	return c.doJSON(ctx, http.MethodPost, "/api/login", nil, in, nil)
+}
+
+func (c *Client) Logout(ctx context.Context) error {
+	return c.doJSON(ctx, http.MethodPost, "/api/logout", nil, nil, nil)
+}
+
+func (c *Client) CheckAuth(ctx context.Context) (*AuthStatus, error) {
+	r := AuthStatus{}
+	if err := c.getJSON(ctx, "/api/auth/check", nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (c *Client) Home(ctx context.Context) (*Home, error) {
+	r := Home{}
+	if err := c.getJSON(ctx, "/api/home.json", nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) Books(ctx context.Context, q BookQuery) ([]Book, error) {
+	return c.books(ctx, "/api/books.json", q.values())
+}
+
+func (c *Client) BooksByStatus(ctx context.Context, status string) ([]Book, error) {
+	return c.books(ctx, "/api/books/"+pathEscape(status)+".json", nil)
+}
+
+func (c *Client) BooksByYear(ctx context.Context, year int) ([]Book, error) {
+	return c.books(ctx, "/api/books/year/"+strconv.Itoa(year)+".json", nil)
+}
+
+func (c *Client) BooksByAuthor(ctx context.Context, name string) ([]Book, error) {
+	return c.books(ctx, "/api/books/author/"+pathEscape(name)+".json", nil)
+}
+
+func (c *Client) BooksBySeries(ctx context.Context, name string) ([]Book, error) {
+	return c.books(ctx, "/api/books/series/"+pathEscape(name)+".json", nil)
+}
+
+func (c *Client) Book(ctx context.Context, id uint) (*Book, error) {
+	r := Book{}
+	if err := c.getJSON(ctx, bookPath(id), nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// BookBibTeX returns the citation of the book as BibTeX, with the same key
+// as in ExportBibTeX.
+func (c *Client) BookBibTeX(ctx context.Context, id uint) ([]byte, error) {
+	return c.citation(ctx, id, "bib")
+}
+
+// BookRIS returns the citation of the book as RIS.
+func (c *Client) BookRIS(ctx context.Context, id uint) ([]byte, error) {
+	return c.citation(ctx, id, "ris")
+}
+
+func (c *Client) citation(ctx context.Context, id uint, ext string) ([]byte, error) {
+	b := []byte{}
+	path := "/api/book/" + strconv.FormatUint(uint64(id), 10) + "/citation." + ext
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, "", &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (c *Client) CreateBook(ctx context.Context, in BookInput) (*Book, error) {
+	r := Book{}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/book.json", nil, in, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) UpdateBook(ctx context.Context, id uint, in BookInput) (*Book, error) {
+	r := Book{}
+	if err := c.doJSON(ctx, http.MethodPost, bookPath(id), nil, in, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) DeleteBook(ctx context.Context, id uint) error {
+	return c.doJSON(ctx, http.MethodDelete, bookPath(id), nil, nil, &okResponse{})
+}
+
+func (c *Client) ChangeBookStatus(ctx context.Context, id uint, status string) error {
+	in := map[string]string{"status": status}
+	path := "/api/book/" + strconv.FormatUint(uint64(id), 10) + "/status.json"
+	return c.doJSON(ctx, http.MethodPost, path, nil, in, &okResponse{})
+}
+
+func (c *Client) SearchGoogleBooks(ctx context.Context, query string, maxResults int) ([]GoogleBook, error) {
+	q := url.Values{"query": {query}}
+	if maxResults > 0 {
+		q.Set("max_results", strconv.Itoa(maxResults))
+	}
+	r := []GoogleBook{}
+	if err := c.getJSON(ctx, "/api/google_book_search.json", q, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (c *Client) DeleteAll(ctx context.Context) error {
+	return c.doJSON(ctx, http.MethodPost, "/api/delete_all.json", nil, nil, &okResponse{})
+}
+
+func (c *Client) books(ctx context.Context, path string, query url.Values) ([]Book, error) {
+	r := []Book{}
+	if err := c.getJSON(ctx, path, query, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (q *BookQuery) values() url.Values {
+	query := url.Values{}
+	setIfNotEmpty(query, "name", q.Name)
+	setIfNotEmpty(query, "sort", q.Sort)
+	setIfNotEmpty(query, "order", q.Order)
+	setIfNotEmpty(query, "status", q.Status)
+	if q.Year != 0 {
+		query.Set("year", strconv.Itoa(q.Year))
+	}
+	setIfNotEmpty(query, "author", q.Author)
+	setIfNotEmpty(query, "series", q.Series)
+	return query
+}
+
+func bookPath(id uint) string {
+	return "/api/book/" + strconv.FormatUint(uint64(id), 10) + ".json"
+}
+
+func setIfNotEmpty(query url.Values, key string, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
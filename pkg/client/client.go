@@ -0,0 +1,203 @@
+// Package client is a Go client for the buku JSON API. The API is described
+// by the OpenAPI document served at /api/openapi.json, which the tests check
+// the requests and responses of the client against.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+)
+
+const (
+	csrfCookieName   = "csrf_"
+	csrfHeaderName   = "X-Csrf-Token"
+	csrfInvalidError = "invalid_csrf_token"
+)
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+}
+
+// New creates a client for the server at baseURL, e.g. "http://localhost:9000".
+// The session and CSRF cookies are kept in an in-memory cookie jar.
+func New(baseURL string) (*Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithHTTPClient(baseURL, &http.Client{Jar: jar})
+}
+
+// NewWithHTTPClient creates a client using hc, which must have a cookie jar.
+func NewWithHTTPClient(baseURL string, hc *http.Client) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if hc.Jar == nil {
+		return nil, fmt.Errorf("http client has no cookie jar")
+	}
+	return &Client{baseURL: u, httpClient: hc}, nil
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is returned for every non-2xx response.
+type Error struct {
+	StatusCode int          `json:"-"`
+	Code       string       `json:"code"`
+	Message    string       `json:"message"`
+	Fields     []FieldError `json:"errors"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("buku: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+type okResponse struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// url joins the base URL and an already escaped path.
+func (c *Client) url(path string, query url.Values) string {
+	u := c.baseURL.String() + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, out any) error {
+	return c.doJSON(ctx, http.MethodGet, path, query, nil, out)
+}
+
+func (c *Client) doJSON(ctx context.Context, method string, path string, query url.Values, in any, out any) error {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+		contentType = "application/json"
+	}
+	return c.do(ctx, method, path, query, body, contentType, out)
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body io.Reader, contentType string, out any) error {
+	resp, err := c.send(ctx, method, path, query, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch v := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*v, err = io.ReadAll(resp.Body)
+		return err
+	default:
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+}
+
+// send performs the request and turns error responses into *Error. Mutating
+// requests carry the CSRF token, fetching one first if none is cached. A
+// token rejected by the server, e.g. after it restarted or the session
+// expired, is dropped and the request retried once with a fresh one.
+func (c *Client) send(ctx context.Context, method string, path string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	var data []byte
+	if body != nil {
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
+
+	resp, err := c.sendOnce(ctx, method, path, query, data, contentType)
+	apiErr := &Error{}
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden && apiErr.Code == csrfInvalidError {
+		c.clearCSRFToken()
+		return c.sendOnce(ctx, method, path, query, data, contentType)
+	}
+	return resp, err
+}
+
+func (c *Client) sendOnce(ctx context.Context, method string, path string, query url.Values, data []byte, contentType string) (*http.Response, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path, query), body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		token, err := c.csrfToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(csrfHeaderName, token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return nil, apiErr
+	}
+	return resp, nil
+}
+
+func (c *Client) csrfToken(ctx context.Context) (string, error) {
+	for _, cookie := range c.httpClient.Jar.Cookies(c.baseURL) {
+		if cookie.Name == csrfCookieName && cookie.Value != "" {
+			return cookie.Value, nil
+		}
+	}
+
+	r := struct {
+		CSRFToken string `json:"csrf_token"`
+	}{}
+	if err := c.getJSON(ctx, "/api/csrf.json", nil, &r); err != nil {
+		return "", err
+	}
+	return r.CSRFToken, nil
+}
+
+// clearCSRFToken expires the cached token, so that the next request fetches
+// a new one.
+func (c *Client) clearCSRFToken() {
+	c.httpClient.Jar.SetCookies(c.baseURL, []*http.Cookie{{Name: csrfCookieName, Path: "/", MaxAge: -1}})
+}
+
+// pathEscape escapes a name used as a path segment. The server decodes these
+// segments with query unescaping, so "+" has to be escaped as well.
+func pathEscape(s string) string {
+	return url.QueryEscape(s)
+}
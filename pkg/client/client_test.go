@@ -0,0 +1,610 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"waynezhang/buku/internal/infra/config"
+	"waynezhang/buku/internal/infra/database"
+	"waynezhang/buku/internal/route"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func testClient(t *testing.T) *Client {
+	db, _ := database.Load(":memory:")
+	f := route.Load(&config.Config{Username: "user", Password: "pass"}, db)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() { _ = f.Listener(ln) }()
+	t.Cleanup(func() { _ = f.Shutdown() })
+
+	jar, _ := cookiejar.New(nil)
+	c, err := NewWithHTTPClient("http://"+ln.Addr().String(), &http.Client{Jar: jar, Transport: newSchemaChecker(t)})
+	assert.Nil(t, err)
+	return c
+}
+
+func TestAuth(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+
+	_, err := c.Books(ctx, BookQuery{})
+	apiErr := &Error{}
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, apiErr.StatusCode, 401)
+
+	err = c.Login(ctx, "user", "wrong")
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, apiErr.Code, "invalid_credentials")
+
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+	status, err := c.CheckAuth(ctx)
+	assert.Nil(t, err)
+	assert.True(t, status.Authenticated)
+
+	assert.Nil(t, c.Logout(ctx))
+	status, _ = c.CheckAuth(ctx)
+	assert.False(t, status.Authenticated)
+
+	// A fresh CSRF token is fetched after the session is gone
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+}
+
+func TestStaleCSRFToken(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	// A token the server doesn't know, e.g. after it restarted, is replaced
+	c.httpClient.Jar.SetCookies(c.baseURL, []*http.Cookie{{Name: csrfCookieName, Value: "stale", Path: "/"}})
+	b, err := c.CreateBook(ctx, BookInput{Title: "Test"})
+	assert.Nil(t, err)
+	assert.Equal(t, b.Title, "Test")
+
+	// Uploads are resent as well
+	c.httpClient.Jar.SetCookies(c.baseURL, []*http.Cookie{{Name: csrfCookieName, Value: "stale", Path: "/"}})
+	_, err = c.ImportClippings(ctx, strings.NewReader(""))
+	assert.Nil(t, err)
+}
+
+func TestBooks(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	_, err := c.CreateBook(ctx, BookInput{})
+	apiErr := &Error{}
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, apiErr.StatusCode, 422)
+	assert.Equal(t, apiErr.Fields[0].Field, "title")
+
+	b, err := c.CreateBook(ctx, BookInput{Title: "Test 1", Author: "A+B C", Series: "S/1", FinishedAt: "2024-05-01"})
+	assert.Nil(t, err)
+	assert.Equal(t, b.Status, StatusRead)
+
+	b, err = c.UpdateBook(ctx, b.ID, BookInput{Title: "Test 2", Author: "A+B C", Series: "S/1", FinishedAt: "2024-05-01"})
+	assert.Nil(t, err)
+	assert.Equal(t, b.Title, "Test 2")
+
+	got, err := c.Book(ctx, b.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, got.Title, "Test 2")
+
+	list, _ := c.Books(ctx, BookQuery{Name: "test"})
+	assert.Len(t, list, 1)
+	list, _ = c.BooksByYear(ctx, 2024)
+	assert.Len(t, list, 1)
+	list, _ = c.BooksByAuthor(ctx, "A+B C")
+	assert.Len(t, list, 1)
+	list, _ = c.BooksBySeries(ctx, "S/1")
+	assert.Len(t, list, 1)
+
+	assert.Nil(t, c.ChangeBookStatus(ctx, b.ID, StatusToRead))
+	list, _ = c.BooksByStatus(ctx, StatusToRead)
+	assert.Len(t, list, 1)
+
+	home, err := c.Home(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, home.Counts.ToRead, int64(1))
+
+	authors, _ := c.Authors(ctx, "", "")
+	assert.Equal(t, authors[0].Name, "A+B C")
+	assert.Nil(t, c.RenameAuthor(ctx, "A+B C", "Author 2"))
+	authors, _ = c.Authors(ctx, "", "")
+	assert.Equal(t, authors[0].Name, "Author 2")
+
+	assert.Nil(t, c.RenameSeries(ctx, "S/1", "Series 2"))
+	series, _ := c.Series(ctx, "", "")
+	assert.Equal(t, series[0].Name, "Series 2")
+
+	assert.Nil(t, c.DeleteBook(ctx, b.ID))
+	err = c.DeleteBook(ctx, b.ID)
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, apiErr.StatusCode, 404)
+}
+
+func TestCredits(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	b, err := c.CreateBook(ctx, BookInput{Title: "Test 1", Credits: []Credit{
+		{Name: "Author 1"},
+		{Name: "Author 2", Role: RoleTranslator},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, b.Author, "Author 1 & Author 2 (translator)")
+
+	_, err = c.CreateBook(ctx, BookInput{Title: "Test 2", Author: "Author 2; Author 3"})
+	assert.Nil(t, err)
+
+	got, _ := c.Book(ctx, b.ID)
+	assert.Equal(t, got.Credits, []Credit{{Name: "Author 1", Role: RoleAuthor}, {Name: "Author 2", Role: RoleTranslator}})
+
+	authors, _ := c.Authors(ctx, "", "")
+	assert.Len(t, authors, 3)
+	assert.Equal(t, authors[1].Name, "Author 2")
+	assert.Equal(t, authors[1].Count, 2)
+	list, _ := c.BooksByAuthor(ctx, "Author 2")
+	assert.Len(t, list, 2)
+
+	_, err = c.CreateBook(ctx, BookInput{Title: "Test 3", Credits: []Credit{{Name: "Author 1", Role: "reader"}}})
+	apiErr := &Error{}
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, apiErr.Fields[0].Field, "credits")
+
+	assert.Nil(t, c.RenameAuthor(ctx, "Author 2", "Author 1"))
+	got, _ = c.Book(ctx, b.ID)
+	assert.Equal(t, got.Author, "Author 1 & Author 1 (translator)")
+}
+
+func TestImportExport(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	csv := "Name,Writer\nBook 1,Author 1\nBook 2,Author 2\n"
+	cols, err := c.ImportReadColumns(ctx, strings.NewReader(csv), ImportOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cols.Columns, []string{"-", "Name", "Writer"})
+
+	mapping := map[string]string{"Title": "Name", "Author": "Writer"}
+	preview, err := c.ImportPreview(ctx, strings.NewReader(csv), ImportOptions{Mapping: mapping})
+	assert.Nil(t, err)
+	assert.Equal(t, preview.Valid, 2)
+	assert.Equal(t, preview.DateFormats["started_at"], "")
+	assert.Equal(t, preview.Rows[1].Line, 3)
+
+	ret, err := c.Import(ctx, strings.NewReader(csv), ImportOptions{Mapping: mapping, Accepted: []int{2}})
+	assert.Nil(t, err)
+	assert.Equal(t, ret.Succeeded, 1)
+	assert.Equal(t, ret.Skipped, 1)
+
+	ret, err = c.Import(ctx, strings.NewReader(csv), ImportOptions{Mapping: mapping})
+	assert.Nil(t, err)
+	assert.Equal(t, ret.Created, 1)
+	assert.Equal(t, ret.Conflicts[0].Action, ConflictSkip)
+
+	ret, err = c.Import(ctx, strings.NewReader(csv), ImportOptions{Mapping: mapping, Conflict: ConflictUpdate})
+	assert.Nil(t, err)
+	assert.Equal(t, ret.Updated, 2)
+
+	out, err := c.Export(ctx)
+	assert.Nil(t, err)
+	assert.Contains(t, string(out), "Book 2,Author 2")
+	assert.Contains(t, string(out), ",Status\n")
+
+	undated := "Name,State\nBook 3,Done reading\n"
+	ret, err = c.Import(ctx, strings.NewReader(undated), ImportOptions{
+		Mapping:     map[string]string{"Title": "Name", "Status": "State"},
+		StatusRules: map[string]string{"done reading": StatusRead},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ret.Created, 1)
+	read, _ := c.Books(ctx, BookQuery{Status: StatusRead})
+	assert.Len(t, read, 1)
+	assert.Nil(t, c.DeleteBook(ctx, read[0].ID))
+
+	backup, err := c.ExportJSON(ctx)
+	assert.Nil(t, err)
+
+	assert.Nil(t, c.DeleteAll(ctx))
+	list, _ := c.Books(ctx, BookQuery{})
+	assert.Len(t, list, 0)
+
+	restored, err := c.ImportJSON(ctx, bytes.NewReader(backup))
+	assert.Nil(t, err)
+	assert.Equal(t, restored.Books, 2)
+	list, _ = c.Books(ctx, BookQuery{})
+	assert.Len(t, list, 2)
+
+	job, err := c.StartImportJob(ctx, strings.NewReader(csv), ImportOptions{Mapping: mapping, Conflict: ConflictCreate})
+	assert.Nil(t, err)
+	for !job.Finished() {
+		time.Sleep(10 * time.Millisecond)
+		job, err = c.ImportJob(ctx, job.ID)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, job.Status, JobSucceeded)
+	assert.Equal(t, job.Total, 2)
+	assert.Equal(t, job.Result.Created, 2)
+
+	// Files which can't be parsed fail the job
+	job, err = c.StartImportJob(ctx, strings.NewReader(csv), ImportOptions{Format: FormatCalibre})
+	assert.Nil(t, err)
+	for !job.Finished() {
+		time.Sleep(10 * time.Millisecond)
+		job, _ = c.ImportJob(ctx, job.ID)
+	}
+	assert.Equal(t, job.Status, JobFailed)
+	assert.NotEmpty(t, job.Error)
+
+	_, err = c.ImportJob(ctx, "unknown")
+	assert.ErrorContains(t, err, "Import job not found")
+}
+
+func TestExportCalendar(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	_, _ = c.CreateBook(ctx, BookInput{Title: "Book 1", StartedAt: "2024-01-10", FinishedAt: "2024-02-03"})
+	_, _ = c.CreateBook(ctx, BookInput{Title: "Book 2", StartedAt: "2023-05-01", FinishedAt: "2023-05-02"})
+
+	ics, err := c.ExportCalendar(ctx, 2024, StatusRead)
+	assert.Nil(t, err)
+	assert.Contains(t, string(ics), "DTSTART;VALUE=DATE:20240110")
+	assert.NotContains(t, string(ics), "Book 2")
+
+	_, err = c.ExportCalendar(ctx, 0, "unknown")
+	assert.ErrorContains(t, err, "Status is invalid")
+}
+
+func TestExportBooks(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	_, _ = c.CreateBook(ctx, BookInput{Title: "Book 1", Author: "Author 1", StartedAt: "2025-01-10", FinishedAt: "2025-02-03"})
+	_, _ = c.CreateBook(ctx, BookInput{Title: "Book 2", Author: "Author 1", StartedAt: "2024-01-10", FinishedAt: "2024-02-03"})
+	_, _ = c.CreateBook(ctx, BookInput{Title: "Book 3", Author: "Author 2"})
+
+	list, err := c.Books(ctx, BookQuery{Year: 2025})
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+
+	out, err := c.ExportBooks(ctx, ExportQuery{
+		BookQuery: BookQuery{Author: "Author 1", Sort: "finished_at"},
+		Columns:   []string{"title", "finished_at"},
+		Format:    ExportTSV,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, string(out), "Title\tFinished\nBook 2\t2024-02-03T00:00:00Z\nBook 1\t2025-02-03T00:00:00Z\n")
+
+	out, err = c.ExportBooks(ctx, ExportQuery{BookQuery: BookQuery{Year: 2025}, Delimiter: ";", BOM: true})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(out), "\uFEFFTitle;Author;"))
+	assert.Contains(t, string(out), "Book 1;Author 1;")
+	assert.NotContains(t, string(out), "Book 2")
+
+	_, err = c.ExportBooks(ctx, ExportQuery{Columns: []string{"rating"}})
+	assert.ErrorContains(t, err, "Column is invalid")
+}
+
+func TestCitations(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	_, _ = c.CreateBook(ctx, BookInput{Title: "Animal Farm", Author: "George Orwell", StartedAt: "2025-01-10", FinishedAt: "2025-02-03"})
+	b2, _ := c.CreateBook(ctx, BookInput{Title: "Animal Farm", Author: "George Orwell", ISBN: "9780452284244", FinishedAt: "2025-06-01"})
+	_, _ = c.CreateBook(ctx, BookInput{Title: "1984", Author: "George Orwell"})
+
+	bib, err := c.ExportBibTeX(ctx, BookQuery{})
+	assert.Nil(t, err)
+	assert.Contains(t, string(bib), "@book{orwell2025animal,\n")
+	assert.Contains(t, string(bib), "@book{orwell2025animalb,\n")
+	assert.Contains(t, string(bib), "@book{orwell1984,\n")
+
+	bib, err = c.BookBibTeX(ctx, b2.ID)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(bib), "@book{orwell2025animalb,\n  author = {Orwell, George},\n"))
+	assert.Contains(t, string(bib), "isbn = {9780452284244}")
+
+	ris, err := c.ExportRIS(ctx, BookQuery{Status: StatusToRead})
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Count(string(ris), "TY  - BOOK"), 1)
+	assert.Contains(t, string(ris), "ID  - orwell1984\r\n")
+
+	_, err = c.BookRIS(ctx, 999)
+	assert.NotNil(t, err)
+}
+
+func TestExportGoodreads(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	_, _ = c.CreateBook(ctx, BookInput{Title: "Book 1", Author: "Author 1", StartedAt: "2024-01-10", FinishedAt: "2024-02-03"})
+
+	data, err := c.ExportGoodreads(ctx)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "Book Id,Title,Author,Author l-f,"))
+	assert.Contains(t, string(data), ",2024/02/03,")
+	assert.Contains(t, string(data), ",read,")
+}
+
+func TestMarkdownVault(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	_, _ = c.CreateBook(ctx, BookInput{Title: "Book 1", Author: "Author 1"})
+
+	data, err := c.ExportMarkdown(ctx)
+	assert.Nil(t, err)
+
+	ret, err := c.ImportMarkdown(ctx, bytes.NewReader(data))
+	assert.Nil(t, err)
+	assert.Equal(t, ret.Total, 1)
+	assert.Equal(t, ret.Unchanged, 1)
+
+	_, err = c.ImportMarkdown(ctx, strings.NewReader("not a zip"))
+	assert.NotNil(t, err)
+}
+
+func TestImportStoryGraph(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	csv := "Title,Authors,ISBN/UID,Read Status,Last Date Read,Dates Read,Star Rating,Tags\n" +
+		"Book 1,Author 1,,read,2024/03/10,2024/03/01-2024/03/10,4,fiction\n"
+	cols, err := c.ImportReadColumns(ctx, strings.NewReader(csv), ImportOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cols.Format, FormatStoryGraph)
+
+	ret, err := c.Import(ctx, strings.NewReader(csv), ImportOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, ret.Created, 1)
+
+	list, _ := c.Books(ctx, BookQuery{Status: StatusRead})
+	assert.Len(t, list, 1)
+	assert.Equal(t, list[0].StartedAt.Format("2006-01-02"), "2024-03-01")
+	assert.Equal(t, list[0].Comments, "Rating: 4\nTags: fiction")
+
+	// Forcing the mapping reads the same file as a plain CSV
+	ret, err = c.Import(ctx, strings.NewReader(csv), ImportOptions{
+		Format:   FormatCSV,
+		Mapping:  map[string]string{"Title": "Title"},
+		Conflict: ConflictCreate,
+	})
+	assert.Nil(t, err)
+	list, _ = c.Books(ctx, BookQuery{Status: StatusToRead})
+	assert.Len(t, list, 1)
+}
+
+func TestImportCalibre(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	path := filepath.Join(t.TempDir(), "metadata.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Exec(`
+CREATE TABLE books (id INTEGER PRIMARY KEY, title TEXT, isbn TEXT, series_index REAL, has_cover BOOL);
+CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE books_authors_link (id INTEGER PRIMARY KEY, book INTEGER, author INTEGER);
+CREATE TABLE series (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE books_series_link (id INTEGER PRIMARY KEY, book INTEGER, series INTEGER);
+CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE books_tags_link (id INTEGER PRIMARY KEY, book INTEGER, tag INTEGER);
+CREATE TABLE identifiers (id INTEGER PRIMARY KEY, book INTEGER, type TEXT, val TEXT);
+CREATE TABLE comments (id INTEGER PRIMARY KEY, book INTEGER, text TEXT);
+INSERT INTO books VALUES (7, 'Book 1', '', 1.0, 0);
+INSERT INTO authors VALUES (1, 'Author 1');
+INSERT INTO books_authors_link VALUES (1, 7, 1);
+INSERT INTO comments VALUES (1, 7, '<p>Great</p>');
+`).Error)
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	cols, err := c.ImportReadColumns(ctx, bytes.NewReader(data), ImportOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cols.Format, FormatCalibre)
+
+	preview, err := c.ImportPreview(ctx, bytes.NewReader(data), ImportOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, preview.Rows, 1)
+	assert.Equal(t, preview.Rows[0].Line, 7)
+
+	ret, err := c.Import(ctx, bytes.NewReader(data), ImportOptions{Format: FormatCalibre})
+	assert.Nil(t, err)
+	assert.Equal(t, ret.Created, 1)
+
+	list, _ := c.Books(ctx, BookQuery{Status: StatusToRead})
+	assert.Len(t, list, 1)
+	assert.Equal(t, list[0].Author, "Author 1")
+	assert.Equal(t, list[0].Comments, "Great")
+
+	_, err = c.Import(ctx, strings.NewReader("Title\nBook\n"), ImportOptions{Format: FormatCalibre})
+	assert.ErrorContains(t, err, "Not a Calibre library")
+}
+
+func TestImportPresets(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	_, err := c.CreateImportPreset(ctx, ImportPresetInput{Name: "Sheet", Columns: map[string]string{"Rating": "Stars"}})
+	apiErr := &Error{}
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, apiErr.StatusCode, 422)
+
+	preset, err := c.CreateImportPreset(ctx, ImportPresetInput{
+		Name:       "Sheet",
+		Delimiter:  ";",
+		Columns:    map[string]string{"Title": "Titel", "Started": "Beginn"},
+		DateFormat: "02.01.2006",
+	})
+	assert.Nil(t, err)
+
+	csv := "Titel;Beginn\nBuch 1;31.01.2024\n"
+	cols, err := c.ImportReadColumns(ctx, strings.NewReader(csv), ImportOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cols.SuggestedPreset.ID, preset.ID)
+
+	ret, err := c.Import(ctx, strings.NewReader(csv), ImportOptions{Preset: preset.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, ret.Created, 1)
+	list, _ := c.Books(ctx, BookQuery{})
+	assert.Equal(t, list[0].StartedAt.Format("2006-01-02"), "2024-01-31")
+
+	preset, err = c.UpdateImportPreset(ctx, preset.ID, ImportPresetInput{Name: "Renamed", Columns: map[string]string{"Title": "Name"}})
+	assert.Nil(t, err)
+	assert.Equal(t, preset.Name, "Renamed")
+	presets, _ := c.ImportPresets(ctx)
+	assert.Len(t, presets, 1)
+
+	assert.Nil(t, c.DeleteImportPreset(ctx, preset.ID))
+	_, err = c.Import(ctx, strings.NewReader(csv), ImportOptions{Preset: preset.ID})
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, apiErr.StatusCode, 422)
+}
+
+func TestHighlights(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	book, err := c.CreateBook(ctx, BookInput{Title: "1984", Author: "George Orwell"})
+	assert.Nil(t, err)
+
+	clippings := "1984 (Orwell, George)\n" +
+		"- Your Highlight on page 3 | Location 40-42 | Added on Monday, 3 April 2023 10:00:00\n\n" +
+		"It was a bright cold day in April\n==========\n" +
+		"Dune (Herbert, Frank)\n" +
+		"- Your Note on Location 12 | Added on Monday, 3 April 2023 10:00:00\n\n" +
+		"Spice\n==========\n"
+	ret, err := c.ImportClippings(ctx, strings.NewReader(clippings))
+	assert.Nil(t, err)
+	assert.Equal(t, *ret, ClippingsResult{Total: 2, Created: 2, BooksMatched: 1, BooksCreated: 1})
+
+	list, err := c.BookHighlights(ctx, book.ID)
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, list[0].Kind, HighlightKindHighlight)
+	assert.Equal(t, list[0].Location, "40-42")
+	assert.Equal(t, list[0].AddedAt.Format("2006-01-02"), "2023-04-03")
+
+	ret, err = c.ImportClippings(ctx, strings.NewReader(clippings))
+	assert.Nil(t, err)
+	assert.Equal(t, ret.Duplicates, 2)
+
+	assert.Nil(t, c.DeleteHighlight(ctx, list[0].ID))
+	list, _ = c.BookHighlights(ctx, book.ID)
+	assert.Len(t, list, 0)
+	assert.NotNil(t, c.DeleteHighlight(ctx, 100))
+
+	_, err = c.BookHighlights(ctx, 100)
+	assert.ErrorContains(t, err, "Book is not found")
+}
+
+func TestImportKobo(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	book, _ := c.CreateBook(ctx, BookInput{Title: "1984", Author: "George Orwell"})
+
+	f, err := os.Open("../../internal/importer/testdata/KoboReader.sqlite")
+	assert.Nil(t, err)
+	defer f.Close()
+
+	ret, err := c.ImportKobo(ctx, f)
+	assert.Nil(t, err)
+	assert.Equal(t, *ret, KoboResult{Total: 4, BooksMatched: 1, BooksCreated: 2, BooksUpdated: 1, BooksSkipped: 1, HighlightsCreated: 4})
+
+	book, _ = c.Book(ctx, book.ID)
+	assert.Equal(t, book.Status, StatusRead)
+	assert.Equal(t, book.FinishedAt.Format("2006-01-02"), "2023-04-20")
+
+	docs, err := c.Documents(ctx, book.ID)
+	assert.Nil(t, err)
+	assert.Len(t, docs, 1)
+	assert.Equal(t, docs[0].Device, "Kobo")
+	assert.Equal(t, docs[0].ReadingTime, 36000)
+
+	_, err = c.ImportKobo(ctx, strings.NewReader("Title\nBook\n"))
+	assert.ErrorContains(t, err, "Not a Kobo database")
+}
+
+func TestDocuments(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	book, _ := c.CreateBook(ctx, BookInput{Title: "Book 1"})
+
+	missing := book.ID + 1
+	_, err := c.LinkDocument(ctx, "0123abcd", &missing)
+	apiErr := &Error{}
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, apiErr.StatusCode, 404)
+
+	doc, err := c.LinkDocument(ctx, "0123abcd", &book.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, *doc.BookID, book.ID)
+	assert.True(t, doc.SyncedAt.IsZero())
+
+	list, err := c.Documents(ctx, book.ID)
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	list, _ = c.Documents(ctx, missing)
+	assert.Len(t, list, 0)
+
+	doc, err = c.LinkDocument(ctx, "0123abcd", nil)
+	assert.Nil(t, err)
+	assert.Nil(t, doc.BookID)
+
+	assert.Nil(t, c.DeleteDocument(ctx, "0123abcd"))
+	list, _ = c.Documents(ctx, 0)
+	assert.Len(t, list, 0)
+}
+
+func TestShares(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t)
+	assert.Nil(t, c.Login(ctx, "user", "pass"))
+
+	_, _ = c.CreateBook(ctx, BookInput{Title: "Test 1", Series: "Series 1", Comments: "private"})
+	s, err := c.CreateShare(ctx, ShareInput{Kind: "series", Value: "Series 1"})
+	assert.Nil(t, err)
+
+	shares, _ := c.Shares(ctx)
+	assert.Len(t, shares, 1)
+
+	public, err := c.PublicShare(ctx, s.Token)
+	assert.Nil(t, err)
+	assert.Equal(t, public.Books[0].Title, "Test 1")
+	assert.Equal(t, public.Books[0].Comments, "")
+
+	assert.Nil(t, c.DeleteShare(ctx, s.ID))
+	_, err = c.PublicShare(ctx, s.Token)
+	assert.NotNil(t, err)
+}
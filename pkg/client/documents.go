@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Documents lists the documents synced by KOReader. When bookID is not
+// zero only those linked to the book are returned.
+func (c *Client) Documents(ctx context.Context, bookID uint) ([]DocumentProgress, error) {
+	query := url.Values{}
+	if bookID != 0 {
+		query.Set("book_id", strconv.FormatUint(uint64(bookID), 10))
+	}
+
+	r := []DocumentProgress{}
+	if err := c.getJSON(ctx, "/api/documents.json", query, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// LinkDocument links the document to a book so that syncs update it, or
+// unlinks it when bookID is nil.
+func (c *Client) LinkDocument(ctx context.Context, document string, bookID *uint) (*DocumentProgress, error) {
+	in := struct {
+		BookID *uint `json:"book_id"`
+	}{bookID}
+
+	r := DocumentProgress{}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/document/"+pathEscape(document)+".json", nil, in, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) DeleteDocument(ctx context.Context, document string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/document/"+pathEscape(document)+".json", nil, nil, &okResponse{})
+}
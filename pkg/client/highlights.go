@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// BookHighlights returns the highlights and notes of a book in reading order.
+func (c *Client) BookHighlights(ctx context.Context, bookID uint) ([]Highlight, error) {
+	r := []Highlight{}
+	path := "/api/book/" + strconv.FormatUint(uint64(bookID), 10) + "/highlights.json"
+	if err := c.getJSON(ctx, path, nil, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (c *Client) DeleteHighlight(ctx context.Context, id uint) error {
+	path := "/api/highlight/" + strconv.FormatUint(uint64(id), 10) + ".json"
+	return c.doJSON(ctx, http.MethodDelete, path, nil, nil, &okResponse{})
+}
+
+// ImportClippings imports a Kindle "My Clippings.txt", matching the clippings
+// to books by title and author.
+func (c *Client) ImportClippings(ctx context.Context, clippings io.Reader) (*ClippingsResult, error) {
+	r := ClippingsResult{}
+	if err := c.upload(ctx, "/api/import/clippings", "My Clippings.txt", clippings, ImportOptions{}, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ImportKobo imports the reading state and highlights of a Kobo
+// KoboReader.sqlite, matching its books by ISBN, title and author.
+func (c *Client) ImportKobo(ctx context.Context, db io.Reader) (*KoboResult, error) {
+	r := KoboResult{}
+	if err := c.upload(ctx, "/api/import/kobo", "KoboReader.sqlite", db, ImportOptions{}, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func (c *Client) ImportReadColumns(ctx context.Context, csv io.Reader, opts ImportOptions) (*ImportColumns, error) {
+	r := ImportColumns{}
+	if err := c.upload(ctx, "/api/import/read_columns", "import.csv", csv, opts, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ImportPreview parses the CSV and reports every row without importing.
+func (c *Client) ImportPreview(ctx context.Context, csv io.Reader, opts ImportOptions) (*ImportPreview, error) {
+	r := ImportPreview{}
+	if err := c.upload(ctx, "/api/import/preview", "import.csv", csv, opts, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) Import(ctx context.Context, csv io.Reader, opts ImportOptions) (*ImportResult, error) {
+	r := ImportResult{}
+	if err := c.upload(ctx, "/api/import", "import.csv", csv, opts, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// StartImportJob imports the CSV in the background. Poll ImportJob until
+// the job is finished.
+func (c *Client) StartImportJob(ctx context.Context, csv io.Reader, opts ImportOptions) (*ImportJob, error) {
+	r := ImportJob{}
+	if err := c.upload(ctx, "/api/import/jobs", "import.csv", csv, opts, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) ImportJob(ctx context.Context, id string) (*ImportJob, error) {
+	r := ImportJob{}
+	if err := c.do(ctx, http.MethodGet, "/api/import/jobs/"+pathEscape(id)+".json", nil, nil, "", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CancelImportJob stops the job and rolls back everything it imported.
+func (c *Client) CancelImportJob(ctx context.Context, id string) (*ImportJob, error) {
+	r := ImportJob{}
+	if err := c.do(ctx, http.MethodDelete, "/api/import/jobs/"+pathEscape(id)+".json", nil, nil, "", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Export returns the whole library as CSV.
+func (c *Client) Export(ctx context.Context) ([]byte, error) {
+	return c.ExportBooks(ctx, ExportQuery{})
+}
+
+// ExportBooks returns the books matching q as CSV or TSV, with the columns
+// of q.
+func (c *Client) ExportBooks(ctx context.Context, q ExportQuery) ([]byte, error) {
+	query := q.values()
+	setIfNotEmpty(query, "columns", strings.Join(q.Columns, ","))
+	setIfNotEmpty(query, "format", q.Format)
+	setIfNotEmpty(query, "delimiter", q.Delimiter)
+	if q.BOM {
+		query.Set("bom", "true")
+	}
+
+	b := []byte{}
+	if err := c.do(ctx, http.MethodGet, "/api/export", query, nil, "", &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ExportGoodreads returns the whole library as a Goodreads library export
+// CSV, which Goodreads, StoryGraph and other trackers import.
+func (c *Client) ExportGoodreads(ctx context.Context) ([]byte, error) {
+	b := []byte{}
+	if err := c.do(ctx, http.MethodGet, "/api/export/goodreads", nil, nil, "", &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ExportBibTeX returns the books matching q, or the whole library, as
+// BibTeX.
+func (c *Client) ExportBibTeX(ctx context.Context, q BookQuery) ([]byte, error) {
+	b := []byte{}
+	if err := c.do(ctx, http.MethodGet, "/api/export/bibtex", q.values(), nil, "", &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ExportRIS returns the books matching q, or the whole library, as RIS.
+func (c *Client) ExportRIS(ctx context.Context, q BookQuery) ([]byte, error) {
+	b := []byte{}
+	if err := c.do(ctx, http.MethodGet, "/api/export/ris", q.values(), nil, "", &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ExportJSON returns the whole library as a versioned JSON backup.
+func (c *Client) ExportJSON(ctx context.Context) ([]byte, error) {
+	b := []byte{}
+	if err := c.do(ctx, http.MethodGet, "/api/export/json", nil, nil, "", &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ExportCalendar returns the reading periods as iCalendar, only those read
+// during year and with status when they are set.
+func (c *Client) ExportCalendar(ctx context.Context, year int, status string) ([]byte, error) {
+	query := url.Values{}
+	if year != 0 {
+		query.Set("year", strconv.Itoa(year))
+	}
+	if len(status) > 0 {
+		query.Set("status", status)
+	}
+
+	b := []byte{}
+	if err := c.do(ctx, http.MethodGet, "/api/export/ics", query, nil, "", &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ExportMarkdown returns the library as a zip of Markdown notes, one per
+// book, to be opened as an Obsidian vault.
+func (c *Client) ExportMarkdown(ctx context.Context) ([]byte, error) {
+	b := []byte{}
+	if err := c.do(ctx, http.MethodGet, "/api/export/markdown", nil, nil, "", &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ImportMarkdown applies the book notes of a vault zip made by
+// ExportMarkdown, after they were edited.
+func (c *Client) ImportMarkdown(ctx context.Context, vault io.Reader) (*MarkdownResult, error) {
+	r := MarkdownResult{}
+	if err := c.upload(ctx, "/api/import/markdown", "vault.zip", vault, ImportOptions{}, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ImportJSON replaces the whole library with a backup made by ExportJSON.
+func (c *Client) ImportJSON(ctx context.Context, backup io.Reader) (*RestoreResult, error) {
+	r := RestoreResult{}
+	if err := c.upload(ctx, "/api/import/json", "backup.json", backup, ImportOptions{}, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) upload(ctx context.Context, path string, filename string, file io.Reader, opts ImportOptions, out any) error {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	if opts.Format != "" {
+		_ = w.WriteField("format", opts.Format)
+	}
+	if opts.Preset != 0 {
+		_ = w.WriteField("preset", strconv.FormatUint(uint64(opts.Preset), 10))
+	}
+	if opts.Delimiter != "" {
+		_ = w.WriteField("delimiter", opts.Delimiter)
+	}
+	for field, column := range opts.Mapping {
+		_ = w.WriteField(field, column)
+	}
+	if opts.DateFormat != "" {
+		_ = w.WriteField("date_format", opts.DateFormat)
+	}
+	if opts.StatusRules != nil {
+		rules, err := json.Marshal(opts.StatusRules)
+		if err != nil {
+			return err
+		}
+		_ = w.WriteField("status_rules", string(rules))
+	}
+	if opts.StatusConflict != "" {
+		_ = w.WriteField("status_conflict", opts.StatusConflict)
+	}
+	if opts.Conflict != "" {
+		_ = w.WriteField("conflict", opts.Conflict)
+	}
+	if opts.Match != "" {
+		_ = w.WriteField("match", opts.Match)
+	}
+	if opts.Accepted != nil {
+		lines := []string{}
+		for _, line := range opts.Accepted {
+			lines = append(lines, strconv.Itoa(line))
+		}
+		_ = w.WriteField("accepted", strings.Join(lines, ","))
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.do(ctx, http.MethodPost, path, nil, body, w.FormDataContentType(), out)
+}
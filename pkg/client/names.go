@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) Authors(ctx context.Context, name string, order string) ([]NameCount, error) {
+	return c.names(ctx, "/api/authors.json", name, order)
+}
+
+func (c *Client) RenameAuthor(ctx context.Context, oldName string, newName string) error {
+	return c.rename(ctx, "/api/author/"+pathEscape(oldName)+".json", newName)
+}
+
+func (c *Client) Series(ctx context.Context, name string, order string) ([]NameCount, error) {
+	return c.names(ctx, "/api/series.json", name, order)
+}
+
+func (c *Client) RenameSeries(ctx context.Context, oldName string, newName string) error {
+	return c.rename(ctx, "/api/series/"+pathEscape(oldName)+".json", newName)
+}
+
+func (c *Client) names(ctx context.Context, path string, name string, order string) ([]NameCount, error) {
+	query := url.Values{}
+	setIfNotEmpty(query, "name", name)
+	setIfNotEmpty(query, "order", order)
+
+	r := []NameCount{}
+	if err := c.getJSON(ctx, path, query, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (c *Client) rename(ctx context.Context, path string, newName string) error {
+	in := map[string]string{"name": newName}
+	return c.doJSON(ctx, http.MethodPost, path, nil, in, &okResponse{})
+}
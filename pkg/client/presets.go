@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+func (c *Client) ImportPresets(ctx context.Context) ([]ImportPreset, error) {
+	r := []ImportPreset{}
+	if err := c.getJSON(ctx, "/api/import/presets.json", nil, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (c *Client) CreateImportPreset(ctx context.Context, in ImportPresetInput) (*ImportPreset, error) {
+	r := ImportPreset{}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/import/preset.json", nil, in, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) UpdateImportPreset(ctx context.Context, id uint, in ImportPresetInput) (*ImportPreset, error) {
+	r := ImportPreset{}
+	path := "/api/import/preset/" + strconv.FormatUint(uint64(id), 10) + ".json"
+	if err := c.doJSON(ctx, http.MethodPost, path, nil, in, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) DeleteImportPreset(ctx context.Context, id uint) error {
+	path := "/api/import/preset/" + strconv.FormatUint(uint64(id), 10) + ".json"
+	return c.doJSON(ctx, http.MethodDelete, path, nil, nil, &okResponse{})
+}
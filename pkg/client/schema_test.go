@@ -0,0 +1,280 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// schemaChecker checks the JSON requests and responses going through it
+// against the OpenAPI document, so that the client, the handlers and the
+// document can't drift apart. Properties missing from a schema are reported
+// too.
+type schemaChecker struct {
+	t     *testing.T
+	spec  map[string]any
+	paths []specPath
+}
+
+type specPath struct {
+	path    string
+	pattern *regexp.Regexp
+	params  int
+	ops     map[string]any
+}
+
+var specParamPattern = regexp.MustCompile(`\\\{\w+\\\}`)
+
+func newSchemaChecker(t *testing.T) *schemaChecker {
+	data, err := os.ReadFile("../../internal/route/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &schemaChecker{t: t, spec: map[string]any{}}
+	if err := json.Unmarshal(data, &c.spec); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, ops := range c.spec["paths"].(map[string]any) {
+		c.paths = append(c.paths, specPath{
+			path:    path,
+			pattern: regexp.MustCompile("^" + specParamPattern.ReplaceAllString(regexp.QuoteMeta(path), "[^/]+") + "$"),
+			params:  strings.Count(path, "{"),
+			ops:     ops.(map[string]any),
+		})
+	}
+	// Literal paths win over templated ones, e.g. /api/book/search.json, and
+	// longer ones over their prefixes, e.g. /share/{token}.json
+	sort.Slice(c.paths, func(i, j int) bool {
+		if c.paths[i].params != c.paths[j].params {
+			return c.paths[i].params < c.paths[j].params
+		}
+		return len(c.paths[i].path) > len(c.paths[j].path)
+	})
+	return c
+}
+
+func (c *schemaChecker) RoundTrip(req *http.Request) (*http.Response, error) {
+	name := req.Method + " " + req.URL.EscapedPath()
+	op := c.operation(req)
+	if op == nil {
+		c.t.Errorf("%s: not documented", name)
+		return http.DefaultTransport.RoundTrip(req)
+	}
+
+	var sent []byte
+	if req.Body != nil && strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		sent = data
+	}
+
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	// Requests rejected by the server may be invalid on purpose
+	if sent != nil && resp.StatusCode < 300 {
+		if schema := dig(op, "requestBody", "content", "application/json", "schema"); schema != nil {
+			c.check(name+" request", schema, sent)
+		} else {
+			c.t.Errorf("%s: JSON request body not documented", name)
+		}
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return resp, nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	name = fmt.Sprintf("%s %d", name, resp.StatusCode)
+	responses, _ := op["responses"].(map[string]any)
+	response, ok := responses[fmt.Sprint(resp.StatusCode)]
+	if !ok {
+		response, ok = responses["default"]
+	}
+	if !ok {
+		c.t.Errorf("%s: response not documented", name)
+		return resp, nil
+	}
+	if schema := dig(c.resolve(response), "content", "application/json", "schema"); schema != nil {
+		c.check(name, schema, data)
+	} else {
+		c.t.Errorf("%s: JSON response not documented", name)
+	}
+	return resp, nil
+}
+
+func (c *schemaChecker) operation(req *http.Request) map[string]any {
+	for _, p := range c.paths {
+		if p.pattern.MatchString(req.URL.EscapedPath()) {
+			op, _ := p.ops[strings.ToLower(req.Method)].(map[string]any)
+			return op
+		}
+	}
+	return nil
+}
+
+func (c *schemaChecker) check(name string, schema any, data []byte) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		c.t.Errorf("%s: %v", name, err)
+		return
+	}
+	for _, problem := range c.validate(schema, v, "$") {
+		c.t.Errorf("%s: %s", name, problem)
+	}
+}
+
+// resolve follows $ref to the component it points to.
+func (c *schemaChecker) resolve(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	for {
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return m
+		}
+		parts := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+		m, _ = dig(c.spec, parts...).(map[string]any)
+	}
+}
+
+// flatten resolves the schema and merges its allOf members into it.
+func (c *schemaChecker) flatten(v any) map[string]any {
+	s := c.resolve(v)
+	all, ok := s["allOf"].([]any)
+	if !ok {
+		return s
+	}
+
+	base := map[string]any{}
+	for k, v := range s {
+		if k != "allOf" {
+			base[k] = v
+		}
+	}
+
+	merged := map[string]any{}
+	properties := map[string]any{}
+	required := []any{}
+	for _, member := range append([]any{base}, all...) {
+		for k, v := range c.flatten(member) {
+			switch k {
+			case "properties":
+				for name, p := range v.(map[string]any) {
+					properties[name] = p
+				}
+			case "required":
+				required = append(required, v.([]any)...)
+			default:
+				merged[k] = v
+			}
+		}
+	}
+	if len(properties) > 0 {
+		merged["properties"] = properties
+		merged["type"] = "object"
+	}
+	merged["required"] = required
+	return merged
+}
+
+func (c *schemaChecker) validate(schema any, v any, at string) []string {
+	s := c.flatten(schema)
+	if v == nil {
+		if nullable, _ := s["nullable"].(bool); nullable || s["type"] == nil {
+			return nil
+		}
+		return []string{at + ": null is not nullable"}
+	}
+
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == v
+		}
+		if !found {
+			return []string{fmt.Sprintf("%s: %v is not one of %v", at, v, enum)}
+		}
+	}
+
+	problems := []string{}
+	wrongType := func() []string {
+		return []string{fmt.Sprintf("%s: %T is not %v", at, v, s["type"])}
+	}
+	switch s["type"] {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return wrongType()
+		}
+		required, _ := s["required"].([]any)
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: %s is missing", at, name))
+			}
+		}
+		properties, _ := s["properties"].(map[string]any)
+		for name, value := range obj {
+			if p, ok := properties[name]; ok {
+				problems = append(problems, c.validate(p, value, at+"."+name)...)
+			} else if extra, ok := s["additionalProperties"].(map[string]any); ok {
+				problems = append(problems, c.validate(extra, value, at+"."+name)...)
+			} else if s["additionalProperties"] != true {
+				problems = append(problems, fmt.Sprintf("%s: %s is not documented", at, name))
+			}
+		}
+	case "array":
+		list, ok := v.([]any)
+		if !ok {
+			return wrongType()
+		}
+		for i, item := range list {
+			problems = append(problems, c.validate(s["items"], item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return wrongType()
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != math.Trunc(n) {
+			return wrongType()
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return wrongType()
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return wrongType()
+		}
+	}
+	return problems
+}
+
+func dig(v any, keys ...string) any {
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+func (c *Client) Shares(ctx context.Context) ([]Share, error) {
+	r := []Share{}
+	if err := c.getJSON(ctx, "/api/shares.json", nil, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (c *Client) CreateShare(ctx context.Context, in ShareInput) (*Share, error) {
+	r := Share{}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/share.json", nil, in, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) DeleteShare(ctx context.Context, id uint) error {
+	path := "/api/share/" + strconv.FormatUint(uint64(id), 10) + ".json"
+	return c.doJSON(ctx, http.MethodDelete, path, nil, nil, &okResponse{})
+}
+
+// PublicShare reads a shared list by its token. No login is required.
+func (c *Client) PublicShare(ctx context.Context, token string) (*PublicShare, error) {
+	r := PublicShare{}
+	if err := c.getJSON(ctx, "/share/"+pathEscape(token)+".json", nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,366 @@
+package client
+
+import "time"
+
+const (
+	StatusToRead  = "to-read"
+	StatusReading = "reading"
+	StatusRead    = "read"
+)
+
+const (
+	RoleAuthor      = "author"
+	RoleEditor      = "editor"
+	RoleTranslator  = "translator"
+	RoleIllustrator = "illustrator"
+)
+
+// Credit is a person credited on a book with a role.
+type Credit struct {
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+}
+
+type Book struct {
+	ID         uint       `json:"id"`
+	Title      string     `json:"title"`
+	Author     string     `json:"author"`
+	Series     string     `json:"series"`
+	ISBN       string     `json:"isbn"`
+	Comments   string     `json:"comments"`
+	Status     string     `json:"status"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	// Credits are only returned by Book.
+	Credits []Credit `json:"credits,omitempty"`
+}
+
+// BookInput is the payload for creating and updating books. Dates are
+// formatted as YYYY-MM-DD, or left empty.
+type BookInput struct {
+	Title      string `json:"title"`
+	Author     string `json:"author"`
+	Series     string `json:"series"`
+	ISBN       string `json:"isbn"`
+	Comments   string `json:"comments"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at"`
+	// Status is kept for books without dates, which are otherwise to-read.
+	Status string `json:"status,omitempty"`
+	// Credits replace Author with their credit line when given.
+	Credits []Credit `json:"credits,omitempty"`
+}
+
+type BookQuery struct {
+	Name   string
+	Sort   string
+	Order  string
+	Status string
+	// Year is the year books were finished in.
+	Year   int
+	Author string
+	Series string
+}
+
+const (
+	ExportCSV = "csv"
+	ExportTSV = "tsv"
+)
+
+// ExportQuery selects the books and columns of ExportBooks. Zero fields
+// export every book with the default columns as CSV.
+type ExportQuery struct {
+	BookQuery
+	Columns []string
+	// Format is ExportCSV or ExportTSV.
+	Format string
+	// Delimiter overrides the one of the format.
+	Delimiter string
+	// BOM starts the file with a UTF-8 byte order mark, for Excel.
+	BOM bool
+}
+
+type NameCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type YearRecord struct {
+	Year  int `json:"year"`
+	Count int `json:"count"`
+	Ratio int `json:"ratio"`
+}
+
+type StatRecord struct {
+	ToRead   int64 `json:"to_read"`
+	Reading  int64 `json:"reading"`
+	Finished int64 `json:"finished"`
+}
+
+type Home struct {
+	YearRecords       []YearRecord `json:"year_records"`
+	CurrentYearRecord YearRecord   `json:"current_year_record"`
+	ReadingBooks      []Book       `json:"reading_books"`
+	Counts            StatRecord   `json:"counts"`
+}
+
+type GoogleBook struct {
+	ID       int    `json:"id"`
+	Title    string `json:"title"`
+	Author   string `json:"author"`
+	ISBN     string `json:"isbn"`
+	InfoLink string `json:"info_link"`
+}
+
+type AuthStatus struct {
+	Authenticated bool   `json:"authenticated"`
+	Username      string `json:"username"`
+}
+
+type ImportColumns struct {
+	Presets         []string      `json:"presets"`
+	Columns         []string      `json:"columns"`
+	Format          string        `json:"format"`
+	SuggestedPreset *ImportPreset `json:"suggested_preset"`
+}
+
+const (
+	ConflictSkip   = "skip"
+	ConflictUpdate = "update"
+	ConflictCreate = "create"
+
+	MatchAny         = "any"
+	MatchISBN        = "isbn"
+	MatchTitleAuthor = "title_author"
+
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+
+	DateFormatAuto      = "auto"
+	DateFormatISO       = "yyyy-mm-dd"
+	DateFormatYMD       = "yyyy/mm/dd"
+	DateFormatDMY       = "dd/mm/yyyy"
+	DateFormatMDY       = "mm/dd/yyyy"
+	DateFormatMonthName = "month_name"
+	DateFormatExcel     = "excel"
+
+	FormatCSV        = "csv"
+	FormatStoryGraph = "storygraph"
+	FormatCalibre    = "calibre"
+
+	StatusConflictDates  = "dates"
+	StatusConflictStatus = "status"
+)
+
+// ImportOptions maps book fields ("Title", "Author", ...) to CSV columns.
+// Format forces the parser, by default Calibre libraries and StoryGraph
+// exports are recognised and read without a mapping. When Preset is set the
+// saved preset's delimiter, mapping and date format are used instead. When Accepted is not nil only the rows at those lines are
+// imported. Conflict and Match decide what happens to rows matching existing
+// books.
+type ImportOptions struct {
+	Format         string
+	Preset         uint
+	Delimiter      string
+	Mapping        map[string]string
+	DateFormat     string
+	StatusRules    map[string]string
+	StatusConflict string
+	Accepted       []int
+	Conflict       string
+	Match          string
+}
+
+type ImportPresetInput struct {
+	Name           string            `json:"name"`
+	Delimiter      string            `json:"delimiter"`
+	Columns        map[string]string `json:"columns"`
+	DateFormat     string            `json:"date_format"`
+	StatusRules    map[string]string `json:"status_rules,omitempty"`
+	StatusConflict string            `json:"status_conflict,omitempty"`
+}
+
+type ImportPreset struct {
+	ID             uint              `json:"id"`
+	Name           string            `json:"name"`
+	Delimiter      string            `json:"delimiter"`
+	Columns        map[string]string `json:"columns"`
+	DateFormat     string            `json:"date_format"`
+	StatusRules    map[string]string `json:"status_rules,omitempty"`
+	StatusConflict string            `json:"status_conflict,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+const (
+	HighlightKindHighlight = "highlight"
+	HighlightKindNote      = "note"
+)
+
+type Highlight struct {
+	ID        uint       `json:"id"`
+	BookID    uint       `json:"book_id"`
+	Kind      string     `json:"kind"`
+	Text      string     `json:"text"`
+	Location  string     `json:"location"`
+	Page      string     `json:"page"`
+	AddedAt   *time.Time `json:"added_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ClippingsResult struct {
+	Total        int `json:"total"`
+	Created      int `json:"created"`
+	Updated      int `json:"updated"`
+	Duplicates   int `json:"duplicates"`
+	BooksMatched int `json:"books_matched"`
+	BooksCreated int `json:"books_created"`
+}
+
+type KoboResult struct {
+	Total             int `json:"total"`
+	BooksMatched      int `json:"books_matched"`
+	BooksCreated      int `json:"books_created"`
+	BooksUpdated      int `json:"books_updated"`
+	BooksSkipped      int `json:"books_skipped"`
+	HighlightsCreated int `json:"highlights_created"`
+	HighlightsUpdated int `json:"highlights_updated"`
+	Duplicates        int `json:"duplicates"`
+}
+
+// DocumentProgress is a document synced by KOReader, or imported from a
+// Kobo. SyncedAt is zero for documents linked before their first sync.
+type DocumentProgress struct {
+	ID         uint    `json:"id"`
+	Document   string  `json:"document"`
+	BookID     *uint   `json:"book_id"`
+	Progress   string  `json:"progress"`
+	Percentage float64 `json:"percentage"`
+	Device     string  `json:"device"`
+	DeviceID   string  `json:"device_id"`
+	// ReadingTime is in seconds.
+	ReadingTime int       `json:"reading_time"`
+	SyncedAt    time.Time `json:"synced_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ImportConflict struct {
+	Line   int    `json:"line"`
+	BookID uint   `json:"book_id"`
+	Action string `json:"action"`
+}
+
+type ImportResult struct {
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Failed    int              `json:"failed"`
+	Skipped   int              `json:"skipped"`
+	Conflicts []ImportConflict `json:"conflicts"`
+	Errors    []ImportRowError `json:"errors"`
+}
+
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// ImportJob is a background import started with StartImportJob.
+type ImportJob struct {
+	ID         string       `json:"id"`
+	Status     string       `json:"status"`
+	Total      int          `json:"total"`
+	Processed  int          `json:"processed"`
+	Result     ImportResult `json:"result"`
+	Error      string       `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	FinishedAt *time.Time   `json:"finished_at"`
+}
+
+// Finished reports whether the job succeeded, failed or was canceled.
+func (j *ImportJob) Finished() bool {
+	return j.FinishedAt != nil
+}
+
+type ImportRow struct {
+	Line            int          `json:"line"`
+	Book            Book         `json:"book"`
+	Errors          []FieldError `json:"errors"`
+	Warnings        []FieldError `json:"warnings"`
+	DuplicateIDs    []uint       `json:"duplicate_ids"`
+	DuplicateOfLine int          `json:"duplicate_of_line"`
+}
+
+type ImportPreview struct {
+	Total       int               `json:"total"`
+	Valid       int               `json:"valid"`
+	Invalid     int               `json:"invalid"`
+	Duplicates  int               `json:"duplicates"`
+	DateFormats map[string]string `json:"date_formats"`
+	Rows        []ImportRow       `json:"rows"`
+}
+
+type RestoreResult struct {
+	Books         int `json:"books"`
+	Shares        int `json:"shares"`
+	ImportPresets int `json:"import_presets"`
+	Highlights    int `json:"highlights"`
+	Documents     int `json:"documents"`
+	Authors       int `json:"authors"`
+	Credits       int `json:"credits"`
+}
+
+type MarkdownResult struct {
+	Total     int             `json:"total"`
+	Created   int             `json:"created"`
+	Updated   int             `json:"updated"`
+	Unchanged int             `json:"unchanged"`
+	Errors    []MarkdownError `json:"errors"`
+}
+
+type MarkdownError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+type Share struct {
+	ID           uint      `json:"id"`
+	Token        string    `json:"token"`
+	Title        string    `json:"title"`
+	Kind         string    `json:"kind"`
+	Value        string    `json:"value"`
+	ShowComments bool      `json:"show_comments"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ShareInput struct {
+	Title        string `json:"title"`
+	Kind         string `json:"kind"`
+	Value        string `json:"value"`
+	ShowComments bool   `json:"show_comments"`
+}
+
+type PublicBook struct {
+	Title      string     `json:"title"`
+	Author     string     `json:"author"`
+	Series     string     `json:"series"`
+	ISBN       string     `json:"isbn"`
+	Comments   string     `json:"comments"`
+	Status     string     `json:"status"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+type PublicShare struct {
+	Title string       `json:"title"`
+	Kind  string       `json:"kind"`
+	Value string       `json:"value"`
+	Books []PublicBook `json:"books"`
+}
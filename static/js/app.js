@@ -12,26 +12,71 @@ function formatDate(date) {
   return `${year}-${month}-${day}`;
 }
 
+function readCookie(name) {
+  const prefix = name + '=';
+  const cookie = document.cookie.split('; ').find(c => c.startsWith(prefix));
+  return cookie ? decodeURIComponent(cookie.substring(prefix.length)) : null;
+}
+
+async function csrfToken() {
+  const token = readCookie('csrf_');
+  if (token) {
+    return token;
+  }
+  const resp = await fetch('/api/csrf.json');
+  return (await resp.json()).csrf_token;
+}
+
+// fetch() which attaches the CSRF token to mutating requests. A stale token
+// is expired by the server, so a rejected request is retried once.
+async function $fetch(url, options = {}) {
+  const method = (options.method || 'GET').toUpperCase();
+  if (['GET', 'HEAD', 'OPTIONS'].includes(method)) {
+    return fetch(url, options);
+  }
+
+  const send = async () => fetch(url, {
+    ...options,
+    headers: { ...(options.headers || {}), 'X-Csrf-Token': await csrfToken() },
+  });
+  const resp = await send();
+  if (resp.status === 403) {
+    return send();
+  }
+  return resp;
+}
+
 async function $json(url, method, data) {
-  const resp = await fetch(url, {
+  const resp = await $fetch(url, {
     method: method || "GET",
     headers: { "Content-Type": "application/json" },
     body: data ? JSON.stringify(data) : null,
   });
 
-  if (resp.status === 401) {
+  if (!resp.ok) {
+    throw await $error(resp);
+  }
+  return await resp.json();
+}
+
+// Converts an error response ({ok, code, message, errors}) into an Error
+// carrying the code and every field message.
+async function $error(resp) {
+  const json = await resp.json().catch(() => ({}));
+
+  if (resp.status === 401 && json.code !== 'invalid_credentials') {
     // Redirect to login on authentication error
     if (window.location.pathname !== '/page/login') {
       router.push('/page/login');
     }
-    throw Error('Authentication required');
   }
 
-  if (!resp.ok) {
-    const json = await resp.json();
-    throw Error(json.message);
-  }
-  return await resp.json();
+  const messages = (json.errors || []).map(e => e.message);
+  const error = Error(messages.length > 0 ? messages.join(', ') : (json.message || resp.statusText));
+  error.status = resp.status;
+  error.code = json.code;
+  error.errors = json.errors || [];
+  return error;
 }
 
 // Simple router
@@ -838,6 +883,10 @@ const BookView = {
   props: ['bookId'],
   setup(props) {
     const book = ref(null);
+    const highlights = ref([]);
+    const documents = ref([]);
+    const unlinkedDocuments = ref([]);
+    const selectedDocument = ref('');
     const loading = ref(true);
 
     const statusOptions = [
@@ -850,6 +899,8 @@ const BookView = {
       try {
         loading.value = true;
         book.value = await $json(`/api/book/${props.bookId}.json`);
+        highlights.value = await $json(`/api/book/${props.bookId}/highlights.json`);
+        await fetchDocuments();
       } catch (error) {
         console.error('Error fetching book:', error);
       } finally {
@@ -881,10 +932,39 @@ const BookView = {
       }
     };
 
+    const fetchDocuments = async () => {
+      const all = await $json('/api/documents.json');
+      documents.value = all.filter(d => d.book_id === book.value.id);
+      unlinkedDocuments.value = all.filter(d => d.book_id === null && d.device);
+    };
+
+    const linkDocument = async (document, bookId) => {
+      try {
+        await $json(`/api/document/${encodeURIComponent(document)}.json`, 'POST', { book_id: bookId });
+        selectedDocument.value = '';
+        await fetchBook();
+      } catch (error) {
+        console.error('Error linking document:', error);
+        alert('Error: ' + error.message);
+      }
+    };
+
+    const deleteHighlight = async (highlight) => {
+      if (confirm('Delete this ' + highlight.kind + '?')) {
+        try {
+          await $json(`/api/highlight/${highlight.id}.json`, 'DELETE');
+          highlights.value = highlights.value.filter(h => h.id !== highlight.id);
+        } catch (error) {
+          console.error('Error deleting highlight:', error);
+        }
+      }
+    };
+
     onMounted(fetchBook);
 
     return { 
-      book, loading, formatDate, navigate, changeStatus, deleteBook, statusOptions
+      book, highlights, documents, unlinkedDocuments, selectedDocument, loading, formatDate, navigate,
+      changeStatus, deleteBook, deleteHighlight, linkDocument, statusOptions
     };
   },
   template: `
@@ -907,7 +987,17 @@ const BookView = {
                         <div class="flex flex-col md:flex-row md:items-start md:justify-between">
                             <div>
                                 <h1 class="text-2xl md:text-3xl font-light text-gray-900 dark:text-gray-100 mb-2">{{ book.title }}</h1>
-                                <p class="text-lg md:text-xl text-gray-600 dark:text-gray-400 mb-3">by {{ book.author }}</p>
+                                <p class="text-lg md:text-xl text-gray-600 dark:text-gray-400 mb-3">by
+                                    <template v-if="book.credits && book.credits.length">
+                                        <template v-for="(credit, i) in book.credits" :key="credit.name + credit.role">
+                                            <span v-if="i > 0"> &amp; </span>
+                                            <a href="#" @click.prevent="navigate('/page/author/' + encodeURIComponent(credit.name))"
+                                               class="hover:text-indigo-600 dark:hover:text-indigo-400">{{ credit.name }}</a>
+                                            <span v-if="credit.role !== 'author'" class="text-sm text-gray-500 dark:text-gray-400"> ({{ credit.role }})</span>
+                                        </template>
+                                    </template>
+                                    <template v-else>{{ book.author }}</template>
+                                </p>
                             </div>
                             <div class="flex space-x-2 mt-4 md:flex-col md:space-y-2 md:space-x-0 md:mt-0 md:ml-4">
                                 <button @click="navigate('/page/book/' + book.id + '/edit')" 
@@ -932,6 +1022,12 @@ const BookView = {
                             </svg>
                             <span class="text-sm text-gray-700 dark:text-gray-300">{{ book.series }}</span>
                         </div>
+                        <div class="text-xs text-gray-500 dark:text-gray-400 mt-3">
+                            Cite:
+                            <a :href="'/api/book/' + book.id + '/citation.bib'" class="text-indigo-600 dark:text-indigo-400 hover:underline">BibTeX</a>
+                            ·
+                            <a :href="'/api/book/' + book.id + '/citation.ris'" class="text-indigo-600 dark:text-indigo-400 hover:underline">RIS</a>
+                        </div>
                     </div>
                 </div>
             </div>
@@ -952,6 +1048,31 @@ const BookView = {
                             label="Current Status"
                             @update:modelValue="changeStatus"
                         />
+                        <div v-for="doc in documents" :key="doc.id" class="text-sm">
+                            <div class="flex items-center justify-between text-gray-600 dark:text-gray-400 mb-1">
+                                <span>{{ doc.device || 'KOReader' }}<span v-if="doc.device"> · {{ formatDate(doc.synced_at) }}</span><span v-if="doc.reading_time"> · {{ (doc.reading_time / 3600).toFixed(1) }} h read</span></span>
+                                <span>
+                                    {{ Math.round(doc.percentage * 100) }}%
+                                    <button @click="linkDocument(doc.document, null)" class="ml-2 text-xs text-red-600 dark:text-red-400">Unlink</button>
+                                </span>
+                            </div>
+                            <div class="w-full bg-gray-200 dark:bg-gray-700 rounded-full h-2">
+                                <div class="bg-indigo-600 dark:bg-indigo-500 h-2 rounded-full" :style="{ width: (doc.percentage * 100) + '%' }"></div>
+                            </div>
+                        </div>
+                        <div v-if="unlinkedDocuments.length > 0" class="flex items-center space-x-2">
+                            <select v-model="selectedDocument"
+                                    class="flex-1 rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-2 py-1 text-xs">
+                                <option value="">Link a KOReader document...</option>
+                                <option v-for="doc in unlinkedDocuments" :key="doc.id" :value="doc.document">
+                                    {{ doc.device }}, {{ Math.round(doc.percentage * 100) }}%, synced {{ formatDate(doc.synced_at) }}
+                                </option>
+                            </select>
+                            <button @click="linkDocument(selectedDocument, book.id)" :disabled="!selectedDocument"
+                                    class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs disabled:opacity-50">
+                                Link
+                            </button>
+                        </div>
                     </div>
                 </div>
 
@@ -1010,6 +1131,33 @@ const BookView = {
                 </div>
             </div>
 
+            <!-- Highlights Section -->
+            <div v-if="highlights.length > 0" class="bg-white dark:bg-gray-800 p-4 md:p-6 rounded-xl shadow-sm border border-gray-100 dark:border-gray-700">
+                <h3 class="text-lg font-medium text-gray-900 dark:text-gray-100 mb-4 flex items-center">
+                    <svg class="w-5 h-5 mr-2 text-yellow-500 dark:text-yellow-400" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                        <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M15.232 5.232l3.536 3.536m-2.036-5.036a2.5 2.5 0 113.536 3.536L6.5 21.036H3v-3.572L16.732 3.732z"></path>
+                    </svg>
+                    Highlights
+                    <span class="ml-2 text-sm text-gray-500 dark:text-gray-400">{{ highlights.length }}</span>
+                </h3>
+                <div class="space-y-3">
+                    <div v-for="h in highlights" :key="h.id"
+                         class="p-3 rounded-lg border-l-4"
+                         :class="h.kind === 'note' ? 'bg-blue-50 dark:bg-blue-900/20 border-blue-300 dark:border-blue-600' : 'bg-yellow-50 dark:bg-yellow-900/20 border-yellow-300 dark:border-yellow-600'">
+                        <p class="text-gray-800 dark:text-gray-200 leading-relaxed whitespace-pre-line" :class="{ 'italic': h.kind === 'note' }">{{ h.text }}</p>
+                        <div class="flex items-center justify-between mt-2 text-xs text-gray-500 dark:text-gray-400">
+                            <span>
+                                <span v-if="h.kind === 'note'">Note · </span>
+                                <span v-if="h.page">Page {{ h.page }} · </span>
+                                <span v-if="h.location">Location {{ h.location }}</span>
+                                <span v-if="h.added_at"> · {{ formatDate(h.added_at) }}</span>
+                            </span>
+                            <button @click="deleteHighlight(h)" class="text-red-600 dark:text-red-400">Delete</button>
+                        </div>
+                    </div>
+                </div>
+            </div>
+
             <!-- Quick Actions -->
             <div class="bg-white dark:bg-gray-800 p-4 md:p-6 rounded-xl shadow-sm border border-gray-100 dark:border-gray-700">
                 <h3 class="text-lg font-medium text-gray-900 dark:text-gray-100 mb-4 flex items-center">
@@ -1027,7 +1175,7 @@ const BookView = {
                             class="bg-green-100 dark:bg-green-900 text-green-700 dark:text-green-300 px-3 py-1.5 rounded-md hover:bg-green-200 dark:hover:bg-green-800 transition-colors text-sm">
                         ✅ Mark as Finished
                     </button>
-                    <button v-if="book.author" @click="navigate('/page/author/' + encodeURIComponent(book.author))"
+                    <button v-if="book.credits && book.credits.length" @click="navigate('/page/author/' + encodeURIComponent(book.credits[0].name))"
                             class="bg-gray-100 dark:bg-gray-700 text-gray-700 dark:text-gray-300 px-3 py-1.5 rounded-md hover:bg-gray-200 dark:hover:bg-gray-600 transition-colors text-sm">
                         👤 View Author's Books
                     </button>
@@ -1106,7 +1254,12 @@ const BookEdit = {
       }
     };
 
-    const filterAuthors = (query) => {
+    // The author field lists co-authors separated by "&" or ";", and the
+    // suggestions complete the last of them
+    const lastAuthorIndex = (value) => Math.max(value.lastIndexOf('&'), value.lastIndexOf(';'));
+
+    const filterAuthors = (value) => {
+      const query = (value || '').slice(lastAuthorIndex(value || '') + 1).trim();
       if (!query) {
         filteredAuthors.value = [];
         showAuthorDropdown.value = false;
@@ -1131,7 +1284,8 @@ const BookEdit = {
     };
 
     const selectAuthor = (author) => {
-      book.author = author;
+      const i = lastAuthorIndex(book.author);
+      book.author = i < 0 ? author : book.author.slice(0, i + 1) + ' ' + author;
       showAuthorDropdown.value = false;
     };
 
@@ -1157,6 +1311,8 @@ const BookEdit = {
         const method = props.bookId ? 'POST' : 'POST';
 
         const bookData = { ...book };
+        // Credits are read back from the author field
+        delete bookData.credits;
         // Ensure dates are strings (empty string if not set)
         bookData.started_at = bookData.started_at || '';
         bookData.finished_at = bookData.finished_at || '';
@@ -1330,6 +1486,7 @@ const BookEdit = {
                             {{ author }}
                         </button>
                     </div>
+                    <p class="text-xs text-gray-500 dark:text-gray-400 mt-1">Separate co-authors with "&amp;", and mark other roles like "Emily Wilson (translator)", "(editor)" or "(illustrator)".</p>
                 </div>
                 
                 <div class="relative">
@@ -1604,11 +1761,185 @@ const Admin = {
       }
     };
 
+    const exportOptions = reactive({ year: '', status: '', format: 'csv', bom: false });
+
     const exportData = () => {
-      window.open('/api/export', '_blank');
+      const params = new URLSearchParams();
+      if (exportOptions.year) params.set('year', exportOptions.year);
+      if (exportOptions.status) params.set('status', exportOptions.status);
+      if (exportOptions.format !== 'csv') params.set('format', exportOptions.format);
+      if (exportOptions.bom) params.set('bom', 'true');
+      const query = params.toString();
+      window.open('/api/export' + (query ? '?' + query : ''), '_blank');
+    };
+
+    const exportJSON = () => {
+      window.open('/api/export/json', '_blank');
+    };
+
+    const exportCalendar = () => {
+      window.open('/api/export/ics', '_blank');
+    };
+
+    const exportCitations = (format) => {
+      const params = new URLSearchParams();
+      if (exportOptions.year) params.set('year', exportOptions.year);
+      if (exportOptions.status) params.set('status', exportOptions.status);
+      const query = params.toString();
+      window.open(`/api/export/${format}` + (query ? '?' + query : ''), '_blank');
+    };
+
+    const exportGoodreads = () => {
+      window.open('/api/export/goodreads', '_blank');
+    };
+
+    const exportMarkdown = () => {
+      window.open('/api/export/markdown', '_blank');
+    };
+
+    const importMarkdown = async (event) => {
+      const selectedFile = event.target.files[0];
+      event.target.value = '';
+      if (!selectedFile) return;
+
+      const formData = new FormData();
+      formData.append('file', selectedFile);
+
+      try {
+        const response = await $fetch('/api/import/markdown', {
+          method: 'POST',
+          body: formData
+        });
+        if (!response.ok) {
+          throw await $error(response);
+        }
+        const result = await response.json();
+        let message = `Markdown imported! Updated: ${result.updated}, Created: ${result.created}, Unchanged: ${result.unchanged}`;
+        if (result.errors.length > 0) {
+          message += '\n\n' + result.errors.map(e => `${e.path}: ${e.message}`).join('\n');
+        }
+        alert(message);
+      } catch (error) {
+        console.error('Error importing Markdown:', error);
+        alert('Error: ' + error.message);
+      }
     };
 
-    return { navigate, deleteAll, exportData };
+    const restoreJSON = async (event) => {
+      const selectedFile = event.target.files[0];
+      event.target.value = '';
+      if (!selectedFile) return;
+      if (!confirm('Restoring replaces every book and shared link with the backup. Continue?')) return;
+
+      const formData = new FormData();
+      formData.append('file', selectedFile);
+
+      try {
+        const response = await $fetch('/api/import/json', {
+          method: 'POST',
+          body: formData
+        });
+        if (!response.ok) {
+          throw await $error(response);
+        }
+        const result = await response.json();
+        alert(`Restore completed! Books: ${result.books}, Shared links: ${result.shares}`);
+        await fetchShares();
+      } catch (error) {
+        console.error('Error restoring backup:', error);
+        alert('Error: ' + error.message);
+      }
+    };
+
+    const importClippings = async (event) => {
+      const selectedFile = event.target.files[0];
+      event.target.value = '';
+      if (!selectedFile) return;
+
+      const formData = new FormData();
+      formData.append('file', selectedFile);
+
+      try {
+        const response = await $fetch('/api/import/clippings', {
+          method: 'POST',
+          body: formData
+        });
+        if (!response.ok) {
+          throw await $error(response);
+        }
+        const result = await response.json();
+        alert(`Highlights imported! New: ${result.created}, Extended: ${result.updated}, Duplicates: ${result.duplicates}, New books: ${result.books_created}`);
+      } catch (error) {
+        console.error('Error importing clippings:', error);
+        alert('Error: ' + error.message);
+      }
+    };
+
+    const importKobo = async (event) => {
+      const selectedFile = event.target.files[0];
+      event.target.value = '';
+      if (!selectedFile) return;
+
+      const formData = new FormData();
+      formData.append('file', selectedFile);
+
+      try {
+        const response = await $fetch('/api/import/kobo', {
+          method: 'POST',
+          body: formData
+        });
+        if (!response.ok) {
+          throw await $error(response);
+        }
+        const result = await response.json();
+        alert(`Kobo imported! Books updated: ${result.books_updated}, New books: ${result.books_created}, New highlights: ${result.highlights_created}, Duplicates: ${result.duplicates}`);
+      } catch (error) {
+        console.error('Error importing Kobo:', error);
+        alert('Error: ' + error.message);
+      }
+    };
+
+    const shares = ref([]);
+    const newShare = reactive({ kind: 'year', value: String(new Date().getFullYear()), title: '', show_comments: false });
+
+    const fetchShares = async () => {
+      try {
+        shares.value = await $json('/api/shares.json');
+      } catch (error) {
+        console.error('Error fetching shares:', error);
+      }
+    };
+
+    const createShare = async () => {
+      try {
+        await $json('/api/share.json', 'POST', newShare);
+        newShare.title = '';
+        await fetchShares();
+      } catch (error) {
+        console.error('Error creating share:', error);
+        alert('Error: ' + error.message);
+      }
+    };
+
+    const revokeShare = async (share) => {
+      if (confirm('Revoke this link? Anyone using it will lose access.')) {
+        try {
+          await $json('/api/share/' + share.id + '.json', 'DELETE');
+          await fetchShares();
+        } catch (error) {
+          console.error('Error revoking share:', error);
+          alert('Error: ' + error.message);
+        }
+      }
+    };
+
+    const shareURL = (share) => window.location.origin + '/share/' + share.token;
+    const kosyncURL = window.location.origin + '/kosync';
+    const opdsURL = window.location.origin + '/opds';
+
+    onMounted(fetchShares);
+
+    return { navigate, deleteAll, exportOptions, exportData, exportJSON, exportCalendar, exportCitations, exportGoodreads, exportMarkdown, importMarkdown, restoreJSON, importClippings, importKobo, shares, newShare, createShare, revokeShare, shareURL, kosyncURL, opdsURL };
   },
   template: `
         <div class="space-y-6">
@@ -1617,20 +1948,143 @@ const Admin = {
             <div class="bg-white dark:bg-gray-800 p-4 rounded-lg shadow-sm space-y-3">
                 <div>
                     <h3 class="text-sm font-medium mb-1.5 text-gray-900 dark:text-gray-100">Import Data</h3>
-                    <button @click="navigate('/page/admin/import')"
-                            class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs">
-                        Import from CSV
-                    </button>
+                    <div class="flex items-center space-x-2">
+                        <button @click="navigate('/page/admin/import')"
+                                class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs">
+                            Import from CSV
+                        </button>
+                        <label class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs cursor-pointer">
+                            Import Kindle Highlights
+                            <input type="file" accept=".txt,text/plain" @change="importClippings" class="hidden">
+                        </label>
+                        <label class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs cursor-pointer">
+                            Import Kobo
+                            <input type="file" accept=".sqlite" @change="importKobo" class="hidden">
+                        </label>
+                        <label class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs cursor-pointer">
+                            Import Markdown
+                            <input type="file" accept=".zip,application/zip" @change="importMarkdown" class="hidden">
+                        </label>
+                    </div>
                 </div>
                 
                 <div>
                     <h3 class="text-sm font-medium mb-1.5 text-gray-900 dark:text-gray-100">Export Data</h3>
-                    <button @click="exportData"
-                            class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs">
-                        Export to CSV
-                    </button>
+                    <div class="flex flex-wrap items-center gap-2 mb-2">
+                        <input v-model="exportOptions.year" type="number" placeholder="Year finished"
+                               class="w-28 rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-2 py-1 text-xs">
+                        <select v-model="exportOptions.status"
+                                class="rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-2 py-1 text-xs">
+                            <option value="">All statuses</option>
+                            <option value="to-read">To Read</option>
+                            <option value="reading">Reading</option>
+                            <option value="read">Read</option>
+                        </select>
+                        <select v-model="exportOptions.format"
+                                class="rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-2 py-1 text-xs">
+                            <option value="csv">CSV</option>
+                            <option value="tsv">TSV</option>
+                        </select>
+                        <label class="flex items-center gap-1 text-xs text-gray-700 dark:text-gray-300">
+                            <input v-model="exportOptions.bom" type="checkbox"> For Excel
+                        </label>
+                    </div>
+                    <div class="flex items-center space-x-2">
+                        <button @click="exportData"
+                                class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs">
+                            Export Books
+                        </button>
+                        <button @click="exportGoodreads"
+                                class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs">
+                            Export for Goodreads
+                        </button>
+                        <button @click="exportCitations('bibtex')"
+                                class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs">
+                            Export BibTeX
+                        </button>
+                        <button @click="exportCitations('ris')"
+                                class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs">
+                            Export RIS
+                        </button>
+                        <button @click="exportCalendar"
+                                class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs">
+                            Export Calendar
+                        </button>
+                        <button @click="exportMarkdown"
+                                class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs">
+                            Export to Markdown
+                        </button>
+                    </div>
+                </div>
+
+                <div>
+                    <h3 class="text-sm font-medium mb-1.5 text-gray-900 dark:text-gray-100">Backup</h3>
+                    <div class="flex items-center space-x-2">
+                        <button @click="exportJSON"
+                                class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs">
+                            Export to JSON
+                        </button>
+                        <label class="bg-gray-600 dark:bg-gray-500 text-white px-2.5 py-1 rounded-md hover:bg-gray-700 dark:hover:bg-gray-600 text-xs cursor-pointer">
+                            Restore from JSON
+                            <input type="file" accept=".json,application/json" @change="restoreJSON" class="hidden">
+                        </label>
+                    </div>
                 </div>
                 
+                <div>
+                    <h3 class="text-sm font-medium mb-1.5 text-gray-900 dark:text-gray-100">Shared Links</h3>
+                    <div class="flex flex-wrap gap-2 mb-2">
+                        <select v-model="newShare.kind"
+                                class="rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-2 py-1 text-xs">
+                            <option value="year">Year</option>
+                            <option value="series">Series</option>
+                            <option value="author">Author</option>
+                            <option value="status">Status</option>
+                            <option value="recent">Recent</option>
+                        </select>
+                        <input v-model="newShare.value" :placeholder="newShare.kind === 'recent' ? 'finished, started or added' : 'Value'"
+                               class="flex-1 rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-2 py-1 text-xs">
+                        <input v-model="newShare.title" placeholder="Title (optional)"
+                               class="flex-1 rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-2 py-1 text-xs">
+                        <label class="flex items-center text-xs text-gray-600 dark:text-gray-400">
+                            <input type="checkbox" v-model="newShare.show_comments" class="mr-1"> Comments
+                        </label>
+                        <button @click="createShare"
+                                class="bg-indigo-600 dark:bg-indigo-500 text-white px-2.5 py-1 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 text-xs">
+                            Create Link
+                        </button>
+                    </div>
+                    <div v-for="share in shares" :key="share.id" class="flex items-center justify-between text-xs py-1">
+                        <a :href="shareURL(share)" target="_blank" class="text-indigo-600 dark:text-indigo-400 truncate mr-2">
+                            {{ share.title || (share.kind + ': ' + share.value) }}
+                        </a>
+                        <span class="flex items-center space-x-2">
+                            <a :href="shareURL(share) + '.atom'" target="_blank" class="text-gray-500 dark:text-gray-400">Atom</a>
+                            <a :href="shareURL(share) + '.rss'" target="_blank" class="text-gray-500 dark:text-gray-400">RSS</a>
+                            <a :href="shareURL(share) + '.ics'" target="_blank" class="text-gray-500 dark:text-gray-400">Calendar</a>
+                            <button @click="revokeShare(share)" class="text-red-600 dark:text-red-400">Revoke</button>
+                        </span>
+                    </div>
+                </div>
+
+                <div>
+                    <h3 class="text-sm font-medium mb-1.5 text-gray-900 dark:text-gray-100">KOReader Sync</h3>
+                    <p class="text-xs text-gray-600 dark:text-gray-400">
+                        In KOReader, open Progress sync, set the custom sync server to
+                        <code class="bg-gray-100 dark:bg-gray-700 px-1 rounded">{{ kosyncURL }}</code>
+                        and log in with your buku username and password. Synced documents can then be linked from the book page.
+                    </p>
+                </div>
+
+                <div>
+                    <h3 class="text-sm font-medium mb-1.5 text-gray-900 dark:text-gray-100">OPDS Catalog</h3>
+                    <p class="text-xs text-gray-600 dark:text-gray-400">
+                        Add
+                        <code class="bg-gray-100 dark:bg-gray-700 px-1 rounded">{{ opdsURL }}</code>
+                        as a catalog in your e-reader app and log in with your buku username and password to browse the library.
+                    </p>
+                </div>
+
                 <div>
                     <h3 class="text-sm font-medium mb-1.5 text-red-600 dark:text-red-400">Danger Zone</h3>
                     <button @click="deleteAll"
@@ -1649,7 +2103,18 @@ const Import = {
     const file = ref(null);
     const csvData = ref(null);
     const importing = ref(false);
-    const step = ref(1); // 1: Select file, 2: Map columns
+    const step = ref(1); // 1: Select file, 2: Map columns, 3: Review rows
+    const preview = ref(null);
+    const accepted = ref({});
+    const conflict = ref('skip');
+    const job = ref(null);
+    const format = ref('csv'); // csv, or an export recognised by its header
+    const presets = ref([]);
+    const presetId = ref(null);
+    const delimiter = ref(',');
+    const dateFormat = ref('');
+    const statusRules = ref(''); // one "value = status" per line
+    const statusConflict = ref('dates');
     const columnMapping = ref({
       Title: '-',
       Author: '-',
@@ -1657,76 +2122,245 @@ const Import = {
       ISBN: '-',
       Comments: '-',
       Started: '-',
-      Finished: '-'
+      Finished: '-',
+      Status: '-'
     });
 
+    const parseStatusRules = () => {
+      const rules = {};
+      statusRules.value.split('\n').forEach(line => {
+        const [value, status] = line.split('=').map(s => s.trim());
+        if (value && status) {
+          rules[value] = status;
+        }
+      });
+      return rules;
+    };
+
+    const readColumns = async () => {
+      const formData = new FormData();
+      formData.append('file', file.value);
+      formData.append('delimiter', delimiter.value);
+
+      const response = await $fetch('/api/import/read_columns', {
+        method: 'POST',
+        body: formData
+      });
+      if (!response.ok) {
+        throw await $error(response);
+      }
+      return await response.json();
+    };
+
     const handleFileChange = async (event) => {
       const selectedFile = event.target.files[0];
       if (!selectedFile) return;
 
       file.value = selectedFile;
-      const formData = new FormData();
-      formData.append('file', selectedFile);
 
       try {
-        const response = await fetch('/api/import/read_columns', {
-          method: 'POST',
-          body: formData
-        });
-        csvData.value = await response.json();
+        csvData.value = await readColumns();
+        format.value = csvData.value.format;
+        if (format.value !== 'csv') {
+          // Known exports need no mapping, go straight to the review
+          await previewData();
+          return;
+        }
+        presets.value = await $json('/api/import/presets.json');
+        if (csvData.value.suggested_preset) {
+          await applyPreset(csvData.value.suggested_preset);
+        }
         step.value = 2; // Move to mapping step
       } catch (error) {
         console.error('Error reading columns:', error);
-        alert('Error reading CSV file: ' + error.message);
+        alert('Error reading file: ' + error.message);
+      }
+    };
+
+    // Copies the preset settings into the form, they can still be changed
+    // before importing.
+    const applyPreset = async (preset) => {
+      presetId.value = preset.id;
+      dateFormat.value = preset.date_format;
+      statusRules.value = Object.entries(preset.status_rules || {}).map(([value, status]) => `${value} = ${status}`).join('\n');
+      statusConflict.value = preset.status_conflict || 'dates';
+      if (delimiter.value !== (preset.delimiter || ',')) {
+        delimiter.value = preset.delimiter || ',';
+        csvData.value = await readColumns();
+      }
+      Object.keys(columnMapping.value).forEach(field => {
+        const column = preset.columns[field];
+        columnMapping.value[field] = csvData.value.columns.includes(column) ? column : '-';
+      });
+    };
+
+    const selectPreset = async () => {
+      const preset = presets.value.find(p => p.id === presetId.value);
+      if (!preset) return;
+
+      try {
+        await applyPreset(preset);
+      } catch (error) {
+        console.error('Error applying preset:', error);
+        alert('Error: ' + error.message);
+      }
+    };
+
+    const changeDelimiter = async () => {
+      try {
+        csvData.value = await readColumns();
+      } catch (error) {
+        console.error('Error reading columns:', error);
+        alert('Error reading file: ' + error.message);
+      }
+    };
+
+    const savePreset = async () => {
+      const current = presets.value.find(p => p.id === presetId.value);
+      const name = prompt('Preset name:', current ? current.name : '');
+      if (!name) return;
+
+      const existing = presets.value.find(p => p.name === name.trim());
+      const data = {
+        name: name,
+        delimiter: delimiter.value,
+        columns: columnMapping.value,
+        date_format: dateFormat.value,
+        status_rules: parseStatusRules(),
+        status_conflict: statusConflict.value
+      };
+
+      try {
+        const saved = existing
+          ? await $json(`/api/import/preset/${existing.id}.json`, 'POST', data)
+          : await $json('/api/import/preset.json', 'POST', data);
+        presets.value = await $json('/api/import/presets.json');
+        presetId.value = saved.id;
+      } catch (error) {
+        console.error('Error saving preset:', error);
+        alert('Error: ' + error.message);
       }
     };
 
     const goBack = () => {
+      if (step.value === 3 && format.value === 'csv') {
+        step.value = 2;
+        return;
+      }
       step.value = 1;
       file.value = null;
       csvData.value = null;
+      format.value = 'csv';
+      presetId.value = null;
+      delimiter.value = ',';
+      dateFormat.value = '';
+      statusRules.value = '';
+      statusConflict.value = 'dates';
       // Reset mappings
       Object.keys(columnMapping.value).forEach(key => {
         columnMapping.value[key] = '-';
       });
     };
 
-    const importData = async () => {
-      if (!file.value) return;
-
+    const buildFormData = () => {
       const formData = new FormData();
       formData.append('file', file.value);
+      formData.append('format', format.value);
+      formData.append('delimiter', delimiter.value);
+      formData.append('date_format', dateFormat.value);
+      formData.append('status_rules', JSON.stringify(parseStatusRules()));
+      formData.append('status_conflict', statusConflict.value);
 
       // Add column mappings to form data
       Object.keys(columnMapping.value).forEach(field => {
         formData.append(field, columnMapping.value[field]);
       });
+      return formData;
+    };
+
+    const previewData = async () => {
+      if (!file.value) return;
 
       try {
         importing.value = true;
-        const response = await fetch('/api/import', {
+        const response = await $fetch('/api/import/preview', {
+          method: 'POST',
+          body: buildFormData()
+        });
+        if (!response.ok) {
+          throw await $error(response);
+        }
+        preview.value = await response.json();
+        // Accept every valid row by default, duplicates are handled by the conflict policy
+        accepted.value = {};
+        preview.value.rows.forEach(row => {
+          accepted.value[row.line] = row.errors.length === 0;
+        });
+        step.value = 3;
+      } catch (error) {
+        console.error('Error previewing import:', error);
+        alert('Error: ' + error.message);
+      } finally {
+        importing.value = false;
+      }
+    };
+
+    const acceptedCount = computed(() => Object.values(accepted.value).filter(v => v).length);
+
+    const importData = async () => {
+      if (!file.value) return;
+
+      const formData = buildFormData();
+      formData.append('accepted', Object.keys(accepted.value).filter(line => accepted.value[line]).join(','));
+      formData.append('conflict', conflict.value);
+
+      try {
+        importing.value = true;
+        const response = await $fetch('/api/import/jobs', {
           method: 'POST',
           body: formData
         });
-        const result = await response.json();
+        if (!response.ok) {
+          throw await $error(response);
+        }
+        job.value = await response.json();
 
-        if (result.ok === false) {
-          alert('Import failed: ' + result.message);
-        } else {
-          alert(`Import completed! Total: ${result.total}, Succeeded: ${result.succeeded}, Failed: ${result.failed}`);
+        while (!job.value.finished_at) {
+          await new Promise(resolve => setTimeout(resolve, 1000));
+          job.value = await $json(`/api/import/jobs/${job.value.id}.json`);
+        }
+
+        const result = job.value.result;
+        if (job.value.status === 'succeeded') {
+          alert(`Import completed! Total: ${result.total}, Created: ${result.created}, Updated: ${result.updated}, Failed: ${result.failed}, Skipped: ${result.skipped}`);
           router.push('/page/admin');
+        } else if (job.value.status === 'failed') {
+          alert('Import failed, nothing was imported: ' + job.value.error);
         }
       } catch (error) {
         console.error('Error importing data:', error);
         alert('Error: ' + error.message);
       } finally {
         importing.value = false;
+        job.value = null;
+      }
+    };
+
+    const cancelImport = async () => {
+      if (!job.value || !confirm('Cancel the import? Nothing will be imported.')) return;
+
+      try {
+        job.value = await $json(`/api/import/jobs/${job.value.id}.json`, 'DELETE');
+      } catch (error) {
+        console.error('Error canceling import:', error);
+        alert('Error: ' + error.message);
       }
     };
 
     return {
-      file, csvData, importing, step, columnMapping,
-      handleFileChange, goBack, importData, router
+      file, csvData, importing, step, columnMapping, preview, accepted, acceptedCount, conflict, job,
+      presets, presetId, delimiter, dateFormat, statusRules, statusConflict,
+      handleFileChange, selectPreset, changeDelimiter, savePreset, goBack, previewData, importData, cancelImport, formatDate, router
     };
   },
   template: `
@@ -1736,9 +2370,10 @@ const Import = {
             <!-- Step 1: Select File -->
             <div v-if="step === 1" class="bg-white dark:bg-gray-800 p-6 rounded-lg shadow-sm space-y-4">
                 <div>
-                    <label class="block text-sm font-medium text-gray-600 dark:text-gray-400 mb-2">Select CSV File</label>
-                    <input type="file" accept=".csv" @change="handleFileChange"
+                    <label class="block text-sm font-medium text-gray-600 dark:text-gray-400 mb-2">Select CSV File or Calibre Library</label>
+                    <input type="file" accept=".csv,.db" @change="handleFileChange"
                            class="block w-full text-sm text-gray-500 dark:text-gray-400 file:mr-4 file:py-2 file:px-4 file:rounded file:border-0 file:text-sm file:bg-indigo-50 dark:file:bg-indigo-900 file:text-indigo-700 dark:file:text-indigo-300 hover:file:bg-indigo-100 dark:hover:file:bg-indigo-800">
+                    <p class="mt-1 text-xs text-gray-500 dark:text-gray-400">StoryGraph exports and Calibre libraries (metadata.db) are recognised and need no column mapping.</p>
                 </div>
                 
                 <div class="flex justify-start">
@@ -1751,6 +2386,36 @@ const Import = {
             
             <!-- Step 2: Map Columns -->
             <div v-if="step === 2" class="bg-white dark:bg-gray-800 p-6 rounded-lg shadow-sm space-y-4">
+                <div class="space-y-3">
+                    <div class="flex items-center justify-between">
+                        <label class="text-sm font-medium text-gray-600 dark:text-gray-400 w-24">Preset:</label>
+                        <select v-model="presetId" @change="selectPreset"
+                                class="flex-1 ml-4 rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-3 py-2 text-sm">
+                            <option :value="null">(None)</option>
+                            <option v-for="p in presets" :key="p.id" :value="p.id">{{ p.name }}</option>
+                        </select>
+                    </div>
+                    <div class="flex items-center justify-between">
+                        <label class="text-sm font-medium text-gray-600 dark:text-gray-400 w-24">Delimiter:</label>
+                        <input v-model="delimiter" @change="changeDelimiter" maxlength="1"
+                               class="flex-1 ml-4 rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-3 py-2 text-sm">
+                    </div>
+                    <div class="flex items-center justify-between">
+                        <label class="text-sm font-medium text-gray-600 dark:text-gray-400 w-24">Date format:</label>
+                        <input v-model="dateFormat" list="date-formats" placeholder="auto"
+                               class="flex-1 ml-4 rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-3 py-2 text-sm">
+                        <datalist id="date-formats">
+                            <option value="auto">Detect per column</option>
+                            <option value="yyyy-mm-dd"></option>
+                            <option value="yyyy/mm/dd"></option>
+                            <option value="dd/mm/yyyy"></option>
+                            <option value="mm/dd/yyyy"></option>
+                            <option value="month_name">31 January 2024</option>
+                            <option value="excel">Spreadsheet serial number</option>
+                        </datalist>
+                    </div>
+                </div>
+
                 <div>
                     <h3 class="font-medium mb-4 text-gray-900 dark:text-gray-100">Map CSV Columns to Book Fields</h3>
                     <div class="space-y-3">
@@ -1767,6 +2432,23 @@ const Import = {
                     </div>
                 </div>
                 
+                <div v-if="columnMapping.Status !== '-'" class="space-y-3">
+                    <div>
+                        <label class="block text-sm font-medium text-gray-600 dark:text-gray-400 mb-1">Status values:</label>
+                        <textarea v-model="statusRules" rows="3" placeholder="abandoned = read"
+                                  class="w-full rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-3 py-2 text-sm"></textarea>
+                        <p class="text-xs text-gray-500 dark:text-gray-400">One "value = to-read, reading or read" per line. Common values such as finished or currently-reading are known already.</p>
+                    </div>
+                    <div class="flex items-center justify-between">
+                        <label class="text-sm font-medium text-gray-600 dark:text-gray-400">When status and dates disagree:</label>
+                        <select v-model="statusConflict"
+                                class="ml-4 rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-3 py-1.5 text-sm">
+                            <option value="dates">Trust the dates</option>
+                            <option value="status">Trust the status</option>
+                        </select>
+                    </div>
+                </div>
+
                 <div v-if="csvData && csvData.columns">
                     <h4 class="text-sm font-medium text-gray-600 dark:text-gray-400 mb-2">Available CSV Columns:</h4>
                     <div class="flex flex-wrap gap-2">
@@ -1782,9 +2464,92 @@ const Import = {
                             class="bg-gray-600 dark:bg-gray-500 text-white px-3 py-1.5 rounded-md hover:bg-gray-700 dark:hover:bg-gray-600 text-sm">
                         Back
                     </button>
-                    <button @click="importData" :disabled="importing"
+                    <div class="space-x-2">
+                        <button @click="savePreset"
+                                class="bg-gray-600 dark:bg-gray-500 text-white px-3 py-1.5 rounded-md hover:bg-gray-700 dark:hover:bg-gray-600 text-sm">
+                            Save as Preset
+                        </button>
+                        <button @click="previewData" :disabled="importing"
+                                class="bg-indigo-600 dark:bg-indigo-500 text-white px-3 py-1.5 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 disabled:opacity-50 text-sm">
+                            {{ importing ? 'Reading...' : 'Preview' }}
+                        </button>
+                    </div>
+                </div>
+            </div>
+
+            <!-- Step 3: Review Rows -->
+            <div v-if="step === 3" class="bg-white dark:bg-gray-800 p-6 rounded-lg shadow-sm space-y-4">
+                <div>
+                    <h3 class="font-medium mb-1 text-gray-900 dark:text-gray-100">Review Rows</h3>
+                    <p v-if="format === 'storygraph'" class="text-xs text-gray-600 dark:text-gray-400">StoryGraph export, columns are mapped automatically</p>
+                    <p v-if="format === 'calibre'" class="text-xs text-gray-600 dark:text-gray-400">Calibre library, books are numbered by their Calibre ID</p>
+                    <p class="text-xs text-gray-600 dark:text-gray-400">
+                        {{ preview.total }} rows, {{ preview.invalid }} invalid, {{ preview.duplicates }} possible duplicates
+                    </p>
+                    <p v-if="preview.date_formats.started_at || preview.date_formats.finished_at" class="text-xs text-gray-600 dark:text-gray-400">
+                        Dates read as
+                        <span v-if="preview.date_formats.started_at">{{ preview.date_formats.started_at }} (started)</span>
+                        <span v-if="preview.date_formats.finished_at">{{ preview.date_formats.finished_at }} (finished)</span>
+                    </p>
+                </div>
+
+                <div class="flex items-center justify-between">
+                    <label class="text-sm font-medium text-gray-600 dark:text-gray-400">Existing books:</label>
+                    <select v-model="conflict"
+                            class="ml-4 rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-3 py-1.5 text-sm">
+                        <option value="skip">Skip</option>
+                        <option value="update">Update existing</option>
+                        <option value="create">Create anyway</option>
+                    </select>
+                </div>
+
+                <div class="space-y-2 max-h-96 overflow-y-auto">
+                    <label v-for="row in preview.rows" :key="row.line"
+                           class="flex items-start text-sm p-2 rounded border border-gray-200 dark:border-gray-700"
+                           :class="{ 'opacity-50': row.errors.length > 0 }">
+                        <input type="checkbox" v-model="accepted[row.line]" :disabled="row.errors.length > 0" class="mt-1 mr-2">
+                        <div class="flex-1">
+                            <div class="text-gray-900 dark:text-gray-100">
+                                <span class="text-xs text-gray-500 mr-1">#{{ row.line }}</span>
+                                {{ row.book.title || '(no title)' }}
+                                <span v-if="row.book.author" class="text-gray-600 dark:text-gray-400">· {{ row.book.author }}</span>
+                            </div>
+                            <div class="text-xs text-gray-500 dark:text-gray-400">
+                                {{ row.book.status }}
+                                <span v-if="row.book.started_at">· {{ formatDate(row.book.started_at) }}</span>
+                                <span v-if="row.book.finished_at">→ {{ formatDate(row.book.finished_at) }}</span>
+                            </div>
+                            <div v-for="e in row.errors" class="text-xs text-red-600 dark:text-red-400">{{ e.message }}</div>
+                            <div v-for="w in row.warnings" class="text-xs text-yellow-600 dark:text-yellow-400">{{ w.message }}</div>
+                            <div v-if="row.duplicate_ids.length > 0" class="text-xs text-yellow-600 dark:text-yellow-400">
+                                Possible duplicate of book #{{ row.duplicate_ids.join(', #') }}
+                            </div>
+                            <div v-if="row.duplicate_of_line > 0" class="text-xs text-yellow-600 dark:text-yellow-400">
+                                Same book as line {{ row.duplicate_of_line }}
+                            </div>
+                        </div>
+                    </label>
+                </div>
+
+                <div v-if="job" class="space-y-1">
+                    <div class="w-full h-2 bg-gray-200 dark:bg-gray-700 rounded">
+                        <div class="h-2 bg-indigo-600 dark:bg-indigo-500 rounded"
+                             :style="{ width: (job.total > 0 ? job.processed * 100 / job.total : 0) + '%' }"></div>
+                    </div>
+                    <div class="flex justify-between text-xs text-gray-600 dark:text-gray-400">
+                        <span>{{ job.processed }} / {{ job.total }} rows · {{ job.result.errors.length }} errors</span>
+                        <button @click="cancelImport" :disabled="!!job.finished_at" class="text-red-600 dark:text-red-400">Cancel</button>
+                    </div>
+                </div>
+
+                <div class="flex justify-between">
+                    <button @click="goBack" :disabled="importing"
+                            class="bg-gray-600 dark:bg-gray-500 text-white px-3 py-1.5 rounded-md hover:bg-gray-700 dark:hover:bg-gray-600 disabled:opacity-50 text-sm">
+                        Back
+                    </button>
+                    <button @click="importData" :disabled="importing || acceptedCount === 0"
                             class="bg-indigo-600 dark:bg-indigo-500 text-white px-3 py-1.5 rounded-md hover:bg-indigo-700 dark:hover:bg-indigo-600 disabled:opacity-50 text-sm">
-                        {{ importing ? 'Importing...' : 'Import Data' }}
+                        {{ importing ? 'Importing...' : 'Import ' + acceptedCount + ' Rows' }}
                     </button>
                 </div>
             </div>
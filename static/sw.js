@@ -1,6 +1,6 @@
-const CACHE_NAME = 'buku-v1';
-const STATIC_CACHE = 'buku-static-v1';
-const DYNAMIC_CACHE = 'buku-dynamic-v1';
+const CACHE_NAME = 'buku-v19';
+const STATIC_CACHE = 'buku-static-v7';
+const DYNAMIC_CACHE = 'buku-dynamic-v7';
 
 const STATIC_FILES = [
   '/',